@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	rand "math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"genxls/pkg/genxls"
+)
+
+// runBench implements `genxls bench`: parse and encode synthetic sheets with
+// and without the cell cache (and with --xlsx, read from workbooks whole and
+// row by row) and report time, allocations and GC runs, to check exporter
+// changes against huge tables without real data. With --baseline it fails
+// when a measurement got slower or allocates more than --tolerance allows.
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	rows := fs.Int("rows", 1000000, "rows of the tall sheet; the other shapes scale from it")
+	distinct := fs.Int("distinct", 100, "distinct values per non-key column")
+	seed := fs.Uint64("seed", 1, "random seed")
+	shapes := fs.String("shape", "tall", "tall|wide|arrays|text|all, or comma-separated")
+	xlsx := fs.Bool("xlsx", false, "also write each sheet to a workbook and time loading it, whole and row by row")
+	count := fs.Int("count", 1, "runs per measurement; the fastest counts")
+	save := fs.String("save", "", "write the results to this JSON file, as a baseline")
+	baseline := fs.String("baseline", "", "fail if results are worse than in this file (see --save)")
+	tolerance := fs.Float64("tolerance", 0.2, "with --baseline, how much slower or allocation-heavier a measurement may get")
+	_ = fs.Parse(args)
+
+	if *rows <= 0 || *distinct <= 0 || *count <= 0 {
+		exitErr(fmt.Errorf("invalid --rows %d, --distinct %d or --count %d", *rows, *distinct, *count))
+	}
+	selected, err := genxls.SelectBenchShapes(*shapes)
+	if err != nil {
+		exitErr(err)
+	}
+	var base []genxls.BenchResult
+	if *baseline != "" {
+		data, err := os.ReadFile(*baseline)
+		if err != nil {
+			exitErr(err)
+		}
+		if err := json.Unmarshal(data, &base); err != nil {
+			exitErr(fmt.Errorf("%s: %w", *baseline, err))
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "shape\tmode\trows\ttime\tallocs\talloc bytes\tlive heap\tGC runs\t")
+	var results []genxls.BenchResult
+	for _, shape := range selected {
+		n := shape.Rows(*rows)
+		grid := genxls.BenchGrid(shape, n, *distinct, rand.New(rand.NewPCG(*seed, 0)))
+		fields, err := genxls.ParseFieldsFromDefineRow(grid, 1, "")
+		if err != nil {
+			exitErr(fmt.Errorf("%s: %w", shape.Name, err))
+		}
+		modes := []string{"parse", "parse+cache"}
+		// The workbook's directory is removed at the end of the shape, and
+		// before exitErr, which skips deferred calls.
+		var dir, path string
+		fail := func(err error) {
+			if dir != "" {
+				_ = os.RemoveAll(dir)
+			}
+			exitErr(err)
+		}
+		if *xlsx {
+			if dir, err = os.MkdirTemp("", "genxls-bench-"); err != nil {
+				exitErr(err)
+			}
+			path = filepath.Join(dir, shape.Name+".xlsx")
+			if err := genxls.WriteBenchWorkbook(path, grid); err != nil {
+				fail(err)
+			}
+			modes = append(modes, "xlsx", "xlsx stream")
+		}
+		for _, mode := range modes {
+			var best genxls.BenchRun
+			for i := 0; i < *count; i++ {
+				run, err := genxls.BenchMeasure(mode, grid, fields, path)
+				if err != nil {
+					fail(fmt.Errorf("%s %s: %w", shape.Name, mode, err))
+				}
+				if i == 0 || run.Elapsed < best.Elapsed {
+					best = run
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%v\t%d\t%s\t%s\t%d\t\n", shape.Name, mode, n, best.Elapsed.Round(time.Millisecond),
+				best.Allocs, genxls.FormatByteSize(int64(best.AllocBytes)), genxls.FormatByteSize(int64(best.Live)), best.GCs)
+			results = append(results, genxls.BenchResult{
+				Shape:        shape.Name,
+				Mode:         mode,
+				Rows:         n,
+				NsPerRow:     float64(best.Elapsed.Nanoseconds()) / float64(n),
+				AllocsPerRow: float64(best.Allocs) / float64(n),
+			})
+		}
+		if dir != "" {
+			_ = os.RemoveAll(dir)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		exitErr(err)
+	}
+
+	if *save != "" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			exitErr(err)
+		}
+		if err := os.WriteFile(*save, append(data, '\n'), 0o644); err != nil {
+			exitErr(err)
+		}
+	}
+	if base != nil {
+		if regressions := genxls.BenchRegressions(base, results, *tolerance); len(regressions) > 0 {
+			exitErr(fmt.Errorf("slower than %s:\n  %s", *baseline, strings.Join(regressions, "\n  ")))
+		}
+	}
+}
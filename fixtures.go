@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	rand "math/rand/v2"
+	"os"
+	"strings"
+
+	"genxls/pkg/genxls"
+)
+
+// runFixtures implements `genxls fixtures`: write a payload with the same
+// schema as the real one but synthetic rows, for automated and load tests that
+// shouldn't ship design data.
+func runFixtures(args []string) {
+	fs := flag.NewFlagSet("fixtures", flag.ExitOnError)
+	in := fs.String("in", "xls", "input xlsx file or directory (only the schemas are used)")
+	outDir := fs.String("out", ".", "output directory")
+	rows := fs.Int("rows", 10, "rows per sheet")
+	seed := fs.Uint64("seed", 1, "random seed; the same seed and schema give the same fixtures")
+	exportFlag := fs.String("flag", "", "export flag: server|client (optional)")
+	dataFormat := fs.String("data-format", "json", "data payload format: json|yaml|toml|xml|jsonl|tsv|csv")
+	config := fs.String("config", "", "config file (default: genxls.json, genxls.yaml or genxls.toml in the working directory, if present)")
+	verbose := fs.Bool("v", false, "verbose")
+	_ = fs.Parse(args)
+
+	if *rows < 0 {
+		exitErr(fmt.Errorf("invalid --rows %d", *rows))
+	}
+	format, err := genxls.ParseDataFormat(*dataFormat)
+	if err != nil {
+		exitErr(err)
+	}
+	cfg, err := genxls.LoadConfig(*config)
+	if err != nil {
+		exitErr(err)
+	}
+	inPaths, err := genxls.ResolveInputPaths(*in, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	sheets, err := genxls.LoadSheets(context.Background(), inPaths, genxls.Options{Flag: *exportFlag}, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	rng := rand.New(rand.NewPCG(*seed, 0))
+	for _, sheet := range sheets {
+		if err := fillFixtureRows(sheet, *rows, rng); err != nil {
+			exitErr(fmt.Errorf("%s: %w", sheet.Origin, err))
+		}
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		exitErr(err)
+	}
+	files, err := genxls.WriteDataPayload(&genxls.OutputLayout{OutDir: *outDir, DataName: "all", RootName: "AllConfig"}, format, sheets)
+	if err != nil {
+		exitErr(err)
+	}
+	if *verbose {
+		for _, f := range files {
+			fmt.Fprintf(os.Stderr, "generated %s\n", f)
+		}
+	}
+}
+
+// fillFixtureRows replaces the sheet's rows with n synthetic ones. The primary
+// key (first column) is unique; ,sort columns are honored.
+func fillFixtureRows(sheet *genxls.Sheet, n int, rng *rand.Rand) error {
+	pk := sheet.Fields[0]
+	if strings.ToLower(pk.RawType) == "bool" && n > 2 {
+		return fmt.Errorf("primary key %s is bool and can't have %d unique rows", pk.RawName, n)
+	}
+	sheet.Items = make([]map[string]any, n)
+	sheet.Rows = make([]int, n)
+	for i := range sheet.Items {
+		item := make(map[string]any, len(sheet.Fields))
+		for j, f := range sheet.Fields {
+			v, err := genxls.FixtureValue(f, i, j == 0, rng)
+			if err != nil {
+				return err
+			}
+			item[f.RawName] = v
+		}
+		sheet.Items[i] = item
+		sheet.Rows[i] = i + 1
+	}
+	genxls.SortSheetRows(sheet, false)
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"genxls/pkg/genxls"
+)
+
+// runNextID implements `genxls next-id`: reserve the next block of primary
+// keys of a sheet in the ids file and print it.
+func runNextID(args []string) {
+	fs := flag.NewFlagSet("next-id", flag.ExitOnError)
+	in := fs.String("in", "xls", "input xlsx file or directory")
+	config := fs.String("config", "", "config file (default: genxls.json, genxls.yaml or genxls.toml in the working directory, if present)")
+	sheetName := fs.String("sheet", "", "sheet to allocate ids in: sheet name, type name or JSON key")
+	count := fs.Int("count", 1, "number of ids to reserve")
+	owner := fs.String("owner", "", "who the ids are for (default: the USER or USERNAME environment variable)")
+	idsFile := fs.String("ids", genxls.DefaultIDsFile, "file recording the allocated id blocks")
+	dryRun := fs.Bool("dry-run", false, "print the next block without recording it")
+	namespace := fs.Bool("namespace-by-file", false, "prefix sheet names with the workbook name, as in the export")
+	_ = fs.Parse(args)
+
+	if *sheetName == "" {
+		exitErr(errors.New("next-id: --sheet is required"))
+	}
+	if *count <= 0 {
+		exitErr(fmt.Errorf("next-id: invalid --count %d", *count))
+	}
+	if *owner == "" {
+		*owner = os.Getenv("USER")
+	}
+	if *owner == "" {
+		*owner = os.Getenv("USERNAME")
+	}
+	cfg, err := genxls.LoadConfig(*config)
+	if err != nil {
+		exitErr(err)
+	}
+	inPaths, err := genxls.ResolveInputPaths(*in, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	ranges, err := genxls.LoadIDRanges(*idsFile)
+	if err != nil {
+		exitErr(err)
+	}
+	if ranges == nil {
+		ranges = genxls.IDRanges{}
+	}
+	sheets, err := genxls.LoadSheets(context.Background(), inPaths, genxls.Options{NamespaceFile: *namespace}, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	if err := genxls.ApplyConfig(cfg, sheets, false); err != nil {
+		exitErr(err)
+	}
+	sheet := genxls.FindSheet(sheets, *sheetName)
+	if sheet == nil {
+		exitErr(fmt.Errorf("next-id: sheet %q not found", *sheetName))
+	}
+	if problems := genxls.CheckIDRanges(ranges, []*genxls.Sheet{sheet}); len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: existing id collisions:\n%s\n", genxls.FormatProblems(problems))
+	}
+
+	block, err := genxls.NextIDBlock(sheet, ranges[sheet.JSONKey], *count)
+	if err != nil {
+		exitErr(err)
+	}
+	block.Owner = *owner
+	block.Date = time.Now().Format("2006-01-02")
+	if !*dryRun {
+		ranges[sheet.JSONKey] = append(ranges[sheet.JSONKey], block)
+		if err := genxls.SaveIDRanges(*idsFile, ranges); err != nil {
+			exitErr(err)
+		}
+	}
+	if block.From == block.To {
+		fmt.Println(block.From)
+	} else {
+		fmt.Printf("%d-%d\n", block.From, block.To)
+	}
+}
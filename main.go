@@ -1,878 +1,133 @@
 package main
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
-	"sort"
-	"strconv"
-	"strings"
+	"os/signal"
 
-	"github.com/xuri/excelize/v2"
+	"genxls/pkg/genxls"
 )
 
-type Orientation int
-
-const (
-	OrientationHorizontal Orientation = iota
-	OrientationVertical
-)
-
-type FieldFlag int
-
-const (
-	FieldFlagAll FieldFlag = iota
-	FieldFlagServer
-	FieldFlagClient
-	FieldFlagNone
-)
-
-type Field struct {
-	RawName   string
-	Name      string
-	RawType   string
-	GoType    string
-	Col       int
-	Flag      FieldFlag
-	Exported  bool
-	IsComment bool
-}
-
-func lowerFirst(s string) string {
-	if s == "" {
-		return s
-	}
-	return strings.ToLower(s[:1]) + s[1:]
-}
-
-func pluralizeTypeName(typeName string) string {
-	if typeName == "" {
-		return typeName
-	}
-	// Minimal pluralization for config names: Item->Items, Quest->Quests
-	if strings.HasSuffix(typeName, "s") || strings.HasSuffix(typeName, "x") || strings.HasSuffix(typeName, "z") || strings.HasSuffix(typeName, "ch") || strings.HasSuffix(typeName, "sh") {
-		return typeName + "es"
-	}
-	return typeName + "s"
-}
-
-func resolveInputPaths(in string) ([]string, error) {
-	in = strings.TrimSpace(in)
-	if in == "" {
-		return nil, errors.New("empty --in")
-	}
-	// If it's already an existing path, keep it.
-	if st, err := os.Stat(in); err == nil {
-		if st.IsDir() {
-			return listExcelFiles(in)
-		}
-		return []string{in}, nil
-	}
-
-	// If user passed just a filename (or a relative path that doesn't exist), try ./xls/<name>.
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-	candidate := filepath.Join(wd, "xls", filepath.Base(in))
-	if st, err := os.Stat(candidate); err == nil {
-		if st.IsDir() {
-			return listExcelFiles(candidate)
-		}
-		return []string{candidate}, nil
-	}
-
-	return nil, fmt.Errorf("input file not found: %s (also tried %s)", in, candidate)
-}
-
-func listExcelFiles(dir string) ([]string, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-	var out []string
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		ext := strings.ToLower(filepath.Ext(name))
-		if ext != ".xlsx" && ext != ".xls" {
-			continue
-		}
-		out = append(out, filepath.Join(dir, name))
-	}
-	sort.Strings(out)
-	if len(out) == 0 {
-		return nil, fmt.Errorf("no .xls/.xlsx files in %s", dir)
-	}
-	return out, nil
-}
-
-func readRowsAuto(path string) ([][]string, error) {
-	f, err := excelize.OpenFile(path)
-	if err == nil {
-		defer func() { _ = f.Close() }()
-		list := f.GetSheetList()
-		if len(list) == 0 {
-			return nil, fmt.Errorf("%s: xlsx has no sheets", path)
-		}
-		rows, err := f.GetRows(list[0])
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", path, err)
-		}
-		return rows, nil
-	}
-	rows, err2 := readTSVRows(path)
-	if err2 != nil {
-		return nil, fmt.Errorf("open %s: %w", path, err)
-	}
-	return rows, nil
-}
-
-func readTSVRows(path string) ([][]string, error) {
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	s := strings.ReplaceAll(string(b), "\r\n", "\n")
-	s = strings.ReplaceAll(s, "\r", "\n")
-	lines := strings.Split(s, "\n")
-	var rows [][]string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		rows = append(rows, strings.Split(line, "\t"))
-	}
-	if len(rows) == 0 {
-		return nil, fmt.Errorf("%s: empty file", path)
-	}
-	return rows, nil
-}
-
-type HeaderSpec struct {
-	HeaderRows  int
-	Orientation Orientation
-	DefineRow   int // 1-based row number in sheet
-}
-
-type Options struct {
-	InPath  string
-	OutDir  string
-	Flag    string
-	Lang    string
-	Pkg     string
-	JSON    bool
-	Verbose bool
-}
-
 func main() {
-	var opts Options
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "preview":
+			runPreview(os.Args[2:])
+			return
+		case "fixtures":
+			runFixtures(os.Args[2:])
+			return
+		case "validation":
+			runValidation(os.Args[2:])
+			return
+		case "synth":
+			runSynth(os.Args[2:])
+			return
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		case "next-id":
+			runNextID(os.Args[2:])
+			return
+		case "schema":
+			runSchema(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		}
+	}
+
+	var opts genxls.Options
+	def := genxls.DefaultOptions()
+	var streamThreshold string
 	flag.StringVar(&opts.InPath, "in", "", "input xlsx file or directory (default: ./xls)")
-	flag.StringVar(&opts.OutDir, "out", ".", "output directory")
-	flag.StringVar(&opts.Flag, "flag", "", "export flag: server|client (optional)")
-	flag.StringVar(&opts.Lang, "lang", "all", "target lang: go|Pb|ts|all (or comma-separated)")
-	flag.StringVar(&opts.Pkg, "pkg", "config", "go package name")
-	flag.BoolVar(&opts.JSON, "json", true, "export json data")
+	flag.StringVar(&opts.OutDir, "out", def.OutDir, "output directory")
+	flag.StringVar(&opts.OutTemplate, "out-template", "", "output path template, e.g. {outDir}/{lang}/{sheet}.gen.{ext} (default: {outDir}/{file})")
+	flag.DurationVar(&opts.WaitLock, "wait-lock", 0, "wait this long for another run writing to --out to finish, instead of failing at once")
+	flag.StringVar(&opts.Sink, "sink", "", "write generated artifacts to a .zip/.tar/.tar.gz archive or PUT them under a URL instead of --out")
+	flag.StringVar(&opts.Flag, "flag", "", "export flag: server|client, or both for server/ and client/ under --out (optional)")
+	flag.BoolVar(&opts.Anonymize, "anonymize", false, "scramble exported values (strings hashed, numbers jittered) for sharing repro cases externally")
+	flag.StringVar(&opts.AnonymizeKey, "anonymize-key", "", "with --anonymize, key for hashing strings and seeding the jitter (default: random per run)")
+	flag.BoolVar(&opts.ScrubServer, "scrub-server", false, "with --flag client, keep server-only (,s) columns but export them as empty/zero values")
+	flag.StringVar(&opts.Lang, "lang", def.Lang, "target lang: go|Pb|ts|gd|ue|php|erl|lua|dart|capnp|proto|all, comma-separated; all is go,Pb,ts and the others are opt-in (all,gd)")
+	flag.BoolVar(&opts.PbData, "pb-data", false, "with the proto target, also serialize the rows into <data>.pb, an encoded root message")
+	flag.BoolVar(&opts.PbGRPC, "pb-grpc", false, "with the proto target, also declare a gRPC service (GetSheet, GetAll, WatchChanges) and write its Go server, grpc.gen.go")
+	flag.StringVar(&opts.Pkg, "pkg", def.Pkg, "go package name")
+	flag.StringVar(&opts.Password, "password", "", "password for protected workbooks without a config \"passwords\" entry")
+	flag.StringVar(&opts.Config, "config", "", "config file (default: genxls.json, genxls.yaml or genxls.toml in the working directory, if present)")
+	flag.StringVar(&opts.Bundle, "bundle", "", "export only the sheets tagged with this bundle, as <bundle>.json and <Bundle>Config")
+	flag.StringVar(&opts.Exclude, "exclude", "", "drop columns matching these name patterns from every sheet, comma-separated (e.g. *_memo,tmp_*)")
+	flag.BoolVar(&opts.MergeSheets, "merge-sheets", false, "export same-named sheets of different workbooks as one sheet when their columns match and their primary keys don't overlap")
+	flag.BoolVar(&opts.NamespaceFile, "namespace-by-file", false, "prefix sheet types and JSON keys with the workbook name (battle.xlsx's Item becomes BattleItem, battleItems)")
+	flag.StringVar(&opts.Only, "only", "", "refresh just this sheet's data outputs in place (sheet name, type name or JSON key)")
+	flag.BoolVar(&opts.JSON, "json", def.JSON, "export data payload (all.<ext>, see --data-format)")
+	flag.StringVar(&opts.DataFormat, "data-format", def.DataFormat, "data payload format: json|yaml|toml|xml|jsonl|tsv|csv")
+	flag.BoolVar(&opts.Sparse, "sparse", false, "write mostly-uniform sheets as a base row plus per-row overrides in all.json")
+	flag.IntVar(&opts.ChunkRows, "chunk-rows", 0, "write sheets with more rows as <key>.0.json, <key>.1.json, ... listed in all.index.json (0: never)")
+	flag.BoolVar(&opts.GoEmbed, "go-embed", false, "write data.gen.go embedding all.json into the go package (decoded at init)")
+	flag.BoolVar(&opts.GoAccessor, "go-accessor", false, "add a concurrency-safe Get() and Init(path) for the loaded config to go.gen.go")
+	flag.StringVar(&opts.Loader, "loader", "", "add a loader with indexed lookups: go (LoadAllConfig(path) and GetItemById-style getters in go.gen.go)")
+	flag.BoolVar(&opts.GoPrometheus, "go-prometheus", false, "with --go-accessor, write prometheus.gen.go reporting config loads to Prometheus")
+	flag.StringVar(&opts.CSStubs, "cs-stubs", "", "create an empty partial class <Type>.cs per generated C# type in this directory, if missing")
+	flag.BoolVar(&opts.TSGuards, "ts-guards", false, "add isItem(obj)/isAllConfig(obj) runtime type guards to ts.gen.ts")
+	flag.BoolVar(&opts.DebugData, "debug-data", false, "add each row's original cell text under \"__raw\" in the json/jsonl payload (debug builds)")
+	flag.BoolVar(&opts.Parquet, "parquet", false, "export parquet data (one file per sheet)")
+	flag.BoolVar(&opts.Avro, "avro", false, "export avro schema and data (one .avsc/.avro pair per sheet)")
+	flag.BoolVar(&opts.Redis, "redis", false, "export redis bulk-load file (redis.gen.resp, for redis-cli --pipe)")
+	flag.BoolVar(&opts.Mongo, "mongo", false, "export <sheetKey>.mongo.json per sheet for mongoimport, with _id set to the primary key")
+	flag.StringVar(&opts.MongoURI, "mongo-uri", "", "with --mongo, import the files into this MongoDB database with mongoimport (e.g. mongodb://localhost/config)")
+	flag.BoolVar(&opts.Int64AsString, "int64-as-string", false, "serialize int64 columns as JSON strings (per column: name#int64,str)")
+	flag.StringVar(&opts.TypePrefix, "type-prefix", "", "prefix for generated sheet type names (e.g. Cfg -> CfgItem)")
+	flag.StringVar(&opts.TypeSuffix, "type-suffix", "", "suffix for generated sheet type names (e.g. Cfg -> ItemCfg)")
+	flag.BoolVar(&opts.Provenance, "provenance", false, "write provenance.json mapping every exported row to its source file, sheet and row")
+	flag.BoolVar(&opts.Docs, "docs", false, "write CONFIG.md documenting every sheet's columns, described by their define-row cell comments")
+	flag.StringVar(&opts.PublishSchema, "publish-schema", "", "publish parsed schema set to a registry dir, file or URL")
+	flag.StringVar(&opts.VerifyAgainst, "verify-against", "", "fail if schemas are incompatible with pinned schemas (dir, file or URL)")
+	flag.BoolVar(&opts.SortRows, "sort-rows", false, "sort rows by primary key (first column) unless the sheet marks ,sort columns")
+	flag.BoolVar(&opts.Strict, "strict", false, "reject undeclared columns, define-row gaps and cells that need coercion")
+	flag.IntVar(&opts.MaxErrors, "max-errors", def.MaxErrors, "print at most this many load errors (bad cells, define rows) of a failed run; 0: all")
+	flag.BoolVar(&opts.Lenient, "lenient", false, "replace unparsable cells with zero values and warn instead of failing")
+	flag.StringVar(&opts.Annotate, "annotate", "", "write copies of workbooks with failing cells highlighted and commented into this directory")
+	flag.StringVar(&opts.FloatToInt, "float-to-int", def.FloatToInt, "accept float text in int columns: none|exact|round|floor|ceil|trunc (per column: name#int,round)")
+	flag.StringVar(&opts.AsOf, "as-of", "", "drop rows whose __start/__end window doesn't include this time (e.g. 2024-05-01 10:00, or now) and the window columns")
+	flag.BoolVar(&opts.Stats, "stats", false, "print min/max/mean/median of numeric columns and flag outliers")
+	flag.Float64Var(&opts.OutlierFactor, "outlier-factor", 100, "with --stats, flag values this many times larger or smaller than the column median")
+	flag.IntVar(&opts.MaxRows, "max-rows", 0, "max rows per sheet (0: unlimited)")
+	flag.IntVar(&opts.MaxStringLen, "max-string-len", 0, "max string cell length in characters (0: unlimited)")
+	flag.StringVar(&streamThreshold, "stream-threshold", "8MB", "read workbooks at least this large row by row to bound memory (0: never)")
+	flag.StringVar(&opts.MaxPayload, "max-payload", "", "max JSON payload size, e.g. 50MB (empty: unlimited)")
+	flag.StringVar(&opts.LimitMode, "limit-mode", def.LimitMode, "what exceeded limits do: error|warn")
+	flag.StringVar(&opts.LastGreen, "last-green", "", "print what changed since the run summary in this file and replace it once the run succeeds (keep it in the CI cache)")
+	flag.StringVar(&opts.Changelog, "changelog", "", "prepend an entry listing rows added/changed/removed since the last run to this file (e.g. CHANGELOG.md)")
+	flag.BoolVar(&opts.AllowDrift, "allow-drift", false, "accept values that changed beyond their config drift limits since the last run")
+	flag.StringVar(&opts.LockFile, "lock", def.LockFile, "schema lock file; when it exists, schema changes fail unless --update-lock is given")
+	flag.StringVar(&opts.IDsFile, "ids", def.IDsFile, "id allocation file (see genxls next-id); when it exists, its sheets fail on overlapping blocks and repeated keys")
+	flag.BoolVar(&opts.UpdateLock, "update-lock", false, "accept the current sheet schemas and write them to the lock file")
+	flag.BoolVar(&opts.FailOnReorder, "fail-on-reorder", false, "fail instead of warn when a sheet's columns were reordered since the last run")
 	flag.BoolVar(&opts.Verbose, "v", false, "verbose")
 	flag.Parse()
-
-	if opts.InPath == "" {
-		opts.InPath = "xls"
-	}
-	inPaths, err := resolveInputPaths(opts.InPath)
+	cfg, err := genxls.LoadConfig(opts.Config)
 	if err != nil {
 		exitErr(err)
 	}
-	langs, err := parseLangs(opts.Lang)
-	if err != nil {
+	if err := genxls.ApplyConfigFlags(flag.CommandLine, cfg); err != nil {
 		exitErr(err)
 	}
-	if len(inPaths) == 0 {
-		exitErr(errors.New("no input files"))
+	if opts.StreamSize, err = genxls.ParseByteSize(streamThreshold); err != nil {
+		exitErr(fmt.Errorf("--stream-threshold: %w", err))
 	}
-
-	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+	opts.Logger = genxls.NewCLILogger(os.Stderr, opts.Verbose)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := genxls.Export(ctx, opts, cfg); err != nil {
 		exitErr(err)
 	}
-
-	rootName := "AllConfig"
-
-	// Aggregated output:
-	// - generate one go.gen.go/Pb.gen.Pb/ts.gen.ts
-	// - generate one all.json with keys based on sheet name (pluralized)
-	schemas := make(map[string][]Field)      // typeName -> fields
-	jsonPayload := make(map[string]any)      // jsonKey -> []object
-	seenKeys := make(map[string]string)      // jsonKey -> origin (file/sheet)
-	orderedTypeNames := make([]string, 0, 8) // stable output order
-
-	addSheet := func(origin string, sheetName string, rows [][]string) {
-		spec, err := detectHeaderSpec(rows)
-		if err != nil {
-			exitErr(fmt.Errorf("%s: %w", origin, err))
-		}
-		if spec.Orientation == OrientationVertical {
-			exitErr(fmt.Errorf("%s: vertical orientation (A1=2) is not supported yet", origin))
-		}
-		fields, err := parseFieldsFromDefineRow(rows, spec.DefineRow, opts.Flag)
-		if err != nil {
-			exitErr(fmt.Errorf("%s: %w", origin, err))
-		}
-		items, err := readHorizontalItems(rows, spec.DefineRow+1, fields)
-		if err != nil {
-			exitErr(fmt.Errorf("%s: %w", origin, err))
-		}
-
-		typeName := exportName(sheetName)
-		if typeName == "" {
-			exitErr(fmt.Errorf("%s: empty sheet name", origin))
-		}
-		fieldName := pluralizeTypeName(typeName)
-		jsonKey := lowerFirst(fieldName)
-		if prev, ok := seenKeys[jsonKey]; ok {
-			exitErr(fmt.Errorf("duplicate sheet key %q from %s (already used by %s)", jsonKey, origin, prev))
-		}
-		seenKeys[jsonKey] = origin
-		schemas[typeName] = fields
-		jsonPayload[jsonKey] = items
-		orderedTypeNames = append(orderedTypeNames, typeName)
-	}
-
-	for _, p := range inPaths {
-		if f, err := excelize.OpenFile(p); err == nil {
-			func() {
-				defer func() { _ = f.Close() }()
-				sheets := f.GetSheetList()
-				if len(sheets) == 0 {
-					exitErr(fmt.Errorf("%s: xlsx has no sheets", p))
-				}
-				for _, sheet := range sheets {
-					rows, err := f.GetRows(sheet)
-					if err != nil {
-						exitErr(fmt.Errorf("%s[%s]: %w", p, sheet, err))
-					}
-					addSheet(fmt.Sprintf("%s[%s]", p, sheet), sheet, rows)
-				}
-			}()
-			continue
-		}
-
-		rows, err := readTSVRows(p)
-		if err != nil {
-			exitErr(err)
-		}
-		sheet := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
-		addSheet(p, sheet, rows)
-	}
-
-	// Generate aggregated code
-	if langs["go"] {
-		goCode, err := generateGoBundle(opts.Pkg, rootName, orderedTypeNames, schemas)
-		if err != nil {
-			exitErr(err)
-		}
-		outFile := filepath.Join(opts.OutDir, "go.gen.go")
-		if err := os.WriteFile(outFile, []byte(goCode), 0o644); err != nil {
-			exitErr(err)
-		}
-		if opts.Verbose {
-			fmt.Fprintf(os.Stderr, "generated %s\n", outFile)
-		}
-	}
-	if langs["Pb"] {
-		csCode, err := generateCSBundle(rootName, orderedTypeNames, schemas)
-		if err != nil {
-			exitErr(err)
-		}
-		outFile := filepath.Join(opts.OutDir, "Pb.gen.Pb")
-		if err := os.WriteFile(outFile, []byte(csCode), 0o644); err != nil {
-			exitErr(err)
-		}
-		if opts.Verbose {
-			fmt.Fprintf(os.Stderr, "generated %s\n", outFile)
-		}
-	}
-	if langs["ts"] {
-		tsCode, err := generateTSBundle(rootName, orderedTypeNames, schemas)
-		if err != nil {
-			exitErr(err)
-		}
-		outFile := filepath.Join(opts.OutDir, "ts.gen.ts")
-		if err := os.WriteFile(outFile, []byte(tsCode), 0o644); err != nil {
-			exitErr(err)
-		}
-		if opts.Verbose {
-			fmt.Fprintf(os.Stderr, "generated %s\n", outFile)
-		}
-	}
-
-	if opts.JSON {
-		data, err := json.MarshalIndent(jsonPayload, "", "  ")
-		if err != nil {
-			exitErr(err)
-		}
-		jsonFile := filepath.Join(opts.OutDir, "all.json")
-		if err := os.WriteFile(jsonFile, data, 0o644); err != nil {
-			exitErr(err)
-		}
-		if opts.Verbose {
-			fmt.Fprintf(os.Stderr, "generated %s\n", jsonFile)
-		}
-	}
-}
-
-func parseLangs(s string) (map[string]bool, error) {
-	s = strings.TrimSpace(strings.ToLower(s))
-	if s == "" || s == "all" {
-		return map[string]bool{"go": true, "Pb": true, "ts": true}, nil
-	}
-	parts := strings.Split(s, ",")
-	out := map[string]bool{"go": false, "Pb": false, "ts": false}
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
-		}
-		switch p {
-		case "go", "Pb", "ts":
-			out[p] = true
-		default:
-			return nil, fmt.Errorf("invalid --lang %q (expect go|Pb|ts|all or comma-separated)", s)
-		}
-	}
-	if !out["go"] && !out["Pb"] && !out["ts"] {
-		return nil, fmt.Errorf("invalid --lang %q (no targets)", s)
-	}
-	return out, nil
 }
 
 func exitErr(err error) {
 	fmt.Fprintln(os.Stderr, err.Error())
 	os.Exit(1)
 }
-
-func detectHeaderSpec(rows [][]string) (HeaderSpec, error) {
-	if len(rows) >= 3 && rowHasFieldDefs(rows[2]) {
-		ori := OrientationHorizontal
-		a1 := ""
-		if len(rows[0]) > 0 {
-			a1 = strings.TrimSpace(rows[0][0])
-		}
-		if a1 == "2" {
-			ori = OrientationVertical
-		}
-		return HeaderSpec{HeaderRows: 3, Orientation: ori, DefineRow: 3}, nil
-	}
-	if len(rows) >= 2 && rowHasFieldDefs(rows[1]) {
-		return HeaderSpec{HeaderRows: 2, Orientation: OrientationHorizontal, DefineRow: 2}, nil
-	}
-	if len(rows) >= 1 && rowHasFieldDefs(rows[0]) {
-		return HeaderSpec{HeaderRows: 1, Orientation: OrientationHorizontal, DefineRow: 1}, nil
-	}
-	return HeaderSpec{}, errors.New("cannot detect header")
-}
-
-func rowHasFieldDefs(row []string) bool {
-	for _, c := range row {
-		if strings.Contains(c, "#") {
-			return true
-		}
-	}
-	return false
-}
-
-var fieldRe = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*#\s*([^,\s]+)\s*(?:,\s*([sc]))?\s*$`)
-
-func parseFieldsFromDefineRow(rows [][]string, defineRow int, exportFlag string) ([]Field, error) {
-	if defineRow <= 0 || defineRow > len(rows) {
-		return nil, fmt.Errorf("define row %d out of range", defineRow)
-	}
-	row := rows[defineRow-1]
-	var fields []Field
-	for colIdx, cell := range row {
-		cell = strings.TrimSpace(cell)
-		if cell == "" {
-			continue
-		}
-
-		lower := strings.ToLower(cell)
-		if strings.Contains(lower, "#comment") || strings.Contains(lower, "#common") {
-			continue
-		}
-
-		m := fieldRe.FindStringSubmatch(cell)
-		if m == nil {
-			return nil, fmt.Errorf("invalid field def %q at row %d", cell, defineRow)
-		}
-		rawName := m[1]
-		rawType := m[2]
-		if strings.ToLower(rawType) == "comment" || strings.ToLower(rawType) == "common" {
-			continue
-		}
-		flagCh := m[3]
-
-		ff := FieldFlagAll
-		switch flagCh {
-		case "":
-			ff = FieldFlagAll
-		case "s":
-			ff = FieldFlagServer
-		case "c":
-			ff = FieldFlagClient
-		default:
-			ff = FieldFlagAll
-		}
-
-		if exportFlag != "" {
-			switch exportFlag {
-			case "server":
-				if ff == FieldFlagClient {
-					continue
-				}
-			case "client":
-				if ff == FieldFlagServer {
-					continue
-				}
-			default:
-				return nil, fmt.Errorf("invalid --flag %q (expect server|client)", exportFlag)
-			}
-		}
-
-		goType, ok := mapGoType(rawType)
-		if !ok {
-			return nil, fmt.Errorf("unsupported type %q", rawType)
-		}
-		fields = append(fields, Field{
-			RawName:  rawName,
-			Name:     exportName(rawName),
-			RawType:  rawType,
-			GoType:   goType,
-			Col:      colIdx,
-			Flag:     ff,
-			Exported: true,
-		})
-	}
-	if len(fields) == 0 {
-		return nil, errors.New("no exported fields found")
-	}
-	return fields, nil
-}
-
-func exportName(name string) string {
-	if name == "" {
-		return name
-	}
-	// If it's already camelCase, keep inner casing and just capitalize first letter.
-	if !strings.ContainsAny(name, "_-") {
-		return strings.ToUpper(name[:1]) + name[1:]
-	}
-	// cid => Cid, data_id => DataId
-	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
-	for i, p := range parts {
-		if p == "" {
-			continue
-		}
-		parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
-	}
-	return strings.Join(parts, "")
-}
-
-func mapGoType(t string) (string, bool) {
-	switch strings.ToLower(t) {
-	case "int", "int32", "int64":
-		return "int", true
-	case "int[]":
-		return "[]int", true
-	case "int[][]":
-		return "[][]int", true
-	case "float", "float32", "float64":
-		return "float64", true
-	case "bool":
-		return "bool", true
-	case "string":
-		return "string", true
-	default:
-		return "", false
-	}
-}
-
-func mapCSType(t string) (string, bool) {
-	switch strings.ToLower(t) {
-	case "int", "int32", "int64":
-		return "int", true
-	case "int[]":
-		return "List<int>", true
-	case "int[][]":
-		return "List<List<int>>", true
-	case "float", "float32", "float64":
-		return "double", true
-	case "bool":
-		return "bool", true
-	case "string":
-		return "string", true
-	default:
-		return "", false
-	}
-}
-
-func mapTSType(t string) (string, bool) {
-	switch strings.ToLower(t) {
-	case "int", "int32", "int64", "float", "float32", "float64":
-		return "number", true
-	case "int[]":
-		return "number[]", true
-	case "int[][]":
-		return "number[][]", true
-	case "bool":
-		return "boolean", true
-	case "string":
-		return "string", true
-	default:
-		return "", false
-	}
-}
-
-func generateGo(pkg, rootName, itemName string, fields []Field) (string, error) {
-	var b strings.Builder
-	b.WriteString("package ")
-	b.WriteString(pkg)
-	b.WriteString("\n\n")
-
-	b.WriteString("type ")
-	b.WriteString(rootName)
-	b.WriteString(" struct {\n")
-	b.WriteString("\tItems []")
-	b.WriteString(itemName)
-	b.WriteString("\n")
-	b.WriteString("}\n\n\n")
-
-	b.WriteString("type ")
-	b.WriteString(itemName)
-	b.WriteString(" struct {\n")
-	for _, f := range fields {
-		b.WriteString("\t")
-		b.WriteString(f.Name)
-		b.WriteString(" ")
-		b.WriteString(f.GoType)
-		b.WriteString(" `json:\"")
-		b.WriteString(f.RawName)
-		b.WriteString("\"`")
-		b.WriteString("\n")
-	}
-	b.WriteString("}\n")
-
-	return b.String(), nil
-}
-
-func generateCS(rootName, itemName string, fields []Field) (string, error) {
-	var b strings.Builder
-	b.WriteString("using System.Collections.Generic;\n")
-	b.WriteString("using System.Text.Json.Serialization;\n\n")
-
-	b.WriteString("public class ")
-	b.WriteString(rootName)
-	b.WriteString("\n{\n")
-	b.WriteString("    public List<")
-	b.WriteString(itemName)
-	b.WriteString("> Items { get; set; }\n")
-	b.WriteString("}\n\n")
-
-	b.WriteString("public class ")
-	b.WriteString(itemName)
-	b.WriteString("\n{\n")
-	for _, f := range fields {
-		csType, ok := mapCSType(f.RawType)
-		if !ok {
-			return "", fmt.Errorf("unsupported type %q", f.RawType)
-		}
-		b.WriteString("    [JsonPropertyName(\"")
-		b.WriteString(f.RawName)
-		b.WriteString("\")]\n")
-		b.WriteString("    public ")
-		b.WriteString(csType)
-		b.WriteString(" ")
-		b.WriteString(f.Name)
-		b.WriteString(" { get; set; }\n\n")
-	}
-	b.WriteString("}\n")
-	return b.String(), nil
-}
-
-func generateGoBundle(pkg, rootName string, orderedTypeNames []string, schemas map[string][]Field) (string, error) {
-	var b strings.Builder
-	b.WriteString("package ")
-	b.WriteString(pkg)
-	b.WriteString("\n\n")
-
-	// Root config
-	b.WriteString("type ")
-	b.WriteString(rootName)
-	b.WriteString(" struct {\n")
-	for _, typeName := range orderedTypeNames {
-		fieldName := pluralizeTypeName(typeName)
-		jsonKey := lowerFirst(fieldName)
-		b.WriteString("\t")
-		b.WriteString(fieldName)
-		b.WriteString(" []")
-		b.WriteString(typeName)
-		b.WriteString(" `json:\"")
-		b.WriteString(jsonKey)
-		b.WriteString("\"`\n")
-	}
-	b.WriteString("}\n\n")
-
-	// Types
-	for _, typeName := range orderedTypeNames {
-		fields := schemas[typeName]
-		b.WriteString("type ")
-		b.WriteString(typeName)
-		b.WriteString(" struct {\n")
-		for _, f := range fields {
-			b.WriteString("\t")
-			b.WriteString(f.Name)
-			b.WriteString(" ")
-			b.WriteString(f.GoType)
-			b.WriteString(" `json:\"")
-			b.WriteString(f.RawName)
-			b.WriteString("\"`\n")
-		}
-		b.WriteString("}\n\n")
-	}
-
-	return strings.TrimRight(b.String(), "\n") + "\n", nil
-}
-
-func generateCSBundle(rootName string, orderedTypeNames []string, schemas map[string][]Field) (string, error) {
-	var b strings.Builder
-	b.WriteString("using System.Collections.Generic;\n")
-	b.WriteString("using System.Text.Json.Serialization;\n\n")
-
-	b.WriteString("public class ")
-	b.WriteString(rootName)
-	b.WriteString("\n{\n")
-	for _, typeName := range orderedTypeNames {
-		fieldName := pluralizeTypeName(typeName)
-		jsonKey := lowerFirst(fieldName)
-		b.WriteString("    [JsonPropertyName(\"")
-		b.WriteString(jsonKey)
-		b.WriteString("\")]\n")
-		b.WriteString("    public List<")
-		b.WriteString(typeName)
-		b.WriteString("> ")
-		b.WriteString(fieldName)
-		b.WriteString(" { get; set; }\n\n")
-	}
-	b.WriteString("}\n\n")
-
-	for _, typeName := range orderedTypeNames {
-		fields := schemas[typeName]
-		b.WriteString("public class ")
-		b.WriteString(typeName)
-		b.WriteString("\n{\n")
-		for _, f := range fields {
-			csType, ok := mapCSType(f.RawType)
-			if !ok {
-				return "", fmt.Errorf("unsupported type %q", f.RawType)
-			}
-			b.WriteString("    [JsonPropertyName(\"")
-			b.WriteString(f.RawName)
-			b.WriteString("\")]\n")
-			b.WriteString("    public ")
-			b.WriteString(csType)
-			b.WriteString(" ")
-			b.WriteString(f.Name)
-			b.WriteString(" { get; set; }\n\n")
-		}
-		b.WriteString("}\n\n")
-	}
-
-	return strings.TrimRight(b.String(), "\n") + "\n", nil
-}
-
-func generateTSBundle(rootName string, orderedTypeNames []string, schemas map[string][]Field) (string, error) {
-	var b strings.Builder
-	for _, typeName := range orderedTypeNames {
-		fields := schemas[typeName]
-		b.WriteString("export interface ")
-		b.WriteString(typeName)
-		b.WriteString(" {\n")
-		for _, f := range fields {
-			tsType, ok := mapTSType(f.RawType)
-			if !ok {
-				return "", fmt.Errorf("unsupported type %q", f.RawType)
-			}
-			b.WriteString("  ")
-			b.WriteString(f.RawName)
-			b.WriteString(": ")
-			b.WriteString(tsType)
-			b.WriteString(";\n")
-		}
-		b.WriteString("}\n\n")
-	}
-
-	b.WriteString("export interface ")
-	b.WriteString(rootName)
-	b.WriteString(" {\n")
-	for _, typeName := range orderedTypeNames {
-		fieldName := pluralizeTypeName(typeName)
-		jsonKey := lowerFirst(fieldName)
-		b.WriteString("  ")
-		b.WriteString(jsonKey)
-		b.WriteString(": ")
-		b.WriteString(typeName)
-		b.WriteString("[];\n")
-	}
-	b.WriteString("}\n")
-
-	return b.String(), nil
-}
-
-func generateTS(rootName, itemName string, fields []Field) (string, error) {
-	var b strings.Builder
-	b.WriteString("export interface ")
-	b.WriteString(itemName)
-	b.WriteString(" {\n")
-	for _, f := range fields {
-		tsType, ok := mapTSType(f.RawType)
-		if !ok {
-			return "", fmt.Errorf("unsupported type %q", f.RawType)
-		}
-		b.WriteString("  ")
-		b.WriteString(f.RawName)
-		b.WriteString(": ")
-		b.WriteString(tsType)
-		b.WriteString(";\n")
-	}
-	b.WriteString("}\n\n")
-
-	b.WriteString("export interface ")
-	b.WriteString(rootName)
-	b.WriteString(" {\n")
-	b.WriteString("  Items: ")
-	b.WriteString(itemName)
-	b.WriteString("[];\n")
-	b.WriteString("}\n")
-	return b.String(), nil
-}
-
-func readHorizontalItems(rows [][]string, dataStartRow int, fields []Field) ([]map[string]any, error) {
-	if dataStartRow <= 0 {
-		dataStartRow = 1
-	}
-	var items []map[string]any
-	for r := dataStartRow - 1; r < len(rows); r++ {
-		row := rows[r]
-		if isEmptyRow(row) {
-			continue
-		}
-		obj := make(map[string]any, len(fields))
-		for _, field := range fields {
-			cell := ""
-			if field.Col >= 0 && field.Col < len(row) {
-				cell = strings.TrimSpace(row[field.Col])
-			}
-			v, err := parseCellValue(field.RawType, cell)
-			if err != nil {
-				return nil, fmt.Errorf("row %d col %d (%s): %w", r+1, field.Col+1, field.RawName, err)
-			}
-			obj[field.RawName] = v
-		}
-		items = append(items, obj)
-	}
-	return items, nil
-}
-
-func isEmptyRow(row []string) bool {
-	for _, c := range row {
-		if strings.TrimSpace(c) != "" {
-			return false
-		}
-	}
-	return true
-}
-
-func parseCellValue(rawType string, s string) (any, error) {
-	if s == "" {
-		switch strings.ToLower(rawType) {
-		case "int", "int32", "int64":
-			return 0, nil
-		case "int[]":
-			return []int{}, nil
-		case "int[][]":
-			return [][]int{}, nil
-		case "float", "float32", "float64":
-			return float64(0), nil
-		case "bool":
-			return false, nil
-		case "string":
-			return "", nil
-		default:
-			return nil, fmt.Errorf("unsupported type %q", rawType)
-		}
-	}
-
-	switch strings.ToLower(rawType) {
-	case "int", "int32", "int64":
-		v, err := strconv.Atoi(s)
-		if err != nil {
-			return nil, err
-		}
-		return v, nil
-	case "int[]":
-		var v []int
-		if err := parseBraceArrayJSON(s, &v); err != nil {
-			return nil, err
-		}
-		return v, nil
-	case "int[][]":
-		var v [][]int
-		if err := parseBraceArrayJSON(s, &v); err != nil {
-			return nil, err
-		}
-		return v, nil
-	case "float", "float32", "float64":
-		v, err := strconv.ParseFloat(s, 64)
-		if err != nil {
-			return nil, err
-		}
-		return v, nil
-	case "bool":
-		ls := strings.ToLower(s)
-		if ls == "1" {
-			return true, nil
-		}
-		if ls == "0" {
-			return false, nil
-		}
-		v, err := strconv.ParseBool(ls)
-		if err != nil {
-			return nil, err
-		}
-		return v, nil
-	case "string":
-		return s, nil
-	default:
-		return nil, fmt.Errorf("unsupported type %q", rawType)
-	}
-}
-
-func parseBraceArrayJSON(s string, out any) error {
-	s = strings.TrimSpace(s)
-	s = strings.Trim(s, "\"")
-	if s == "" || s == "{}" {
-		s = "[]"
-	}
-	// Convert Lua-like braces to JSON arrays.
-	s = strings.ReplaceAll(s, "{", "[")
-	s = strings.ReplaceAll(s, "}", "]")
-	if !strings.HasPrefix(strings.TrimSpace(s), "[") {
-		s = "[" + s + "]"
-	}
-	return json.Unmarshal([]byte(s), out)
-}
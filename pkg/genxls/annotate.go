@@ -0,0 +1,160 @@
+package genxls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// annotateFill is the background of highlighted cells, Excel's "bad" red.
+const annotateFill = "FFC7CE"
+
+// badCellProblems returns the cells replaced by zero values as problems.
+func badCellProblems(sheets []*Sheet) []Problem {
+	var problems []Problem
+	for _, sheet := range sheets {
+		for _, e := range sheet.BadCells {
+			p := problemf(sheet, "%s: %v", sheet.Origin, e)
+			p.Row, p.Col = e.Row, e.Col
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// writeAnnotatedWorkbooks writes a copy of every workbook with a problem about
+// a cell into dir, under its base name, with those cells filled red and the
+// problems in a cell comment. It returns the files written. Tab-separated
+// inputs can't hold either and are skipped with a warning.
+func writeAnnotatedWorkbooks(dir string, problems []Problem, cfg *Config, password string) ([]string, error) {
+	byFile := make(map[string][]Problem)
+	var files []string
+	for _, p := range problems {
+		if p.Sheet == nil || p.Row <= 0 || p.Col <= 0 {
+			continue
+		}
+		if _, ok := byFile[p.Sheet.File]; !ok {
+			files = append(files, p.Sheet.File)
+		}
+		byFile[p.Sheet.File] = append(byFile[p.Sheet.File], p)
+	}
+	if len(files) > 0 {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	var written []string
+	seen := make(map[string]string) // output path -> input
+	for _, file := range files {
+		pw := cfg.PasswordFor(file, password)
+		f, err := OpenWorkbook(file, pw)
+		if err != nil {
+			return written, err
+		}
+		if f == nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: not a workbook, no annotated copy\n", file)
+			continue
+		}
+		path := filepath.Join(dir, filepath.Base(file))
+		if prev, ok := seen[path]; ok {
+			_ = f.Close()
+			return written, fmt.Errorf("--annotate: %s and %s would both be written to %s", prev, file, path)
+		}
+		seen[path] = file
+		err = annotateWorkbook(f, byFile[file])
+		if err == nil {
+			var opts []excelize.Options
+			if pw != "" {
+				opts = append(opts, excelize.Options{Password: pw})
+			}
+			err = f.SaveAs(path, opts...)
+		}
+		_ = f.Close()
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", file, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// annotateWorkbook highlights the cells of problems, all from f, and lists
+// their messages in a comment, after any comment the cell already had.
+func annotateWorkbook(f *excelize.File, problems []Problem) error {
+	type cellKey struct {
+		sheet string
+		cell  string
+	}
+	var keys []cellKey
+	msgs := make(map[cellKey][]string)
+	for _, p := range problems {
+		cell, err := excelize.CoordinatesToCellName(p.Col, p.Row)
+		if err != nil {
+			return err
+		}
+		k := cellKey{p.Sheet.Name, cell}
+		if _, ok := msgs[k]; !ok {
+			keys = append(keys, k)
+		}
+		// The comment sits on the cell, so drop the "file[sheet]" prefix.
+		msg := strings.TrimLeft(strings.TrimPrefix(p.Msg, p.Sheet.Origin), ": ")
+		msgs[k] = append(msgs[k], msg)
+	}
+
+	existing := make(map[cellKey]string)
+	loaded := make(map[string]bool)
+	for _, k := range keys {
+		if loaded[k.sheet] {
+			continue
+		}
+		loaded[k.sheet] = true
+		comments, err := f.GetComments(k.sheet)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			existing[cellKey{k.sheet, c.Cell}] = commentText(c)
+		}
+	}
+
+	styles := make(map[int]int) // original style -> highlighted style
+	for _, k := range keys {
+		orig, err := f.GetCellStyle(k.sheet, k.cell)
+		if err != nil {
+			return err
+		}
+		style, ok := styles[orig]
+		if !ok {
+			s, err := f.GetStyle(orig)
+			if err != nil {
+				return err
+			}
+			s.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{annotateFill}}
+			if style, err = f.NewStyle(s); err != nil {
+				return err
+			}
+			styles[orig] = style
+		}
+		if err := f.SetCellStyle(k.sheet, k.cell, k.cell, style); err != nil {
+			return err
+		}
+
+		// The text says where it came from: excelize attributes comments by an
+		// author already in the sheet to its first author.
+		text := "genxls: " + strings.Join(msgs[k], "\n")
+		if prev, ok := existing[k]; ok {
+			if err := f.DeleteComment(k.sheet, k.cell); err != nil {
+				return err
+			}
+			text = prev + "\n\n" + text
+		}
+		if err := f.AddComment(k.sheet, excelize.Comment{Cell: k.cell, Author: "genxls", Text: text}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
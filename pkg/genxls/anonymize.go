@@ -0,0 +1,157 @@
+package genxls
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	mrand "math/rand/v2"
+	"strings"
+	"time"
+)
+
+// anonymizeJitter is the largest relative change applied to a number.
+const anonymizeJitter = 0.1
+
+// anonymizeSheets scrambles every exported value in place while keeping the
+// schema and the shape of the data: strings become keyed hashes (equal strings
+// stay equal, so string keys still match across sheets), numbers move by up to
+// ±10% within their column's range, and datetimes by up to 10% of the sheet's
+// time span (see anonymizeTimes). Integer primary keys, bools and int arrays,
+// usually ids and flags, are kept, and so are ref columns and the columns they
+// point at, so references still resolve. An empty key picks a random one.
+func anonymizeSheets(sheets []*Sheet, key string) error {
+	k := []byte(key)
+	if len(k) == 0 {
+		k = make([]byte, 32)
+		if _, err := rand.Read(k); err != nil {
+			return err
+		}
+	}
+	seed := sha256.Sum256(k)
+	rng := mrand.New(mrand.NewPCG(binary.LittleEndian.Uint64(seed[:8]), binary.LittleEndian.Uint64(seed[8:16])))
+	// Refs point at int or string columns; strings hash alike on both sides.
+	kept := make(map[*Field]bool)
+	for _, sheet := range sheets {
+		for i := range sheet.Fields {
+			f := &sheet.Fields[i]
+			if f.Ref == nil {
+				continue
+			}
+			kept[f] = true
+			if target := FindSheet(sheets, f.Ref.Sheet); target != nil {
+				if tf := refTarget(target, f.Ref); tf != nil {
+					kept[tf] = true
+				}
+			}
+		}
+	}
+	for _, sheet := range sheets {
+		stats := make(map[string]ColumnStats)
+		for _, st := range numericColumnStats(sheet) {
+			stats[st.Field.RawName] = st
+		}
+		times := anonymizeTimes(rng, sheet)
+		for j := range sheet.Fields {
+			f := &sheet.Fields[j]
+			st, numeric := stats[f.RawName]
+			if times[f.RawName] {
+				continue
+			}
+			for _, item := range sheet.Items {
+				switch v := item[f.RawName].(type) {
+				case string:
+					item[f.RawName] = anonymizeString(k, v)
+				case int:
+					if j > 0 && numeric && !kept[f] {
+						item[f.RawName] = int(math.Round(jitter(rng, float64(v), st)))
+					}
+				case float64:
+					if numeric {
+						item[f.RawName] = jitter(rng, v, st)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// anonymizeTimes moves the datetime cells of every row by one random offset
+// of up to anonymizeJitter of the sheet's time span, clamped to each column's
+// range, so windows stay ordered. It returns the columns it handled.
+func anonymizeTimes(rng *mrand.Rand, sheet *Sheet) map[string]bool {
+	type span struct{ min, max time.Time }
+	cols := make(map[string]*span)
+	var all *span
+	widen := func(sp *span, t time.Time) *span {
+		if sp == nil {
+			return &span{t, t}
+		}
+		if t.Before(sp.min) {
+			sp.min = t
+		}
+		if t.After(sp.max) {
+			sp.max = t
+		}
+		return sp
+	}
+	for _, f := range sheet.Fields {
+		if strings.ToLower(f.RawType) != "datetime" {
+			continue
+		}
+		cols[f.RawName] = nil
+		for _, item := range sheet.Items {
+			v, _ := item[f.RawName].(string)
+			if t, err := time.Parse(dateTimeLayout, v); err == nil {
+				cols[f.RawName] = widen(cols[f.RawName], t)
+				all = widen(all, t)
+			}
+		}
+	}
+	handled := make(map[string]bool, len(cols))
+	for name := range cols {
+		handled[name] = true
+	}
+	if all == nil {
+		return handled
+	}
+	width := float64(all.max.Sub(all.min))
+	for _, item := range sheet.Items {
+		d := time.Duration(width * anonymizeJitter * (2*rng.Float64() - 1)).Truncate(time.Second)
+		for name, sp := range cols {
+			v, _ := item[name].(string)
+			t, err := time.Parse(dateTimeLayout, v)
+			if err != nil || sp == nil {
+				continue
+			}
+			t = t.Add(d)
+			if t.Before(sp.min) {
+				t = sp.min
+			}
+			if t.After(sp.max) {
+				t = sp.max
+			}
+			item[name] = formatDateTime(t)
+		}
+	}
+	return handled
+}
+
+func anonymizeString(key []byte, s string) string {
+	if s == "" {
+		return s
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return "x" + hex.EncodeToString(mac.Sum(nil))[:11]
+}
+
+// jitter moves v by up to anonymizeJitter of its magnitude, clamped to the
+// column's observed min and max.
+func jitter(rng *mrand.Rand, v float64, st ColumnStats) float64 {
+	v += v * anonymizeJitter * (2*rng.Float64() - 1)
+	return math.Min(math.Max(v, st.Min), st.Max)
+}
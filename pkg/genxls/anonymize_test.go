@@ -0,0 +1,74 @@
+package genxls
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAnonymizeKeepsRefs(t *testing.T) {
+	items := [][]string{{"id#int", "name#string", "slot#int", "power#int"}}
+	drops := [][]string{{"id#int", "item#ref:Item", "slot#ref:Item.slot", "name#ref:Item.name"}}
+	for i := 1; i <= 50; i++ {
+		items = append(items, []string{fmt.Sprint(i), fmt.Sprint("item", i), fmt.Sprint(100 + i), fmt.Sprint(10 * i)})
+		drops = append(drops, []string{fmt.Sprint(i), fmt.Sprint(51 - i), fmt.Sprint(100 + i), fmt.Sprint("item", i)})
+	}
+	src := NewMemorySource("test").Add("Item", items).Add("Drop", drops)
+	sheets, err := LoadSources(context.Background(), []SheetSource{src}, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := anonymizeSheets(sheets, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if problems := checkRefs(sheets); len(problems) > 0 {
+		t.Fatalf("broken refs after anonymizing: %v", problems)
+	}
+	moved := 0
+	for i, item := range sheets[0].Items {
+		if item["power"] != 10*(i+1) {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Error("plain int column was not jittered")
+	}
+}
+
+func TestAnonymizeJittersTimes(t *testing.T) {
+	rows := [][]string{{"id#int", "start_time#datetime", "end_time#datetime"}}
+	for i := 1; i <= 50; i++ {
+		rows = append(rows, []string{fmt.Sprint(i), fmt.Sprintf("2024-01-%02dT00:00:00", i%28+1), fmt.Sprintf("2024-02-%02dT12:00:00", i%28+1)})
+	}
+	sheets, err := LoadSources(context.Background(), []SheetSource{NewMemorySource("test").Add("Event", rows)}, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := anonymizeSheets(sheets, "key"); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for i, item := range sheets[0].Items {
+		start, err := time.Parse(dateTimeLayout, item["start_time"].(string))
+		if err != nil {
+			t.Fatalf("row %d: start_time %v is no longer a datetime", i+1, item["start_time"])
+		}
+		end, err := time.Parse(dateTimeLayout, item["end_time"].(string))
+		if err != nil {
+			t.Fatalf("row %d: end_time %v is no longer a datetime", i+1, item["end_time"])
+		}
+		if end.Before(start) {
+			t.Errorf("row %d: window %v..%v out of order", i+1, start, end)
+		}
+		if start.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || start.After(time.Date(2024, 1, 28, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("row %d: start_time %v outside the column's range", i+1, start)
+		}
+		if item["start_time"] != rows[i+1][1] {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Error("datetimes were not jittered")
+	}
+}
@@ -0,0 +1,200 @@
+package genxls
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+type avroField struct {
+	Name string `json:"name"`
+	Type any    `json:"type"`
+}
+
+type avroRecord struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name"`
+	Fields []avroField `json:"fields"`
+}
+
+type avroArray struct {
+	Type  string `json:"type"`
+	Items any    `json:"items"`
+}
+
+func mapAvroType(t string) (any, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return "long", true
+	case "int[]":
+		return avroArray{Type: "array", Items: "long"}, true
+	case "int[][]":
+		return avroArray{Type: "array", Items: avroArray{Type: "array", Items: "long"}}, true
+	case "float", "float32", "float64":
+		return "double", true
+	case "bool":
+		return "boolean", true
+	case "string", "datetime":
+		return "string", true
+	default:
+		return nil, false
+	}
+}
+
+func generateAvroSchema(typeName string, fields []Field) ([]byte, error) {
+	rec := avroRecord{Type: "record", Name: typeName}
+	for _, f := range fields {
+		t, ok := mapAvroType(f.RawType)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %q", f.RawType)
+		}
+		rec.Fields = append(rec.Fields, avroField{Name: f.RawName, Type: t})
+	}
+	return json.MarshalIndent(rec, "", "  ")
+}
+
+// writeAvroBundle writes <jsonKey>.avsc and an uncompressed Avro object
+// container file <jsonKey>.avro per sheet, returning the written paths.
+func writeAvroBundle(out *OutputLayout, sheets []*Sheet) ([]string, error) {
+	var written []string
+	for _, sheet := range sheets {
+		fields := sheet.Fields
+		schema, err := generateAvroSchema(sheet.TypeName, fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sheet.TypeName, err)
+		}
+		schemaFile, err := out.WriteFile("avro", sheet.JSONKey+".avsc", sheet, schema)
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, schemaFile)
+
+		data, err := encodeAvroContainer(schema, fields, sheet.Items)
+		if err != nil {
+			return nil, fmt.Errorf("%s: avro: %w", sheet.Origin, err)
+		}
+		dataFile, err := out.WriteFile("avro", sheet.JSONKey+".avro", sheet, data)
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, dataFile)
+	}
+	return written, nil
+}
+
+func encodeAvroContainer(schema []byte, fields []Field, items []map[string]any) ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("Obj\x01")
+
+	// File metadata is an avro map<bytes>: one block with two entries, then the end marker.
+	writeAvroLong(&out, 2)
+	writeAvroBytes(&out, []byte("avro.schema"))
+	writeAvroBytes(&out, schema)
+	writeAvroBytes(&out, []byte("avro.codec"))
+	writeAvroBytes(&out, []byte("null"))
+	writeAvroLong(&out, 0)
+
+	// Derive the sync marker from the schema so repeated runs produce identical files.
+	sync := md5.Sum(schema)
+	out.Write(sync[:])
+
+	if len(items) == 0 {
+		return out.Bytes(), nil
+	}
+	var block bytes.Buffer
+	for i, item := range items {
+		for _, f := range fields {
+			if err := writeAvroValue(&block, f.RawType, item[f.RawName]); err != nil {
+				return nil, fmt.Errorf("data row %d column %s: %w", i+1, f.RawName, err)
+			}
+		}
+	}
+	writeAvroLong(&out, int64(len(items)))
+	writeAvroLong(&out, int64(block.Len()))
+	out.Write(block.Bytes())
+	out.Write(sync[:])
+	return out.Bytes(), nil
+}
+
+// writeAvroValue encodes v as rawType; a value of another Go type (say, a
+// float a script left in an int column) is an error, not a zero value.
+func writeAvroValue(b *bytes.Buffer, rawType string, v any) error {
+	mismatch := func() error {
+		return fmt.Errorf("%T value %v, want %s", v, v, rawType)
+	}
+	switch strings.ToLower(rawType) {
+	case "int", "int32", "int64":
+		n, ok := v.(int)
+		if !ok {
+			return mismatch()
+		}
+		writeAvroLong(b, int64(n))
+	case "int[]":
+		list, ok := v.([]int)
+		if !ok {
+			return mismatch()
+		}
+		if len(list) > 0 {
+			writeAvroLong(b, int64(len(list)))
+			for _, n := range list {
+				writeAvroLong(b, int64(n))
+			}
+		}
+		writeAvroLong(b, 0)
+	case "int[][]":
+		list, ok := v.([][]int)
+		if !ok {
+			return mismatch()
+		}
+		if len(list) > 0 {
+			writeAvroLong(b, int64(len(list)))
+			for _, inner := range list {
+				if err := writeAvroValue(b, "int[]", inner); err != nil {
+					return err
+				}
+			}
+		}
+		writeAvroLong(b, 0)
+	case "float", "float32", "float64":
+		n, ok := v.(float64)
+		if !ok {
+			return mismatch()
+		}
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(n))
+		b.Write(buf[:])
+	case "bool":
+		t, ok := v.(bool)
+		if !ok {
+			return mismatch()
+		}
+		if t {
+			b.WriteByte(1)
+		} else {
+			b.WriteByte(0)
+		}
+	case "string", "datetime":
+		s, ok := v.(string)
+		if !ok {
+			return mismatch()
+		}
+		writeAvroBytes(b, []byte(s))
+	default:
+		return fmt.Errorf("unsupported type %q", rawType)
+	}
+	return nil
+}
+
+func writeAvroLong(b *bytes.Buffer, n int64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutVarint(buf[:], n)])
+}
+
+func writeAvroBytes(b *bytes.Buffer, p []byte) {
+	writeAvroLong(b, int64(len(p)))
+	b.Write(p)
+}
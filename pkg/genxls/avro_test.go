@@ -0,0 +1,22 @@
+package genxls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAvroTypeMismatch(t *testing.T) {
+	fields := []Field{{RawName: "id", RawType: "int"}, {RawName: "rate", RawType: "int"}}
+	schema, err := generateAvroSchema("Item", fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []map[string]any{{"id": 1, "rate": 2}, {"id": 2, "rate": 2.5}}
+	_, err = encodeAvroContainer(schema, fields, items)
+	if err == nil || !strings.Contains(err.Error(), "data row 2 column rate: float64 value 2.5, want int") {
+		t.Errorf("got %v, want a type mismatch naming the row and column", err)
+	}
+	if _, err := encodeAvroContainer(schema, fields, items[:1]); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,249 @@
+package genxls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	rand "math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// BenchShape is a kind of synthetic sheet `genxls bench` parses: its define
+// row and a generator for data row i.
+type BenchShape struct {
+	Name   string
+	define []string
+	Rows   func(n int) int // data rows for --rows n
+	row    func(i, distinct int, rng *rand.Rand) []string
+}
+
+// benchShapes cover the tables that stress different parts of the exporter.
+var benchShapes = []BenchShape{
+	{
+		// A unique key plus the repetitive columns typical of game tables.
+		Name:   "tall",
+		define: []string{"id#int", "level#int", "kind#string", "weight#float", "active#bool", "drops#int[]", "name#string"},
+		Rows:   func(n int) int { return n },
+		row: func(i, distinct int, rng *rand.Rand) []string {
+			return []string{
+				strconv.Itoa(i + 1),
+				strconv.Itoa(rng.IntN(distinct) + 1000),
+				"kind_" + strconv.Itoa(rng.IntN(distinct)),
+				strconv.FormatFloat(float64(rng.IntN(distinct))/4, 'g', -1, 64),
+				strconv.FormatBool(rng.IntN(2) == 1),
+				"{" + strconv.Itoa(rng.IntN(distinct)) + "," + strconv.Itoa(rng.IntN(distinct)) + "}",
+				"Item " + strconv.Itoa(i+1),
+			}
+		},
+	},
+	{
+		// 200 numeric and string columns, a twentieth of the rows.
+		Name:   "wide",
+		define: benchWideDefine(),
+		Rows:   func(n int) int { return max(n/20, 1) },
+		row: func(i, distinct int, rng *rand.Rand) []string {
+			row := []string{strconv.Itoa(i + 1)}
+			for c := 1; c < 200; c++ {
+				switch c % 3 {
+				case 0:
+					row = append(row, strconv.Itoa(rng.IntN(distinct)))
+				case 1:
+					row = append(row, strconv.FormatFloat(float64(rng.IntN(distinct))/8, 'g', -1, 64))
+				default:
+					row = append(row, "v"+strconv.Itoa(rng.IntN(distinct)))
+				}
+			}
+			return row
+		},
+	},
+	{
+		// Long, mostly unique arrays, which bypass the cell cache's value sharing.
+		Name:   "arrays",
+		define: []string{"id#int", "path#int[]", "costs#int[][]", "tags#int[]"},
+		Rows:   func(n int) int { return max(n/4, 1) },
+		row: func(i, distinct int, rng *rand.Rand) []string {
+			list := func(n int) string {
+				parts := make([]string, n)
+				for j := range parts {
+					parts[j] = strconv.Itoa(rng.IntN(distinct * 100))
+				}
+				return "{" + strings.Join(parts, ",") + "}"
+			}
+			nested := make([]string, rng.IntN(4)+1)
+			for j := range nested {
+				nested[j] = list(rng.IntN(4) + 1)
+			}
+			return []string{strconv.Itoa(i + 1), list(rng.IntN(16) + 1), "{" + strings.Join(nested, ",") + "}", list(rng.IntN(3))}
+		},
+	},
+	{
+		// Unique localized text, as in dialogue and quest tables.
+		Name:   "text",
+		define: []string{"id#int", "title#string", "body#string", "hint#string"},
+		Rows:   func(n int) int { return max(n/4, 1) },
+		row: func(i, distinct int, rng *rand.Rand) []string {
+			return []string{
+				strconv.Itoa(i + 1),
+				"任务 " + strconv.Itoa(i+1),
+				strings.Repeat("前往北方的村庄，与长老交谈。", rng.IntN(6)+1) + strconv.Itoa(i),
+				"提示 " + strconv.Itoa(rng.IntN(distinct)),
+			}
+		},
+	},
+}
+
+func benchWideDefine() []string {
+	define := []string{"id#int"}
+	for c := 1; c < 200; c++ {
+		define = append(define, fmt.Sprintf("c%d#%s", c, [3]string{"int", "float", "string"}[c%3]))
+	}
+	return define
+}
+
+// BenchResult is one measurement; --save writes them and --baseline compares
+// against them per shape and mode.
+type BenchResult struct {
+	Shape        string  `json:"shape"`
+	Mode         string  `json:"mode"`
+	Rows         int     `json:"rows"`
+	NsPerRow     float64 `json:"nsPerRow"`
+	AllocsPerRow float64 `json:"allocsPerRow"`
+}
+
+// SelectBenchShapes returns the shapes --shape names: "all" or a
+// comma-separated list.
+func SelectBenchShapes(s string) ([]BenchShape, error) {
+	if s == "all" {
+		return benchShapes, nil
+	}
+	var names []string
+	for _, shape := range benchShapes {
+		names = append(names, shape.Name)
+	}
+	var out []BenchShape
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(benchShapes, func(b BenchShape) bool { return b.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("invalid --shape %q (expect %s|all)%s", name, strings.Join(names, "|"), didYouMean(name, names))
+		}
+		out = append(out, benchShapes[i])
+	}
+	return out, nil
+}
+
+// BenchGrid returns a define row followed by n data rows.
+func BenchGrid(shape BenchShape, n, distinct int, rng *rand.Rand) [][]string {
+	grid := make([][]string, 0, n+1)
+	grid = append(grid, shape.define)
+	for i := 0; i < n; i++ {
+		grid = append(grid, shape.row(i, distinct, rng))
+	}
+	return grid
+}
+
+func WriteBenchWorkbook(path string, grid [][]string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		return err
+	}
+	for i, row := range grid {
+		cells := make([]any, len(row))
+		for j, v := range row {
+			cells[j] = v
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, cells); err != nil {
+			return err
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	if err := f.SetSheetName("Sheet1", "Bench"); err != nil {
+		return err
+	}
+	return f.SaveAs(path)
+}
+
+type BenchRun struct {
+	Elapsed    time.Duration
+	Allocs     uint64
+	AllocBytes uint64
+	Live       uint64
+	GCs        uint32
+}
+
+// BenchMeasure parses the grid (parse, parse+cache) or loads the workbook at
+// path (xlsx, xlsx stream) and encodes the result as all.json.
+func BenchMeasure(mode string, grid [][]string, fields []Field, path string) (BenchRun, error) {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var sheets []*Sheet
+	switch mode {
+	case "parse", "parse+cache":
+		items, rowNums, err := readHorizontalItems(grid, 2, fields, CellModeDefault, mode == "parse+cache", nil)
+		if err != nil {
+			return BenchRun{}, err
+		}
+		sheets = []*Sheet{{JSONKey: "items", Fields: fields, Items: items, Rows: rowNums}}
+	case "xlsx", "xlsx stream":
+		var opts Options
+		if mode == "xlsx stream" {
+			opts.StreamSize = 1 // any workbook
+		}
+		var err error
+		if sheets, err = LoadSheets(context.Background(), []string{path}, opts, nil); err != nil {
+			return BenchRun{}, err
+		}
+	default:
+		return BenchRun{}, errors.New("unknown mode")
+	}
+	if _, err := json.Marshal(buildJSONPayload(sheets)); err != nil {
+		return BenchRun{}, err
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(sheets)
+	return BenchRun{
+		Elapsed:    elapsed,
+		Allocs:     after.Mallocs - before.Mallocs,
+		AllocBytes: after.TotalAlloc - before.TotalAlloc,
+		Live:       after.HeapAlloc - min(after.HeapAlloc, before.HeapAlloc),
+		GCs:        after.NumGC - before.NumGC,
+	}, nil
+}
+
+// BenchRegressions compares results with a baseline of the same shapes,
+// modes and row counts; measurements the baseline lacks are not compared.
+func BenchRegressions(base, results []BenchResult, tolerance float64) []string {
+	var out []string
+	for _, r := range results {
+		for _, b := range base {
+			if b.Shape != r.Shape || b.Mode != r.Mode || b.Rows != r.Rows {
+				continue
+			}
+			if r.NsPerRow > b.NsPerRow*(1+tolerance) {
+				out = append(out, fmt.Sprintf("%s %s: %.0fns per row, was %.0fns", r.Shape, r.Mode, r.NsPerRow, b.NsPerRow))
+			}
+			if r.AllocsPerRow > b.AllocsPerRow*(1+tolerance) {
+				out = append(out, fmt.Sprintf("%s %s: %.1f allocations per row, was %.1f", r.Shape, r.Mode, r.AllocsPerRow, b.AllocsPerRow))
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package genxls
+
+import (
+	rand "math/rand/v2"
+	"testing"
+)
+
+// benchmarkRows is the --rows of the sheets the benchmarks parse; shapes
+// scale it as `genxls bench` does. -short uses a thousandth.
+const benchmarkRows = 1_000_000
+
+// BenchmarkParse parses synthetic sheets without (unpooled) and with (pooled)
+// the cell cache, as `genxls bench` does, e.g.:
+//
+//	go test ./pkg/genxls -run '^$' -bench Parse/tall -benchtime 3x
+func BenchmarkParse(b *testing.B) {
+	rows := benchmarkRows
+	if testing.Short() {
+		rows /= 1000
+	}
+	for _, shape := range benchShapes {
+		b.Run(shape.Name, func(b *testing.B) {
+			n := shape.Rows(rows)
+			grid := BenchGrid(shape, n, 100, rand.New(rand.NewPCG(1, 0)))
+			fields, err := ParseFieldsFromDefineRow(grid, 1, "")
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, mode := range []struct {
+				name   string
+				pooled bool
+			}{{"unpooled", false}, {"pooled", true}} {
+				b.Run(mode.name, func(b *testing.B) {
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						if _, _, err := readHorizontalItems(grid, 2, fields, CellModeDefault, mode.pooled, nil); err != nil {
+							b.Fatal(err)
+						}
+					}
+					b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/row")
+				})
+			}
+		})
+	}
+}
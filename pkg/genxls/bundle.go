@@ -0,0 +1,64 @@
+package genxls
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// bundleMarkerPrefix marks a row-1 cell of a 3-row header that tags the sheet,
+// e.g. "bundle:event" or "bundle:event,seasonal".
+const bundleMarkerPrefix = "bundle:"
+
+// sheetMarkerBundles returns the bundles named by marker cells. Only 3-row
+// headers have a free first row; A1 stays the orientation marker.
+func sheetMarkerBundles(rows [][]string, spec HeaderSpec) []string {
+	if spec.HeaderRows != 3 || len(rows[0]) < 2 {
+		return nil
+	}
+	var bundles []string
+	for _, c := range rows[0][1:] {
+		c = strings.TrimSpace(c)
+		if len(c) < len(bundleMarkerPrefix) || !strings.EqualFold(c[:len(bundleMarkerPrefix)], bundleMarkerPrefix) {
+			continue
+		}
+		bundles = mergeBundles(bundles, strings.Split(c[len(bundleMarkerPrefix):], ","))
+	}
+	return bundles
+}
+
+// mergeBundles adds the trimmed, lower-cased names in add to bundles, keeping
+// the result sorted and free of duplicates.
+func mergeBundles(bundles, add []string) []string {
+	for _, b := range add {
+		b = strings.ToLower(strings.TrimSpace(b))
+		if b != "" && !slices.Contains(bundles, b) {
+			bundles = append(bundles, b)
+		}
+	}
+	slices.Sort(bundles)
+	return bundles
+}
+
+// filterBundle keeps the sheets tagged with bundle.
+func filterBundle(sheets []*Sheet, bundle string) ([]*Sheet, error) {
+	bundle = strings.ToLower(bundle)
+	var kept []*Sheet
+	var known []string
+	for _, sheet := range sheets {
+		known = mergeBundles(known, sheet.Bundles)
+		if slices.Contains(sheet.Bundles, bundle) {
+			kept = append(kept, sheet)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no sheets in bundle %q%s; known bundles: %s",
+			bundle, didYouMean(bundle, known), strings.Join(known, ", "))
+	}
+	return kept, nil
+}
+
+// bundleRootName is the generated root type for a bundle, e.g. EventConfig.
+func bundleRootName(bundle string) string {
+	return exportName(strings.ToLower(bundle)) + "Config"
+}
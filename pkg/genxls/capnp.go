@@ -0,0 +1,336 @@
+package genxls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	capnpTypeNameRe  = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	capnpFieldNameRe = regexp.MustCompile(`^[a-z][A-Za-z0-9]*$`)
+)
+
+// capnpType maps a column type to its Cap'n Proto type.
+func capnpType(rawType string) (string, bool) {
+	switch strings.ToLower(rawType) {
+	case "int", "int64":
+		return "Int64", true
+	case "int32":
+		return "Int32", true
+	case "float", "float64":
+		return "Float64", true
+	case "float32":
+		return "Float32", true
+	case "bool":
+		return "Bool", true
+	case "string", "datetime":
+		return "Text", true
+	case "int[]":
+		return "List(Int64)", true
+	case "int[][]":
+		return "List(List(Int64))", true
+	default:
+		return "", false
+	}
+}
+
+// capnpFieldName is the camelCase name Cap'n Proto requires for a column:
+// itemId for item_id.
+func capnpFieldName(f Field) string {
+	return lowerFirst(f.Name)
+}
+
+// capnpFileID derives the schema file's 64-bit id from the root name, so it is
+// stable across runs; Cap'n Proto requires the high bit set.
+func capnpFileID(rootName string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte("genxls:" + rootName))
+	return h.Sum64() | 1<<63
+}
+
+// GenerateCapnpSchema renders capnp.gen.capnp: the root struct with a list per
+// sheet and a struct per sheet, numbered in column order.
+func GenerateCapnpSchema(rootName string, sheets []*Sheet) (string, error) {
+	if !capnpTypeNameRe.MatchString(rootName) {
+		return "", fmt.Errorf("capnp: invalid struct name %q", rootName)
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("@0x%016x;\n\n", capnpFileID(rootName)))
+	b.WriteString("struct " + rootName + " {\n")
+	for i, sheet := range sheets {
+		name := lowerFirst(sheet.FieldName)
+		if !capnpFieldNameRe.MatchString(name) {
+			return "", fmt.Errorf("capnp: %s: invalid field name %q", sheet.Origin, name)
+		}
+		b.WriteString(fmt.Sprintf("  %s @%d :List(%s);\n", name, i, sheet.TypeName))
+	}
+	b.WriteString("}\n")
+
+	for _, sheet := range sheets {
+		if !capnpTypeNameRe.MatchString(sheet.TypeName) {
+			return "", fmt.Errorf("capnp: %s: invalid struct name %q", sheet.Origin, sheet.TypeName)
+		}
+		b.WriteString("\nstruct " + sheet.TypeName + " {\n")
+		seen := make(map[string]string)
+		for i, f := range sheet.Fields {
+			name := capnpFieldName(f)
+			if !capnpFieldNameRe.MatchString(name) {
+				return "", fmt.Errorf("capnp: %s: column %q has no valid field name (%q)", sheet.Origin, f.RawName, name)
+			}
+			if prev, ok := seen[name]; ok {
+				return "", fmt.Errorf("capnp: %s: columns %q and %q both become field %q", sheet.Origin, prev, f.RawName, name)
+			}
+			seen[name] = f.RawName
+			t, ok := capnpType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("capnp: %s: unsupported type %q", sheet.Origin, f.RawType)
+			}
+			writeDocComment(&b, "  # ", f.Doc)
+			b.WriteString(fmt.Sprintf("  %s @%d :%s;\n", name, i, t))
+		}
+		b.WriteString("}\n")
+	}
+	return b.String(), nil
+}
+
+// capnpSlot is where a field lives in a struct: a pointer index, or an offset
+// in units of its size (1 << lgSize bits) in the data section.
+type capnpSlot struct {
+	pointer bool
+	offset  int
+	lgSize  int // 0: bit, 5: 32 bits, 6: 64 bits
+}
+
+// capnpLayout assigns the slots the Cap'n Proto compiler assigns to fields
+// numbered in order without unions: each data field takes the first free hole
+// of its size, splitting larger holes, before the data section grows by a
+// word. It returns the slots and the data and pointer section sizes in words.
+func capnpLayout(fields []Field) (slots []capnpSlot, dataWords, ptrWords int) {
+	var holes [6]int // free offset per lgSize; 0 is none, as offset 0 is always taken first
+	var tryAllocate func(lgSize int) (int, bool)
+	tryAllocate = func(lgSize int) (int, bool) {
+		if lgSize >= len(holes) {
+			return 0, false
+		}
+		if holes[lgSize] != 0 {
+			r := holes[lgSize]
+			holes[lgSize] = 0
+			return r, true
+		}
+		next, ok := tryAllocate(lgSize + 1)
+		if !ok {
+			return 0, false
+		}
+		holes[lgSize] = next*2 + 1
+		return next * 2, true
+	}
+	for _, f := range fields {
+		lgSize := -1
+		switch t, _ := capnpType(f.RawType); t {
+		case "Int64", "Float64":
+			lgSize = 6
+		case "Int32", "Float32":
+			lgSize = 5
+		case "Bool":
+			lgSize = 0
+		}
+		if lgSize < 0 {
+			slots = append(slots, capnpSlot{pointer: true, offset: ptrWords})
+			ptrWords++
+			continue
+		}
+		offset, ok := tryAllocate(lgSize)
+		if !ok {
+			offset = dataWords << (6 - lgSize)
+			dataWords++
+			for lg, hole := lgSize, offset+1; lg < 6; lg, hole = lg+1, (hole+1)/2 {
+				holes[lg] = hole
+			}
+		}
+		slots = append(slots, capnpSlot{offset: offset, lgSize: lgSize})
+	}
+	return slots, dataWords, ptrWords
+}
+
+// capnpMessage builds a single-segment message.
+type capnpMessage struct {
+	words []uint64
+}
+
+func (m *capnpMessage) alloc(n int) int {
+	i := len(m.words)
+	m.words = append(m.words, make([]uint64, n)...)
+	return i
+}
+
+// setStruct points the pointer word at ptr to a struct at target.
+func (m *capnpMessage) setStruct(ptr, target, dataWords, ptrWords int) {
+	m.words[ptr] = uint64(uint32(int32(target-ptr-1)<<2)) | uint64(dataWords)<<32 | uint64(ptrWords)<<48
+}
+
+// setList points the pointer word at ptr to a list at target. elemSize is the
+// element size code (2: byte, 5: 8 bytes, 6: pointer, 7: composite); n is the
+// element count, or the word count of a composite list.
+func (m *capnpMessage) setList(ptr, target, elemSize, n int) {
+	m.words[ptr] = uint64(uint32(int32(target-ptr-1)<<2|1)) | uint64(elemSize)<<32 | uint64(n)<<35
+}
+
+// setText writes s as a NUL-terminated byte list; empty strings stay null,
+// which readers return as "".
+func (m *capnpMessage) setText(ptr int, s string) {
+	if s == "" {
+		return
+	}
+	data := append([]byte(s), 0)
+	target := m.alloc((len(data) + 7) / 8)
+	for i, c := range data {
+		m.words[target+i/8] |= uint64(c) << (8 * (i % 8))
+	}
+	m.setList(ptr, target, 2, len(data))
+}
+
+func (m *capnpMessage) setInts(ptr int, v []int) {
+	if len(v) == 0 {
+		return
+	}
+	target := m.alloc(len(v))
+	for i, n := range v {
+		m.words[target+i] = uint64(int64(n))
+	}
+	m.setList(ptr, target, 5, len(v))
+}
+
+// encodeCapnpPayload encodes every sheet into a message whose root is the
+// root struct of GenerateCapnpSchema, packed.
+func encodeCapnpPayload(sheets []*Sheet) ([]byte, error) {
+	m := &capnpMessage{}
+	m.alloc(1) // root pointer
+	root := m.alloc(len(sheets))
+	m.setStruct(0, root, 0, len(sheets))
+	for si, sheet := range sheets {
+		slots, dataWords, ptrWords := capnpLayout(sheet.Fields)
+		size := dataWords + ptrWords
+		tag := m.alloc(1 + len(sheet.Items)*size)
+		m.words[tag] = uint64(uint32(int32(len(sheet.Items))<<2)) | uint64(dataWords)<<32 | uint64(ptrWords)<<48
+		m.setList(root+si, tag, 7, len(sheet.Items)*size)
+		for i, item := range sheet.Items {
+			base := tag + 1 + i*size
+			for j, f := range sheet.Fields {
+				slot := slots[j]
+				if err := m.setField(base, dataWords, slot, f, item[f.RawName]); err != nil {
+					return nil, fmt.Errorf("%s row %d (%s): %w", sheet.TypeName, i+1, f.RawName, err)
+				}
+			}
+		}
+	}
+
+	out := make([]byte, 8, 8+8*len(m.words))
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(m.words))) // one segment
+	for _, w := range m.words {
+		out = binary.LittleEndian.AppendUint64(out, w)
+	}
+	return packCapnp(out), nil
+}
+
+func (m *capnpMessage) setField(base, dataWords int, slot capnpSlot, f Field, v any) error {
+	if slot.pointer {
+		ptr := base + dataWords + slot.offset
+		switch x := v.(type) {
+		case string:
+			m.setText(ptr, x)
+		case []int:
+			m.setInts(ptr, x)
+		case [][]int:
+			if len(x) == 0 {
+				return nil
+			}
+			target := m.alloc(len(x))
+			m.setList(ptr, target, 6, len(x))
+			for i, inner := range x {
+				m.setInts(target+i, inner)
+			}
+		default:
+			return fmt.Errorf("unsupported value type %T", v)
+		}
+		return nil
+	}
+	var bits uint64
+	switch x := v.(type) {
+	case int:
+		bits = uint64(int64(x))
+		if slot.lgSize == 5 {
+			bits = uint64(uint32(int32(x)))
+		}
+	case float64:
+		bits = math.Float64bits(x)
+		if slot.lgSize == 5 {
+			bits = uint64(math.Float32bits(float32(x)))
+		}
+	case bool:
+		if x {
+			bits = 1
+		}
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+	bit := slot.offset << slot.lgSize
+	m.words[base+bit/64] |= bits << (bit % 64)
+	return nil
+}
+
+// packCapnp applies Cap'n Proto's packing: per word a tag byte flagging its
+// non-zero bytes, followed by those bytes. A zero tag is followed by the count
+// of further zero words, a 0xff tag by the count of further words copied
+// verbatim.
+func packCapnp(in []byte) []byte {
+	out := make([]byte, 0, len(in)/2)
+	for i := 0; i < len(in); {
+		word := in[i : i+8]
+		i += 8
+		var tag byte
+		for j, c := range word {
+			if c != 0 {
+				tag |= 1 << j
+			}
+		}
+		out = append(out, tag)
+		for _, c := range word {
+			if c != 0 {
+				out = append(out, c)
+			}
+		}
+		switch tag {
+		case 0:
+			n := 0
+			for n < 255 && i < len(in) && binary.LittleEndian.Uint64(in[i:]) == 0 {
+				n++
+				i += 8
+			}
+			out = append(out, byte(n))
+		case 0xff:
+			// Copy following words with at most one zero byte verbatim, as
+			// packing them would not save anything.
+			start, n := i, 0
+			for n < 255 && i < len(in) {
+				zeros := 0
+				for _, c := range in[i : i+8] {
+					if c == 0 {
+						zeros++
+					}
+				}
+				if zeros >= 2 {
+					break
+				}
+				n++
+				i += 8
+			}
+			out = append(out, byte(n))
+			out = append(out, in[start:i]...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/binary"
+	rand "math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wireTestSheet has the cases binary encoders get wrong: a negative int, a
+// string longer than 127 bytes (a two-byte length), and empty lists, also
+// inside int[][].
+func wireTestSheet() *Sheet {
+	sheet := protoTestSheet("Item", "items", "id#int", "name#string", "tags#int[]", "costs#int[][]")
+	sheet.FieldName = "Items"
+	sheet.Items = []map[string]any{
+		{"id": -2, "name": strings.Repeat("x", 130), "tags": []int{}, "costs": [][]int{{}, {-3}}},
+		{"id": 3, "name": "", "tags": []int{-1, 7}, "costs": [][]int{}},
+	}
+	return sheet
+}
+
+func TestCapnpPayloadGolden(t *testing.T) {
+	data, err := encodeCapnpPayload([]*Sheet{wireTestSheet()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := "\x10!@\x01\x11\x01GQ\b\x01\x03\xff\xfe\xff\xff\xff\xff\xff\xff\xff\x001\x19\x1a\x04\x00\x00\x11U\x16\x01" +
+		"\x03\x00\x00\x11U\x15\x00\x00\xffxxxxxxxx\x0f" + strings.Repeat("x", 120) + "\x03xx\x00\x00\x11\x01\r\xff\xfd" +
+		"\xff\xff\xff\xff\xff\xff\xff\x01\xff\xff\xff\xff\xff\xff\xff\xff\x01\a"
+	if string(data) != golden {
+		t.Errorf("got %q\nwant %q", data, golden)
+	}
+
+	// Read the rows back from the unpacked message: root struct, then the
+	// composite list of Items, whose struct has id in the data section and
+	// name, tags and costs as pointers.
+	msg := unpackCapnpTest(t, data)
+	if segs, size := binary.LittleEndian.Uint32(msg), binary.LittleEndian.Uint32(msg[4:]); segs != 0 || int(size) != len(msg)/8-1 {
+		t.Fatalf("segment table: %d more segments, %d words, want 0, %d", segs, size, len(msg)/8-1)
+	}
+	r := make(capnpTestReader, len(msg)/8-1)
+	for i := range r {
+		r[i] = binary.LittleEndian.Uint64(msg[8+8*i:])
+	}
+	root := r.target(t, 0, 0)
+	list := r.target(t, root, 1)
+	if r[root]>>32&7 != 7 {
+		t.Fatalf("items: element size %d, want a composite list", r[root]>>32&7)
+	}
+	n, dataWords, ptrWords := int(uint32(r[list])>>2), int(uint16(r[list]>>32)), int(uint16(r[list]>>48))
+	if dataWords != 1 || ptrWords != 3 {
+		t.Fatalf("items: %d data and %d pointer words, want 1 and 3", dataWords, ptrWords)
+	}
+	var got []map[string]any
+	for i := 0; i < n; i++ {
+		base := list + 1 + i*(dataWords+ptrWords)
+		ptrs := base + dataWords
+		costs := [][]int{}
+		if r[ptrs+2] != 0 {
+			outer := r.target(t, ptrs+2, 1)
+			for j := 0; j < int(r[ptrs+2]>>35); j++ {
+				costs = append(costs, r.ints(t, outer+j))
+			}
+		}
+		got = append(got, map[string]any{
+			"id":    int(int64(r[base])),
+			"name":  r.text(t, ptrs),
+			"tags":  r.ints(t, ptrs+1),
+			"costs": costs,
+		})
+	}
+	if want := wireTestSheet().Items; !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %v\nwant %v", got, want)
+	}
+}
+
+func TestPackCapnpRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 0))
+	for n := 0; n < 300; n++ {
+		words := make([]byte, 8*rng.IntN(600))
+		// Runs of zero, dense and sparse words, the cases packing treats apart.
+		for i := 0; i < len(words); i += 8 {
+			switch rng.IntN(3) {
+			case 1:
+				for j := 0; j < 8; j++ {
+					words[i+j] = byte(rng.IntN(255) + 1)
+				}
+			case 2:
+				words[i+rng.IntN(8)] = byte(rng.Uint32())
+			}
+		}
+		if got := unpackCapnpTest(t, packCapnp(words)); !bytes.Equal(got, words) {
+			t.Fatalf("%d words: unpacked % x\nwant % x", len(words)/8, got, words)
+		}
+	}
+}
+
+// unpackCapnpTest reverses packCapnp.
+func unpackCapnpTest(t *testing.T, in []byte) []byte {
+	t.Helper()
+	var out []byte
+	next := func() byte {
+		if len(in) == 0 {
+			t.Fatal("packed message ends early")
+		}
+		c := in[0]
+		in = in[1:]
+		return c
+	}
+	for len(in) > 0 {
+		tag := next()
+		for j := 0; j < 8; j++ {
+			if tag&(1<<j) != 0 {
+				out = append(out, next())
+			} else {
+				out = append(out, 0)
+			}
+		}
+		switch tag {
+		case 0:
+			out = append(out, make([]byte, 8*int(next()))...)
+		case 0xff:
+			n := 8 * int(next())
+			if n > len(in) {
+				t.Fatal("packed message ends early")
+			}
+			out, in = append(out, in[:n]...), in[n:]
+		}
+	}
+	return out
+}
+
+// capnpTestReader reads the words of a single-segment message.
+type capnpTestReader []uint64
+
+// target follows the pointer at word p, of kind 0 (struct) or 1 (list).
+func (r capnpTestReader) target(t *testing.T, p, kind int) int {
+	t.Helper()
+	if int(r[p]&3) != kind {
+		t.Fatalf("word %d: pointer kind %d, want %d", p, r[p]&3, kind)
+	}
+	return p + 1 + int(int32(uint32(r[p]))>>2)
+}
+
+func (r capnpTestReader) text(t *testing.T, p int) string {
+	if r[p] == 0 {
+		return ""
+	}
+	target, n := r.target(t, p, 1), int(r[p]>>35)
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r[target+i/8] >> (8 * (i % 8)))
+	}
+	if n == 0 || b[n-1] != 0 {
+		t.Fatalf("word %d: text without NUL", p)
+	}
+	return string(b[:n-1])
+}
+
+func (r capnpTestReader) ints(t *testing.T, p int) []int {
+	out := []int{}
+	if r[p] == 0 {
+		return out
+	}
+	target := r.target(t, p, 1)
+	if r[p]>>32&7 != 5 {
+		t.Fatalf("word %d: element size %d, want 8 bytes", p, r[p]>>32&7)
+	}
+	for i := 0; i < int(r[p]>>35); i++ {
+		out = append(out, int(int64(r[target+i])))
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package genxls
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// intSlabSize is how many ints of int[] cells are allocated at once.
+const intSlabSize = 4096
+
+// maxCachedCells bounds the distinct texts cached per column, so columns of
+// unique values (ids, names) stop caching instead of growing without limit.
+const maxCachedCells = 4096
+
+// cellCache keeps the parsed, boxed value of each distinct cell text per
+// column while a sheet is read. Game tables repeat the same few values (enum
+// strings, levels, flags) across millions of cells; sharing one interface
+// value per text saves an allocation, and the GC a pointer, per cell. Only
+// immutable scalars are cached; int[] cells get their own slices, carved out
+// of shared slabs instead of allocated one by one.
+type cellCache struct {
+	cols []map[string]any
+	ints []int // rest of the current slab
+}
+
+var cellCachePool = sync.Pool{New: func() any { return new(cellCache) }}
+
+// getCellCache returns an empty cache for n columns, reusing the maps of an
+// earlier sheet.
+func getCellCache(n int) *cellCache {
+	c := cellCachePool.Get().(*cellCache)
+	for len(c.cols) < n {
+		c.cols = append(c.cols, make(map[string]any))
+	}
+	return c
+}
+
+func putCellCache(c *cellCache) {
+	for _, m := range c.cols {
+		clear(m)
+	}
+	c.ints = nil // the slices handed out stay in the sheet's items
+	cellCachePool.Put(c)
+}
+
+// parse is parseFieldValue for column i, through the cache; a nil cache
+// parses every cell.
+func (c *cellCache) parse(i int, f Field, s string) (any, error) {
+	if c == nil {
+		return parseFieldValue(f, s)
+	}
+	if strings.EqualFold(f.RawType, "int[]") && f.Coerce.Unit == nil {
+		if v, ok := c.parseIntList(s); ok {
+			return v, nil
+		}
+		return parseFieldValue(f, s)
+	}
+	if !isScalarType(f.RawType) {
+		return parseFieldValue(f, s)
+	}
+	m := c.cols[i]
+	if v, ok := m[s]; ok {
+		return v, nil
+	}
+	v, err := parseFieldValue(f, s)
+	if err == nil && len(m) < maxCachedCells {
+		m[s] = v
+	}
+	return v, err
+}
+
+func isScalarType(rawType string) bool {
+	switch strings.ToLower(rawType) {
+	case "int", "int32", "int64", "float", "float32", "float64", "bool", "string", "datetime":
+		return true
+	}
+	return false
+}
+
+// parseIntList parses the common int[] forms ({1,2,3}, 1,2,3, {}) without
+// going through encoding/json. It reports false for anything else, which is
+// then parsed (or rejected) the usual way.
+func (c *cellCache) parseIntList(s string) ([]int, bool) {
+	s = strings.Trim(s, "\"")
+	if strings.HasPrefix(s, "{") != strings.HasSuffix(s, "}") || s == "" {
+		return nil, false
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if strings.TrimSpace(s) == "" {
+		return c.allocInts(0), true
+	}
+	n := strings.Count(s, ",") + 1
+	out := c.allocInts(n)
+	for i := range out {
+		tok, rest, _ := strings.Cut(s, ",")
+		tok, s = strings.TrimSpace(tok), rest
+		if !isJSONInt(tok) {
+			return nil, false
+		}
+		v, err := strconv.Atoi(tok)
+		if err != nil {
+			return nil, false
+		}
+		out[i] = v
+	}
+	return out, true
+}
+
+// allocInts returns a zeroed slice of n ints that can't grow into its
+// neighbours.
+func (c *cellCache) allocInts(n int) []int {
+	if n == 0 {
+		return []int{} // encodes as [], not null
+	}
+	if n > intSlabSize/4 {
+		return make([]int, n)
+	}
+	if len(c.ints) < n {
+		c.ints = make([]int, intSlabSize)
+	}
+	out := c.ints[:n:n]
+	c.ints = c.ints[n:]
+	return out
+}
+
+// isJSONInt reports whether s is an integer as JSON writes it: -?(0|[1-9][0-9]*).
+func isJSONInt(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" || len(s) > 1 && s[0] == '0' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
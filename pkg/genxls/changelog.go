@@ -0,0 +1,145 @@
+package genxls
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rowKeys returns the primary key of every row (the first column, as for the
+// Redis export). Repeated keys get a #n suffix so every row stays addressable.
+func rowKeys(sheet *Sheet) ([]string, error) {
+	keys := make([]string, len(sheet.Items))
+	seen := make(map[string]bool, len(sheet.Items))
+	for i, item := range sheet.Items {
+		base, err := redisValue(item[sheet.Fields[0].RawName])
+		if err != nil {
+			return nil, fmt.Errorf("%s row %d: %w", sheet.TypeName, i+1, err)
+		}
+		key := base
+		for n := 2; seen[key]; n++ {
+			key = base + "#" + strconv.Itoa(n)
+		}
+		seen[key] = true
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// rowHashes maps every row's key to a hash of its JSON encoding.
+func rowHashes(sheet *Sheet) (map[string]string, error) {
+	keys, err := rowKeys(sheet)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(keys))
+	for i, item := range sheet.Items {
+		data, err := EncodeJSONObject(sheet.Fields, item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sheet.TypeName, err)
+		}
+		sum := sha256.Sum256(data)
+		out[keys[i]] = hex.EncodeToString(sum[:8])
+	}
+	return out, nil
+}
+
+// buildChangelogEntry describes the rows added, changed and removed since the
+// previous run, or returns "" when nothing changed or there is no previous run.
+func buildChangelogEntry(prev, cur *RunState, sheets []*Sheet, when time.Time) string {
+	if len(prev.Sheets) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, sheet := range sheets {
+		rows := cur.Sheets[sheet.JSONKey].Rows
+		old, ok := prev.Sheets[sheet.JSONKey]
+		if !ok {
+			fmt.Fprintf(&b, "\n### %s\n\n- new sheet, rows: %d\n", sheet.JSONKey, len(rows))
+			continue
+		}
+		if old.Rows == nil {
+			continue // previous state predates row hashes
+		}
+		keys, _ := rowKeys(sheet) // already validated by buildRunState
+		added, changed, removed := diffRowHashes(keys, old.Rows, rows)
+		if len(added)+len(changed)+len(removed) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", sheet.JSONKey)
+		writeChangelogLine(&b, "added", added)
+		writeChangelogLine(&b, "changed", changed)
+		writeChangelogLine(&b, "removed", removed)
+	}
+	var gone []string
+	for key := range prev.Sheets {
+		if _, ok := cur.Sheets[key]; !ok {
+			gone = append(gone, key)
+		}
+	}
+	sort.Strings(gone)
+	for _, key := range gone {
+		fmt.Fprintf(&b, "\n### %s\n\n- sheet removed\n", key)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "## " + when.Format("2006-01-02 15:04") + "\n" + b.String()
+}
+
+// diffRowHashes compares the row hashes of two runs: added and changed keys in
+// row order (keys), removed ones in key order.
+func diffRowHashes(keys []string, old, cur map[string]string) (added, changed, removed []string) {
+	for _, key := range keys {
+		h, seen := old[key]
+		switch {
+		case !seen:
+			added = append(added, key)
+		case h != cur[key]:
+			changed = append(changed, key)
+		}
+	}
+	for key := range old {
+		if _, ok := cur[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return lessKey(removed[i], removed[j]) })
+	return added, changed, removed
+}
+
+func writeChangelogLine(b *strings.Builder, what string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "- %s (%d): %s\n", what, len(keys), strings.Join(keys, ", "))
+}
+
+// lessKey orders numeric keys by value and everything else as text.
+func lessKey(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// prependChangelog puts entry at the top of path, newest first.
+func prependChangelog(path, entry string) error {
+	old, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	data := entry
+	if len(old) > 0 {
+		data += "\n" + string(old)
+	}
+	return os.WriteFile(path, []byte(data), 0o644)
+}
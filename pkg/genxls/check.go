@@ -0,0 +1,145 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxCheckUpload bounds the workbooks POST /check accepts.
+const maxCheckUpload = 64 << 20
+
+// checkResult is the response of POST /check: the problems of the uploaded
+// workbook and, when it loads, its payload as all.json would hold it.
+type checkResult struct {
+	Workbook string         `json:"workbook"`
+	Problems []rpcProblem   `json:"problems"`
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// checkServer serves `genxls serve --http`, the "check my sheet" button of the
+// Excel add-in. Workbooks are checked one at a time.
+type checkServer struct {
+	config string
+	mu     sync.Mutex
+}
+
+// ServeCheckHTTP listens on addr, which must be a loopback address: uploaded
+// workbooks are design data and the endpoint has no authentication.
+func ServeCheckHTTP(addr, config string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("--http: %w", err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("--http: %s is not a loopback address (use e.g. 127.0.0.1:7700)", addr)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/check", &checkServer{config: config})
+	fmt.Fprintf(os.Stderr, "listening on http://%s/check\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *checkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Office add-ins run in a browser origin of their own.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "POST a workbook", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCheckUpload)
+	name, data, err := readCheckUpload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	p := rpcSheetParams{
+		Config:          s.config,
+		Flag:            q.Get("flag"),
+		Sheet:           q.Get("sheet"),
+		NamespaceByFile: q.Get("namespaceByFile") == "true",
+	}
+	s.mu.Lock()
+	result, err := checkWorkbook(name, data, p)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// readCheckUpload returns the uploaded workbook: the "file" field of a
+// multipart form, or else the raw body named by the "name" query parameter.
+func readCheckUpload(r *http.Request) (name string, data []byte, err error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, h, err := r.FormFile("file")
+		if err != nil {
+			return "", nil, fmt.Errorf("file: %w", err)
+		}
+		defer f.Close()
+		name = h.Filename
+		data, err = io.ReadAll(f)
+		if err != nil {
+			return "", nil, err
+		}
+	} else {
+		name = r.URL.Query().Get("name")
+		if data, err = io.ReadAll(r.Body); err != nil {
+			return "", nil, err
+		}
+	}
+	if len(data) == 0 {
+		return "", nil, errors.New("empty upload")
+	}
+	if name = filepath.Base(name); name == "." || name == string(filepath.Separator) {
+		name = "workbook.xlsx"
+	}
+	return name, data, nil
+}
+
+// checkWorkbook validates and parses one uploaded workbook, saved under its
+// own name in a temporary directory so problems and sheet origins name it as
+// the designer knows it.
+func checkWorkbook(name string, data []byte, p rpcSheetParams) (result checkResult, err error) {
+	dir, err := os.MkdirTemp("", "genxls-check-")
+	if err != nil {
+		return checkResult{}, err
+	}
+	defer os.RemoveAll(dir)
+	p.Path = filepath.Join(dir, name)
+	if err := os.WriteFile(p.Path, data, 0o644); err != nil {
+		return checkResult{}, err
+	}
+	trim := func(s string) string { return strings.ReplaceAll(s, dir+string(filepath.Separator), "") }
+
+	result = checkResult{Workbook: name}
+	if sheets, err := rpcLoadSheets(p, true); err != nil {
+		result.Problems = loadProblems(err)
+	} else {
+		result.Problems = sheetProblems(sheets)
+		result.Data = buildJSONPayload(sheets)
+	}
+	for i := range result.Problems {
+		result.Problems[i].Origin = trim(result.Problems[i].Origin)
+		result.Problems[i].Message = trim(result.Problems[i].Message)
+	}
+	return result, nil
+}
@@ -0,0 +1,244 @@
+package genxls
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// markChunkedSheets sets ChunkRows on every sheet with more than n rows, whose
+// rows are then written as numbered chunk files instead of inside all.json
+// (--chunk-rows).
+func markChunkedSheets(sheets []*Sheet, n int) {
+	for _, sheet := range sheets {
+		if n > 0 && len(sheet.Items) > n {
+			sheet.ChunkRows = n
+		}
+	}
+}
+
+func hasChunkedSheets(sheets []*Sheet) bool {
+	for _, sheet := range sheets {
+		if sheet.ChunkRows > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sheetChunks splits the payload rows of a chunked sheet into chunks of at
+// most ChunkRows rows.
+func sheetChunks(sheet *Sheet) [][]map[string]any {
+	items := jsonItems(sheet)
+	if sheet.Cells != nil {
+		items = withRawCells(sheet, items)
+	}
+	var chunks [][]map[string]any
+	for len(items) > 0 {
+		n := min(sheet.ChunkRows, len(items))
+		chunks = append(chunks, items[:n])
+		items = items[n:]
+	}
+	return chunks
+}
+
+// chunkIndexPath is the index listing the chunk files of the payload at
+// dataFile: all.json -> all.index.json, next to it so loaders find it from the
+// payload path alone.
+func chunkIndexPath(dataFile string) string {
+	return strings.TrimSuffix(dataFile, ".json") + ".index.json"
+}
+
+// chunkIndexEntry lists the chunk files of one sheet, relative to the index,
+// in row order.
+type chunkIndexEntry struct {
+	Rows   int      `json:"rows"`
+	Chunks []string `json:"chunks"`
+}
+
+// writeChunkedSheets writes <jsonKey>.<n>.json per chunk of every chunked sheet
+// and the index next to dataFile, and returns the written paths. Loaders only
+// read the index when go.gen.go etc. were generated with chunked sheets.
+func writeChunkedSheets(out *OutputLayout, dataFile string, sheets []*Sheet) ([]string, error) {
+	indexFile := chunkIndexPath(dataFile)
+	index := make(map[string]chunkIndexEntry)
+	var written []string
+	for _, sheet := range sheets {
+		if sheet.ChunkRows <= 0 {
+			continue
+		}
+		entry := chunkIndexEntry{Rows: len(sheet.Items)}
+		for i, rows := range sheetChunks(sheet) {
+			data, err := json.MarshalIndent(rows, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", sheet.TypeName, err)
+			}
+			outFile, err := out.WriteFile("json", sheet.JSONKey+"."+strconv.Itoa(i)+".json", sheet, data)
+			if err != nil {
+				return nil, err
+			}
+			rel, err := filepath.Rel(filepath.Dir(indexFile), outFile)
+			if err != nil {
+				return nil, err
+			}
+			entry.Chunks = append(entry.Chunks, filepath.ToSlash(rel))
+			written = append(written, outFile)
+		}
+		index[sheet.JSONKey] = entry
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := out.write(indexFile, data); err != nil {
+		return nil, err
+	}
+	return append(written, indexFile), nil
+}
+
+// chunkPayloadProblems checks every chunk file against --max-payload.
+func chunkPayloadProblems(sheets []*Sheet, max int64) ([]Problem, error) {
+	var problems []Problem
+	for _, sheet := range sheets {
+		if sheet.ChunkRows <= 0 {
+			continue
+		}
+		for i, rows := range sheetChunks(sheet) {
+			data, err := json.Marshal(rows)
+			if err != nil {
+				return nil, err
+			}
+			if int64(len(data)) > max {
+				problems = append(problems, problemf(sheet, "%s: chunk %d size %s exceeds --max-payload %s (lower --chunk-rows)", sheet.Origin, i, FormatByteSize(int64(len(data))), FormatByteSize(max)))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// goChunkImports are the imports goChunkLoader needs.
+var goChunkImports = []string{"encoding/json", "fmt", "os", "path/filepath", "strings"}
+
+// mergeImports returns the sorted union of import paths.
+func mergeImports(lists ...[]string) []string {
+	var out []string
+	for _, l := range lists {
+		out = append(out, l...)
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// goChunkLoader renders StitchChunks, which merges the chunk files listed in
+// the index back into the payload before it is decoded.
+func goChunkLoader() string {
+	var b strings.Builder
+	b.WriteString("// StitchChunks returns data, the payload read from path, with the rows of the\n")
+	b.WriteString("// sheets split into chunk files (--chunk-rows) read back in from the index\n")
+	b.WriteString("// next to it. Decode the result instead of data.\n")
+	b.WriteString("func StitchChunks(path string, data []byte) ([]byte, error) {\n")
+	b.WriteString("\treturn stitchChunks(path, data, os.ReadFile)\n}\n\n")
+	b.WriteString("func stitchChunks(path string, data []byte, read func(path string) ([]byte, error)) ([]byte, error) {\n")
+	b.WriteString("\tindexPath := strings.TrimSuffix(path, \".json\") + \".index.json\"\n")
+	b.WriteString("\tindexData, err := read(indexPath)\n")
+	b.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	b.WriteString("\tvar index map[string]struct {\n\t\tChunks []string `json:\"chunks\"`\n\t}\n")
+	b.WriteString("\tif err := json.Unmarshal(indexData, &index); err != nil {\n\t\treturn nil, fmt.Errorf(\"%s: %w\", indexPath, err)\n\t}\n")
+	b.WriteString("\tvar raw map[string]json.RawMessage\n")
+	b.WriteString("\tif err := json.Unmarshal(data, &raw); err != nil {\n\t\treturn nil, fmt.Errorf(\"%s: %w\", path, err)\n\t}\n")
+	b.WriteString("\tfor key, sheet := range index {\n")
+	b.WriteString("\t\trows := []json.RawMessage{}\n")
+	b.WriteString("\t\tfor _, chunk := range sheet.Chunks {\n")
+	b.WriteString("\t\t\tchunkPath := filepath.Join(filepath.Dir(path), filepath.FromSlash(chunk))\n")
+	b.WriteString("\t\t\tchunkData, err := read(chunkPath)\n")
+	b.WriteString("\t\t\tif err != nil {\n\t\t\t\treturn nil, err\n\t\t\t}\n")
+	b.WriteString("\t\t\tvar part []json.RawMessage\n")
+	b.WriteString("\t\t\tif err := json.Unmarshal(chunkData, &part); err != nil {\n\t\t\t\treturn nil, fmt.Errorf(\"%s: %w\", chunkPath, err)\n\t\t\t}\n")
+	b.WriteString("\t\t\trows = append(rows, part...)\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t\tif raw[key], err = json.Marshal(rows); err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn json.Marshal(raw)\n}\n")
+	return b.String()
+}
+
+// csChunkLoader is a static LoadFile method for the root class.
+func csChunkLoader(rootName string) string {
+	var b strings.Builder
+	b.WriteString("    // LoadFile reads the payload at path with the rows of chunked sheets read back\n")
+	b.WriteString("    // in from the chunk files listed in the index next to it.\n")
+	b.WriteString("    public static " + rootName + " LoadFile(string path)\n    {\n")
+	b.WriteString("        var root = JsonNode.Parse(File.ReadAllText(path))!.AsObject();\n")
+	b.WriteString("        var index = JsonNode.Parse(File.ReadAllText(Path.ChangeExtension(path, null) + \".index.json\"))!.AsObject();\n")
+	b.WriteString("        var dir = Path.GetDirectoryName(path) ?? \"\";\n")
+	b.WriteString("        foreach (var (key, sheet) in index)\n        {\n")
+	b.WriteString("            var rows = new JsonArray();\n")
+	b.WriteString("            foreach (var chunk in sheet![\"chunks\"]!.AsArray())\n")
+	b.WriteString("                foreach (var row in JsonNode.Parse(File.ReadAllText(Path.Combine(dir, (string)chunk!)))!.AsArray())\n")
+	b.WriteString("                    rows.Add(row?.DeepClone());\n")
+	b.WriteString("            root[key] = rows;\n        }\n")
+	b.WriteString("        return root.Deserialize<" + rootName + ">()!;\n    }\n\n")
+	return b.String()
+}
+
+func tsChunkLoader(rootName string) string {
+	var b strings.Builder
+	b.WriteString("\n// load" + rootName + " reads the payload at path with the rows of chunked sheets\n")
+	b.WriteString("// read back in from the chunk files listed in the index next to it. read\n")
+	b.WriteString("// returns the parsed JSON of a path, e.g. through fetch or fs.\n")
+	b.WriteString("export async function load" + rootName + "(read: (path: string) => Promise<any>, path: string): Promise<" + rootName + "> {\n")
+	b.WriteString("  const dir = path.slice(0, path.lastIndexOf(\"/\") + 1);\n")
+	b.WriteString("  const [raw, index] = await Promise.all([read(path), read(path.replace(/\\.json$/, \"\") + \".index.json\")]);\n")
+	b.WriteString("  const out = { ...raw };\n")
+	b.WriteString("  for (const [key, sheet] of Object.entries(index as Record<string, { chunks: string[] }>)) {\n")
+	b.WriteString("    const parts = await Promise.all(sheet.chunks.map((chunk) => read(dir + chunk)));\n")
+	b.WriteString("    out[key] = parts.flat();\n")
+	b.WriteString("  }\n")
+	b.WriteString("  return out as " + rootName + ";\n}\n")
+	return b.String()
+}
+
+const gdChunkLoader = `
+static func _stitch_chunks(path: String, data: Dictionary) -> bool:
+	var index = JSON.parse_string(FileAccess.get_file_as_string(path.get_basename() + ".index.json"))
+	if typeof(index) != TYPE_DICTIONARY:
+		push_error("invalid config index for " + path)
+		return false
+	for key in index:
+		var rows: Array = []
+		for chunk in index[key].get("chunks", []):
+			var part = JSON.parse_string(FileAccess.get_file_as_string(path.get_base_dir().path_join(chunk)))
+			if typeof(part) != TYPE_ARRAY:
+				push_error("invalid config chunk: " + chunk)
+				return false
+			rows.append_array(part)
+		data[key] = rows
+	return true
+`
+
+func dartChunkLoader(rootName string) string {
+	var b strings.Builder
+	b.WriteString("\n  /// Reads the payload at [path] with the rows of chunked sheets read back in\n")
+	b.WriteString("  /// from the chunk files listed in the index next to it. [read] returns the\n")
+	b.WriteString("  /// text of a path, e.g. a file or an asset.\n")
+	b.WriteString("  static Future<" + rootName + "> load(\n")
+	b.WriteString("      Future<String> Function(String path) read, String path) async {\n")
+	b.WriteString("    final json = jsonDecode(await read(path)) as Map<String, dynamic>;\n")
+	b.WriteString("    final index = jsonDecode(\n")
+	b.WriteString("            await read('${path.replaceFirst(RegExp(r'\\.json$'), '')}.index.json'))\n")
+	b.WriteString("        as Map<String, dynamic>;\n")
+	b.WriteString("    final dir = path.substring(0, path.lastIndexOf('/') + 1);\n")
+	b.WriteString("    for (final e in index.entries) {\n")
+	b.WriteString("      final rows = <dynamic>[];\n")
+	b.WriteString("      for (final chunk in (e.value as Map<String, dynamic>)['chunks'] as List<dynamic>) {\n")
+	b.WriteString("        rows.addAll(jsonDecode(await read('$dir$chunk')) as List<dynamic>);\n")
+	b.WriteString("      }\n")
+	b.WriteString("      json[e.key] = rows;\n")
+	b.WriteString("    }\n")
+	b.WriteString("    return " + rootName + ".fromJson(json);\n")
+	b.WriteString("  }\n")
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Coercion lists the opt-in conversions applied to a column's cells before they
+// are parsed, set by define-row options or --float-to-int.
+type Coercion struct {
+	FloatToInt string // "", or one of floatToIntPolicies
+	Thousands  bool   // accept "1,234,567" in numeric columns
+	YesNo      bool   // accept yes/no and y/n in bool columns
+	// Zones normalizes datetime columns, from the config "timezone".
+	Zones *DateTimeZones
+	// Unit converts numeric columns, e.g. seconds entered to milliseconds
+	// exported (",unit(s->ms)").
+	Unit *UnitConversion
+}
+
+// floatToIntPolicies are the ways an int column can accept float text like
+// "3.0": exact only takes whole numbers, the rest round as their math namesake
+// (round is half away from zero).
+var floatToIntPolicies = []string{"exact", "round", "floor", "ceil", "trunc"}
+
+var thousandsRe = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d*)?$`)
+
+// parseFieldValue applies the field's coercions to s and parses the result.
+func parseFieldValue(f Field, s string) (any, error) {
+	if s == "" {
+		return parseCellValue(f.RawType, s)
+	}
+	c := f.Coerce
+	if c.Unit != nil {
+		plain := f
+		plain.Coerce.Unit = nil
+		v, err := parseFieldValue(plain, s)
+		if err != nil {
+			return nil, err
+		}
+		return c.Unit.convert(f.RawType, v)
+	}
+	switch strings.ToLower(f.RawType) {
+	case "int", "int32", "int64":
+		if c.Thousands && thousandsRe.MatchString(s) {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+		if _, err := strconv.Atoi(s); err != nil && c.FloatToInt != "" {
+			v, err := floatToInt(s, c.FloatToInt)
+			if err == nil {
+				err = checkIntRange(f.RawType, v)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
+	case "float", "float32", "float64":
+		if c.Thousands && thousandsRe.MatchString(s) {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case "datetime":
+		if c.Zones != nil {
+			t, err := parseDateTimeIn(s, c.Zones.In)
+			if err != nil {
+				return nil, err
+			}
+			return c.Zones.format(t), nil
+		}
+	case "bool":
+		if c.YesNo {
+			switch strings.ToLower(s) {
+			case "yes", "y":
+				return true, nil
+			case "no", "n":
+				return false, nil
+			}
+		}
+	}
+	return parseCellValue(f.RawType, s)
+}
+
+func floatToInt(s, policy string) (int, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch policy {
+	case "exact":
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("%s is not a whole number", s)
+		}
+	case "round":
+		v = math.Round(v)
+	case "floor":
+		v = math.Floor(v)
+	case "ceil":
+		v = math.Ceil(v)
+	case "trunc":
+		v = math.Trunc(v)
+	default:
+		return 0, fmt.Errorf("unknown float-to-int policy %q", policy)
+	}
+	if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		return 0, fmt.Errorf("%s is out of integer range", s)
+	}
+	return int(v), nil
+}
+
+func parseFloatToIntPolicy(s string) (string, error) {
+	p := strings.ToLower(strings.TrimSpace(s))
+	if p == "" || p == "none" {
+		return "", nil
+	}
+	if !slices.Contains(floatToIntPolicies, p) {
+		return "", fmt.Errorf("invalid --float-to-int %q (expect none|%s)", s, strings.Join(floatToIntPolicies, "|"))
+	}
+	return p, nil
+}
@@ -0,0 +1,254 @@
+package genxls
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// numbersDocumentPart is in every Apple Numbers package, zipped or not.
+const numbersDocumentPart = "Index/Document.iwa"
+
+func numbersError(path string) error {
+	return fmt.Errorf("%s: Apple Numbers document; in Numbers use File > Export To > Excel and export the .xlsx instead", path)
+}
+
+// isNumbersPackage reports whether zr is a zipped Apple Numbers document,
+// whatever its file name.
+func isNumbersPackage(zr *zip.Reader) bool {
+	for _, e := range zr.File {
+		if e.Name == numbersDocumentPart {
+			return true
+		}
+	}
+	return false
+}
+
+// trimGhostCells drops the empty cells at the end of every row and the empty
+// rows at the end of the sheet. WPS (and Excel after formatting whole columns)
+// writes styled empty cells up to a "ghost" dimension far beyond the data,
+// which GetRows returns as empty strings.
+func trimGhostCells(rows [][]string) [][]string {
+	for i, row := range rows {
+		n := len(row)
+		for n > 0 && row[n-1] == "" {
+			n--
+		}
+		rows[i] = row[:n]
+	}
+	n := len(rows)
+	for n > 0 && len(rows[n-1]) == 0 {
+		n--
+	}
+	return rows[:n]
+}
+
+// isWPSWorkbook reports whether f was last saved by WPS Office.
+func isWPSWorkbook(f *excelize.File) bool {
+	props, err := f.GetAppProps()
+	return err == nil && strings.Contains(strings.ToUpper(props.Application), "WPS")
+}
+
+// danglingString is a cell that references a shared string past the end of the
+// shared string table, which WPS occasionally writes. excelize returns the
+// index itself as the cell's text.
+type danglingString struct {
+	Cell  string
+	Index int
+}
+
+// danglingSharedStrings scans the worksheets of an unencrypted package for
+// cells referencing missing shared strings, by sheet name.
+func danglingSharedStrings(zr *zip.Reader) (map[string][]danglingString, error) {
+	parts := make(map[string]*zip.File, len(zr.File))
+	for _, e := range zr.File {
+		parts[strings.TrimPrefix(e.Name, "/")] = e
+	}
+
+	count := 0
+	if e, ok := parts["xl/sharedStrings.xml"]; ok {
+		err := decodePart(e, func(d *xml.Decoder, se xml.StartElement) error {
+			if se.Name.Local == "si" {
+				count++
+				return d.Skip()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sheets, err := worksheetParts(parts)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]danglingString)
+	for name, target := range sheets {
+		e, ok := parts[target]
+		if !ok {
+			continue
+		}
+		err := decodePart(e, func(d *xml.Decoder, se xml.StartElement) error {
+			if se.Name.Local != "c" {
+				return nil
+			}
+			var c struct {
+				R string `xml:"r,attr"`
+				T string `xml:"t,attr"`
+				V string `xml:"v"`
+			}
+			if err := d.DecodeElement(&c, &se); err != nil {
+				return err
+			}
+			if c.T != "s" || c.R == "" {
+				return nil
+			}
+			if i, err := strconv.Atoi(strings.TrimSpace(c.V)); err == nil && i >= count {
+				out[name] = append(out[name], danglingString{Cell: c.R, Index: i})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+	}
+	return out, nil
+}
+
+// worksheetParts maps sheet names to their part names, from xl/workbook.xml
+// and its relationships.
+func worksheetParts(parts map[string]*zip.File) (map[string]string, error) {
+	ids := make(map[string]string) // relationship id -> sheet name
+	if e, ok := parts["xl/workbook.xml"]; ok {
+		err := decodePart(e, func(d *xml.Decoder, se xml.StartElement) error {
+			if se.Name.Local != "sheet" {
+				return nil
+			}
+			var name, id string
+			for _, a := range se.Attr {
+				switch {
+				case a.Name.Local == "name":
+					name = a.Value
+				case a.Name.Local == "id" && a.Name.Space != "":
+					id = a.Value
+				}
+			}
+			ids[id] = name
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sheets := make(map[string]string)
+	if e, ok := parts["xl/_rels/workbook.xml.rels"]; ok {
+		err := decodePart(e, func(d *xml.Decoder, se xml.StartElement) error {
+			if se.Name.Local != "Relationship" {
+				return nil
+			}
+			var id, target string
+			for _, a := range se.Attr {
+				switch a.Name.Local {
+				case "Id":
+					id = a.Value
+				case "Target":
+					target = a.Value
+				}
+			}
+			name, ok := ids[id]
+			if !ok {
+				return nil
+			}
+			if strings.HasPrefix(target, "/") {
+				sheets[name] = strings.TrimPrefix(target, "/")
+			} else {
+				sheets[name] = path.Join("xl", target)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sheets, nil
+}
+
+// decodePart calls fn for every start element of an XML part.
+func decodePart(e *zip.File, fn func(*xml.Decoder, xml.StartElement) error) error {
+	r, err := e.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			if err := fn(d, se); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// blankDanglingStrings empties the cells of rows that reference missing
+// shared strings and returns how many it emptied.
+func blankDanglingStrings(rows [][]string, cells []danglingString) int {
+	n := 0
+	for _, c := range cells {
+		col, row, err := excelize.CellNameToCoordinates(c.Cell)
+		if err != nil || row > len(rows) {
+			continue
+		}
+		if blankDanglingCell(rows[row-1], col, c.Index) {
+			n++
+		}
+	}
+	return n
+}
+
+// danglingByRow indexes cells by their 1-based row, for blanking a sheet read
+// row by row.
+func danglingByRow(cells []danglingString) map[int][]danglingString {
+	out := make(map[int][]danglingString)
+	for _, c := range cells {
+		if _, row, err := excelize.CellNameToCoordinates(c.Cell); err == nil {
+			out[row] = append(out[row], c)
+		}
+	}
+	return out
+}
+
+// blankDanglingRow is blankDanglingStrings for one row.
+func blankDanglingRow(row []string, cells []danglingString) int {
+	n := 0
+	for _, c := range cells {
+		if col, _, err := excelize.CellNameToCoordinates(c.Cell); err == nil && blankDanglingCell(row, col, c.Index) {
+			n++
+		}
+	}
+	return n
+}
+
+// blankDanglingCell empties the 1-based column col of row if it holds the
+// index of a missing shared string.
+func blankDanglingCell(row []string, col, index int) bool {
+	if col > len(row) || row[col-1] != strconv.Itoa(index) {
+		return false
+	}
+	row[col-1] = ""
+	return true
+}
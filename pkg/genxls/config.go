@@ -0,0 +1,277 @@
+package genxls
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Config holds settings that don't fit on the command line.
+type Config struct {
+	// Sheets is keyed by sheet name, type name or JSON key (case-insensitive).
+	Sheets map[string]SheetConfig `json:"sheets"`
+	// GoPackages generates extra, minimal Go packages holding only some sheets.
+	GoPackages []GoPackageConfig `json:"goPackages,omitempty"`
+	// Roots are extra root types, each with its own payload, keyed by payload
+	// name: {"battle": ["Monster", "Skill"]} adds BattleConfig and battle.json.
+	Roots map[string][]string `json:"roots,omitempty"`
+	// Owners routes problems in matching workbooks to their owner.
+	Owners []OwnerRule `json:"owners,omitempty"`
+	// Passwords opens protected workbooks.
+	Passwords []PasswordRule `json:"passwords,omitempty"`
+	// Outputs overrides --out-template per target, e.g. {"json": "{outDir}/{bundle}/{sheet}.json"}.
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Exclude drops the columns matching these patterns from every sheet,
+	// e.g. ["*_memo", "tmp_*"].
+	Exclude []string `json:"exclude,omitempty"`
+	// Timezone is the IANA zone datetime cells are entered in, e.g.
+	// "Europe/Berlin". With it, datetime values are exported as RFC 3339 in
+	// OutputTimezone instead of as naive wall clock times.
+	Timezone string `json:"timezone,omitempty"`
+	// OutputTimezone is the zone datetime values are written in; default UTC.
+	OutputTimezone string `json:"outputTimezone,omitempty"`
+	// Connectors holds the settings of remote inputs (--in feishu:<folder>
+	// etc.) per connector, e.g. {"feishu": {"appId": "cli_a1"}}. Environment
+	// variables take precedence.
+	Connectors map[string]map[string]string `json:"connectors,omitempty"`
+	// Flags are defaults for the command line flags by name, e.g. {"out":
+	// "gen", "lang": ["go", "ts"]}. Flags given on the command line win.
+	Flags map[string]any `json:"flags,omitempty"`
+
+	file  string         // the config file, for errors
+	dir   string         // directory of the config file, for relative paths
+	zones *DateTimeZones // from Timezone and OutputTimezone, nil without Timezone
+}
+
+type SheetConfig struct {
+	Owner string `json:"owner,omitempty"` // name or chat handle, overrides owners rules
+	// JSONKey replaces the payload key derived from the sheet name, e.g. "data"
+	// instead of "datas". Generated field names stay as they are.
+	JSONKey string `json:"jsonKey,omitempty"`
+	// RenamedFrom is the sheet's previous name. The payload keeps the key
+	// derived from it, so renaming a sheet doesn't break consumers; with
+	// BothKeys the rows are written under the new key and the old one.
+	RenamedFrom string   `json:"renamedFrom,omitempty"`
+	BothKeys    bool     `json:"bothKeys,omitempty"`
+	Bundles     []string `json:"bundles,omitempty"`
+	// Drift limits how much numeric columns may change between runs, e.g.
+	// {"price": "50%"}.
+	Drift map[string]string `json:"drift,omitempty"`
+	// GoMethods are text/template snippets rendered after the sheet's Go type.
+	GoMethods []string `json:"goMethods,omitempty"`
+	// IDs is the range every primary key must fall in, e.g. "10000-19999".
+	IDs string `json:"ids,omitempty"`
+	// Pivot turns a long attribute table into wide rows before anything else
+	// touches the columns.
+	Pivot *PivotConfig `json:"pivot,omitempty"`
+	// Script is a Starlark transform script run on the rows after the pivot,
+	// before derived columns (relative to the config file); see runSheetScript.
+	Script string `json:"script,omitempty"`
+	// Derived columns are computed by the exporter and appended to the sheet.
+	Derived []DerivedField `json:"derived,omitempty"`
+	// Joins embed columns of the rows other sheets' primary keys reference.
+	Joins []JoinConfig `json:"joins,omitempty"`
+}
+
+type GoPackageConfig struct {
+	Pkg    string   `json:"pkg"`
+	Out    string   `json:"out"` // relative to the config file
+	Sheets []string `json:"sheets"`
+}
+
+// LoadConfig reads path, or the genxls.json, .yaml or .toml of the working
+// directory when path is empty; without one the config is empty. YAML and
+// TOML files are read by their extension.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		var err error
+		if path, err = findDefaultConfig(); path == "" || err != nil {
+			return cfg, err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = configJSON(path, data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, r := range cfg.Owners {
+		if _, err := filepath.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: owners pattern %q: %w", path, r.Pattern, err)
+		}
+	}
+	for _, r := range cfg.Passwords {
+		if _, err := filepath.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: passwords pattern %q: %w", path, r.Pattern, err)
+		}
+	}
+	for name, sc := range cfg.Sheets {
+		if sc.JSONKey != "" && !token.IsIdentifier(sc.JSONKey) {
+			return nil, fmt.Errorf("%s: %s: invalid jsonKey %q", path, name, sc.JSONKey)
+		}
+		switch {
+		case sc.RenamedFrom != "" && exportName(sc.RenamedFrom) == "":
+			return nil, fmt.Errorf("%s: %s: invalid renamedFrom %q", path, name, sc.RenamedFrom)
+		case sc.BothKeys && sc.RenamedFrom == "":
+			return nil, fmt.Errorf("%s: %s: bothKeys requires renamedFrom", path, name)
+		case sc.JSONKey != "" && sc.RenamedFrom != "" && !sc.BothKeys:
+			return nil, fmt.Errorf("%s: %s: jsonKey and renamedFrom both set the key (add bothKeys to write both)", path, name)
+		}
+	}
+	for name := range cfg.Connectors {
+		if remoteConnectors[name] == nil {
+			return nil, fmt.Errorf("%s: connectors: unknown connector %q%s", path, name, didYouMean(name, remoteConnectorNames()))
+		}
+	}
+	for target := range cfg.Outputs {
+		if !slices.Contains(outputTargets, target) {
+			return nil, fmt.Errorf("%s: outputs: unknown target %q%s", path, target, didYouMean(target, outputTargets))
+		}
+	}
+	if cfg.zones, err = loadZones(cfg.Timezone, cfg.OutputTimezone); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.file, cfg.dir = path, filepath.Dir(path)
+	return cfg, nil
+}
+
+// jsonKeysOf returns the configured JSON key of a sheet, "" for the default,
+// and the old key it is also written under (renamedFrom with bothKeys).
+func (c *Config) jsonKeysOf(file, sheetName string, opts Options) (key, old string) {
+	if c == nil {
+		return "", ""
+	}
+	names := make([]string, 0, len(c.Sheets))
+	for name := range c.Sheets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sc := c.Sheets[name]
+		if (sc.JSONKey == "" && sc.RenamedFrom == "") || !sheetNameMatches(file, sheetName, name, opts) {
+			continue
+		}
+		key = sc.JSONKey
+		if sc.RenamedFrom != "" {
+			old = lowerFirst(pluralizeTypeName(exportName(sc.RenamedFrom)))
+			if !sc.BothKeys {
+				key, old = old, ""
+			}
+		}
+		return key, old
+	}
+	return "", ""
+}
+
+// ApplyConfig merges the per-sheet settings into sheets. Entries that match no
+// sheet are an error, so renamed sheets don't silently lose their settings,
+// unless only some sheets were loaded (partial).
+func ApplyConfig(cfg *Config, sheets []*Sheet, partial bool) error {
+	names := make([]string, 0, len(cfg.Sheets))
+	for name := range cfg.Sheets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sheet := FindSheet(sheets, name)
+		if sheet == nil && partial {
+			continue
+		}
+		if sheet == nil {
+			var known []string
+			for _, s := range sheets {
+				known = append(known, s.Name)
+			}
+			return fmt.Errorf("config: no sheet %q%s", name, didYouMean(name, known))
+		}
+		sc := cfg.Sheets[name]
+		sheet.Bundles = mergeBundles(sheet.Bundles, sc.Bundles)
+		if sc.Pivot != nil {
+			if err := pivotSheet(sheet, sc.Pivot); err != nil {
+				return fmt.Errorf("config: %s: %w", name, err)
+			}
+		}
+		if sc.Script != "" {
+			path := sc.Script
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(cfg.dir, path)
+			}
+			if err := runSheetScript(sheet, path); err != nil {
+				return fmt.Errorf("config: %s: script %w", name, err)
+			}
+		}
+		if err := addDerivedFields(sheet, sc.Derived); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+		methods, err := parseGoMethods(sheet, sc.GoMethods)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+		sheet.GoMethods = methods
+		if sc.IDs != "" {
+			if !isIntType(sheet.Fields[0].RawType) {
+				return fmt.Errorf("config: %s: ids range for non-integer primary key %q", name, sheet.Fields[0].RawName)
+			}
+			r, err := parseIDRange(sc.IDs)
+			if err != nil {
+				return fmt.Errorf("config: %s: %w", name, err)
+			}
+			sheet.IDRange = &r
+		}
+		for col, rule := range sc.Drift {
+			f := findField(sheet.Fields, col)
+			if f == nil {
+				var known []string
+				for _, f := range sheet.Fields {
+					known = append(known, f.RawName)
+				}
+				return fmt.Errorf("config: %s: drift rule for unknown column %q%s", name, col, didYouMean(col, known))
+			}
+			if !isIntType(f.RawType) && !isFloatType(f.RawType) {
+				return fmt.Errorf("config: %s: drift rule for non-numeric column %q", name, col)
+			}
+			limit, err := parseDriftLimit(rule)
+			if err != nil {
+				return fmt.Errorf("config: %s.%s: %w", name, col, err)
+			}
+			if sheet.Drift == nil {
+				sheet.Drift = make(map[string]float64)
+			}
+			sheet.Drift[f.RawName] = limit
+		}
+	}
+	// Joins run once every sheet has its derived columns, which they can embed.
+	order, err := joinOrder(cfg, sheets, names)
+	if err != nil {
+		return err
+	}
+	for _, name := range order {
+		sheet := FindSheet(sheets, name)
+		if err := addJoinedFields(sheet, sheets, cfg.Sheets[name].Joins); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// findField looks up a column by define-row name, ignoring case.
+func findField(fields []Field, name string) *Field {
+	for i := range fields {
+		if strings.EqualFold(fields[i].RawName, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles are looked for in the working directory when --config is
+// not given. At most one of them may exist.
+var defaultConfigFiles = []string{"genxls.json", "genxls.yaml", "genxls.yml", "genxls.toml"}
+
+// findDefaultConfig returns the config file of the working directory, or ""
+// when there is none.
+func findDefaultConfig() (string, error) {
+	var found []string
+	for _, name := range defaultConfigFiles {
+		if _, err := os.Stat(name); err == nil {
+			found = append(found, name)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("found %s; keep one config file or pick one with --config", strings.Join(found, " and "))
+	}
+}
+
+// configJSON converts a YAML or TOML config, by path's extension, to the JSON
+// LoadConfig decodes, so all three formats share one schema and its checks.
+func configJSON(path string, data []byte) ([]byte, error) {
+	var v any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		if v == nil {
+			v = map[string]any{}
+		}
+	case ".toml":
+		m := map[string]any{}
+		if err := toml.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		v = m
+	default:
+		return data, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid config: %w", err)
+	}
+	return data, nil
+}
+
+// ApplyConfigFlags sets the flags of the config's "flags" section that were
+// not given on the command line, which takes precedence. Lists become
+// comma-separated values, e.g. lang: [go, ts] is --lang go,ts.
+func ApplyConfigFlags(fs *flag.FlagSet, cfg *Config) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	names := make([]string, 0, len(cfg.Flags))
+	for name := range cfg.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fs.Lookup(name) == nil || name == "config" {
+			var known []string
+			fs.VisitAll(func(f *flag.Flag) { known = append(known, f.Name) })
+			return fmt.Errorf("%s: flags: unknown flag %q%s", cfg.file, name, didYouMean(name, known))
+		}
+		if given[name] {
+			continue
+		}
+		s, err := configFlagValue(cfg.Flags[name])
+		if err == nil {
+			err = fs.Set(name, s)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: flags: %s: %w", cfg.file, name, err)
+		}
+	}
+	return nil
+}
+
+func configFlagValue(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, len(x))
+		for i, e := range x {
+			s, err := configFlagValue(e)
+			if err != nil {
+				return "", err
+			}
+			if _, ok := e.([]any); ok {
+				return "", errors.New("nested lists are not supported")
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
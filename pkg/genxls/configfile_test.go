@@ -0,0 +1,41 @@
+package genxls
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConfigJSONTOML(t *testing.T) {
+	src := `
+[flags]
+lang = ["go", "ts"]
+max-errors = 10
+
+[sheets.Item]
+bundles = ["core"]
+ids = """
+10000-19999"""
+
+[[goPackages]]
+pkg = "items"
+out = "gen/items"
+sheets = ["Item"]
+`
+	data, err := configJSON("genxls.toml", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"flags":{"lang":["go","ts"],"max-errors":10},"goPackages":[{"out":"gen/items","pkg":"items","sheets":["Item"]}],"sheets":{"Item":{"bundles":["core"],"ids":"10000-19999"}}}`
+	if string(data) != want {
+		t.Errorf("got %s\nwant %s", data, want)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := configJSON("genxls.toml", []byte("[flags]\nout = \"gen\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("unterminated string: got %v, want an error at line 2", err)
+	}
+}
@@ -0,0 +1,44 @@
+package genxls
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeCSStubs creates an empty partial class <Type>.cs in dir for the root and
+// every sheet type that doesn't have one yet, for hand-written helpers next to
+// the generated classes. Existing files are never touched. It returns the
+// created paths.
+func writeCSStubs(dir, rootName string, sheets []*Sheet) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	types := []string{rootName}
+	for _, sheet := range sheets {
+		types = append(types, sheet.TypeName)
+	}
+	var created []string
+	for _, t := range types {
+		path := filepath.Join(dir, t+".cs")
+		code := fmt.Sprintf("// Extends the generated %s. genxls creates this file once and never overwrites it.\npublic partial class %s\n{\n}\n", t, t)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.WriteString(code); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
+		created = append(created, path)
+	}
+	return created, nil
+}
@@ -0,0 +1,183 @@
+package genxls
+
+import (
+	"fmt"
+	"strings"
+)
+
+func mapDartType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return "int", true
+	case "int[]":
+		return "List<int>", true
+	case "int[][]":
+		return "List<List<int>>", true
+	case "float", "float32", "float64":
+		return "double", true
+	case "bool":
+		return "bool", true
+	case "string", "datetime":
+		return "String", true
+	default:
+		return "", false
+	}
+}
+
+// dartFromJSON returns the expression decoding json[key] for a column,
+// falling back to the zero value like the exporter does for empty cells.
+func dartFromJSON(f Field) (string, error) {
+	get := "json['" + f.RawName + "']"
+	if f.JSONString {
+		return "int.parse(" + get + "?.toString() ?? '0')", nil
+	}
+	switch strings.ToLower(f.RawType) {
+	case "int", "int32", "int64":
+		return "(" + get + " as num?)?.toInt() ?? 0", nil
+	case "int[]":
+		return "(" + get + " as List<dynamic>? ?? const [])\n" +
+			"            .map((e) => (e as num).toInt())\n" +
+			"            .toList()", nil
+	case "int[][]":
+		return "(" + get + " as List<dynamic>? ?? const [])\n" +
+			"            .map((e) => (e as List<dynamic>).map((e) => (e as num).toInt()).toList())\n" +
+			"            .toList()", nil
+	case "float", "float32", "float64":
+		return "(" + get + " as num?)?.toDouble() ?? 0", nil
+	case "bool":
+		return get + " as bool? ?? false", nil
+	case "string", "datetime":
+		return get + " as String? ?? ''", nil
+	default:
+		return "", fmt.Errorf("unsupported type %q", f.RawType)
+	}
+}
+
+func GenerateDartBundle(rootName string, sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	if hasChunkedSheets(sheets) {
+		b.WriteString("import 'dart:convert';\n\n")
+	}
+	for _, sheet := range sheets {
+		fields := sheet.Fields
+		b.WriteString("class ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(" {\n")
+		for _, f := range fields {
+			dartType, ok := mapDartType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("unsupported type %q", f.RawType)
+			}
+			writeDocComment(&b, "  /// ", f.Doc)
+			b.WriteString("  final ")
+			b.WriteString(dartType)
+			b.WriteString(" ")
+			b.WriteString(lowerFirst(f.Name))
+			b.WriteString(";\n")
+		}
+
+		b.WriteString("\n  const ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString("({\n")
+		for _, f := range fields {
+			b.WriteString("    required this.")
+			b.WriteString(lowerFirst(f.Name))
+			b.WriteString(",\n")
+		}
+		b.WriteString("  });\n\n")
+
+		b.WriteString("  factory ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(".fromJson(Map<String, dynamic> json) => ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString("(\n")
+		for _, f := range fields {
+			expr, err := dartFromJSON(f)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("        ")
+			b.WriteString(lowerFirst(f.Name))
+			b.WriteString(": ")
+			b.WriteString(expr)
+			b.WriteString(",\n")
+		}
+		b.WriteString("      );\n\n")
+
+		b.WriteString("  Map<String, dynamic> toJson() => {\n")
+		for _, f := range fields {
+			b.WriteString("        '")
+			b.WriteString(f.RawName)
+			b.WriteString("': ")
+			b.WriteString(lowerFirst(f.Name))
+			if f.JSONString {
+				b.WriteString(".toString()")
+			}
+			b.WriteString(",\n")
+		}
+		b.WriteString("      };\n}\n\n")
+	}
+
+	b.WriteString("class ")
+	b.WriteString(rootName)
+	b.WriteString(" {\n")
+	for _, sheet := range sheets {
+		b.WriteString("  final List<")
+		b.WriteString(sheet.TypeName)
+		b.WriteString("> ")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString(";\n")
+	}
+	b.WriteString("\n  const ")
+	b.WriteString(rootName)
+	b.WriteString("({\n")
+	for _, sheet := range sheets {
+		b.WriteString("    required this.")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString(",\n")
+	}
+	b.WriteString("  });\n\n")
+
+	b.WriteString("  factory ")
+	b.WriteString(rootName)
+	b.WriteString(".fromJson(Map<String, dynamic> json) => ")
+	b.WriteString(rootName)
+	b.WriteString("(\n")
+	for _, sheet := range sheets {
+		b.WriteString("        ")
+		b.WriteString(sheet.JSONKey)
+		if sheet.Sparse {
+			b.WriteString(": _expandSparse(json['")
+			b.WriteString(sheet.JSONKey)
+			b.WriteString("'])\n")
+		} else {
+			b.WriteString(": (json['")
+			b.WriteString(sheet.JSONKey)
+			b.WriteString("'] as List<dynamic>? ?? const [])\n")
+		}
+		b.WriteString("            .map((e) => ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(".fromJson(e as Map<String, dynamic>))\n")
+		b.WriteString("            .toList(),\n")
+	}
+	b.WriteString("      );\n\n")
+
+	b.WriteString("  Map<String, dynamic> toJson() => {\n")
+	for _, sheet := range sheets {
+		b.WriteString("        '")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString("': ")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString(".map((e) => e.toJson()).toList(),\n")
+	}
+	b.WriteString("      };\n")
+	b.WriteString(dartSingletonGetters(sheets))
+	if hasChunkedSheets(sheets) {
+		b.WriteString(dartChunkLoader(rootName))
+	}
+	b.WriteString("}\n")
+	if hasSparseSheets(sheets) {
+		b.WriteString(dartSparseLoader)
+	}
+	return b.String(), nil
+}
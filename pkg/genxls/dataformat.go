@@ -0,0 +1,245 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func ParseDataFormat(s string) (string, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	switch s {
+	case "", "json":
+		return "json", nil
+	case "yaml", "yml":
+		return "yaml", nil
+	case "toml":
+		return "toml", nil
+	case "jsonl", "ndjson":
+		return "jsonl", nil
+	case "tsv", "csv", "xml":
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid --data-format %q (expect json|yaml|toml|xml|jsonl|tsv|csv)", s)
+	}
+}
+
+func isPerSheetFormat(format string) bool {
+	return format == "jsonl" || format == "tsv" || format == "csv"
+}
+
+// WriteDataPayload writes the data payload and returns the written paths. jsonl,
+// tsv and csv produce one <jsonKey>.<ext> file per sheet (isPerSheetFormat),
+// every other format a single aggregated <DataName>.<ext> file (all.json unless
+// a bundle is exported), plus the chunk files and index of chunked sheets.
+func WriteDataPayload(out *OutputLayout, format string, sheets []*Sheet) ([]string, error) {
+	switch format {
+	case "jsonl":
+		return writeJSONLBundle(out, sheets)
+	case "tsv", "csv":
+		return writeDelimitedBundle(out, format, sheets)
+	case "xml":
+		return writeXMLBundle(out, sheets)
+	}
+	data, ext, err := encodeDataPayload(format, sheets)
+	if err != nil {
+		return nil, err
+	}
+	outFile, err := out.WriteFile(format, out.DataName+"."+ext, nil, data)
+	if err != nil {
+		return nil, err
+	}
+	if format == "json" && hasChunkedSheets(sheets) {
+		files, err := writeChunkedSheets(out, outFile, sheets)
+		if err != nil {
+			return nil, err
+		}
+		return append([]string{outFile}, files...), nil
+	}
+	return []string{outFile}, nil
+}
+
+// encodeDataPayload renders the aggregated payload in the given format and
+// returns it together with the output file extension. YAML and TOML follow
+// sheet order and column order instead of sorting keys.
+func encodeDataPayload(format string, sheets []*Sheet) ([]byte, string, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(buildJSONPayload(sheets), "", "  ")
+		return data, "json", err
+	case "yaml":
+		data, err := encodeYAMLPayload(sheets)
+		return data, "yaml", err
+	case "toml":
+		data, err := encodeTOMLPayload(sheets)
+		return data, "toml", err
+	default:
+		return nil, "", fmt.Errorf("unsupported data format %q", format)
+	}
+}
+
+func encodeYAMLPayload(sheets []*Sheet) ([]byte, error) {
+	var b bytes.Buffer
+	for _, sheet := range sheets {
+		b.WriteString(sheet.JSONKey)
+		if len(sheet.Items) == 0 {
+			b.WriteString(": []\n")
+			continue
+		}
+		b.WriteString(":\n")
+		for _, item := range sheet.Items {
+			for i, f := range sheet.Fields {
+				if i == 0 {
+					b.WriteString("  - ")
+				} else {
+					b.WriteString("    ")
+				}
+				v, err := formatScalarValue(item[f.RawName], ".inf", ".nan")
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", sheet.TypeName, f.RawName, err)
+				}
+				b.WriteString(f.RawName)
+				b.WriteString(": ")
+				b.WriteString(v)
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.Bytes(), nil
+}
+
+func encodeTOMLPayload(sheets []*Sheet) ([]byte, error) {
+	var b bytes.Buffer
+	// Plain keys must precede any table header, so empty sheets go first.
+	for _, sheet := range sheets {
+		if len(sheet.Items) == 0 {
+			b.WriteString(sheet.JSONKey)
+			b.WriteString(" = []\n")
+		}
+	}
+	for _, sheet := range sheets {
+		for _, item := range sheet.Items {
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("[[")
+			b.WriteString(sheet.JSONKey)
+			b.WriteString("]]\n")
+			for _, f := range sheet.Fields {
+				v, err := formatScalarValue(item[f.RawName], "inf", "nan")
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", sheet.TypeName, f.RawName, err)
+				}
+				b.WriteString(f.RawName)
+				b.WriteString(" = ")
+				b.WriteString(v)
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// formatScalarValue renders a parsed cell value using the flow syntax shared by
+// YAML and TOML: JSON-quoted strings and bracketed arrays. inf/nan are the
+// spellings for non-finite floats in the target format.
+func formatScalarValue(v any, inf, nan string) (string, error) {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x), nil
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return nan, nil
+		case math.IsInf(x, 1):
+			return inf, nil
+		case math.IsInf(x, -1):
+			return "-" + inf, nil
+		}
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case string:
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(x); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case [][]int:
+		parts := make([]string, len(x))
+		for i, inner := range x {
+			s, err := formatScalarValue(inner, inf, nan)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func writeJSONLBundle(out *OutputLayout, sheets []*Sheet) ([]string, error) {
+	var written []string
+	for _, sheet := range sheets {
+		var b bytes.Buffer
+		for i, item := range jsonItems(sheet) {
+			line, err := EncodeJSONObject(sheet.Fields, item)
+			if err == nil && sheet.Cells != nil {
+				line, err = appendRawCells(line, sheet, i)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", sheet.TypeName, err)
+			}
+			b.Write(line)
+			b.WriteString("\n")
+		}
+		outFile, err := out.WriteFile("jsonl", sheet.JSONKey+".jsonl", sheet, b.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, outFile)
+	}
+	return written, nil
+}
+
+// EncodeJSONObject marshals one row as a compact JSON object whose keys follow
+// the column order of fields.
+func EncodeJSONObject(fields []Field, item map[string]any) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(f.RawName)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(item[f.RawName])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.RawName, err)
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(val)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
@@ -0,0 +1,66 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// rawCellsKey holds a row's original cell text in --debug-data payloads.
+const rawCellsKey = "__raw"
+
+// rawCells returns the original text of item i's cells keyed by field, read
+// from the sheet grid kept for --debug-data.
+func rawCells(sheet *Sheet, i int) map[string]string {
+	row := sheet.Cells[sheet.Rows[i]-1]
+	out := make(map[string]string, len(sheet.Fields))
+	for _, f := range sheet.Fields {
+		if f.Col >= 0 && f.Col < len(row) {
+			out[f.RawName] = row[f.Col]
+		} else {
+			out[f.RawName] = ""
+		}
+	}
+	return out
+}
+
+// withRawCells returns copies of items carrying their original cell text
+// under __raw.
+func withRawCells(sheet *Sheet, items []map[string]any) []map[string]any {
+	out := make([]map[string]any, len(items))
+	for i, item := range items {
+		cp := make(map[string]any, len(item)+1)
+		for k, v := range item {
+			cp[k] = v
+		}
+		cp[rawCellsKey] = rawCells(sheet, i)
+		out[i] = cp
+	}
+	return out
+}
+
+// appendRawCells adds "__raw" to a compact JSON object encoded by
+// EncodeJSONObject, keeping the column order.
+func appendRawCells(obj []byte, sheet *Sheet, i int) ([]byte, error) {
+	raw := rawCells(sheet, i)
+	var b bytes.Buffer
+	b.Write(obj[:len(obj)-1])
+	b.WriteString(`,"` + rawCellsKey + `":{`)
+	for j, f := range sheet.Fields {
+		if j > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(f.RawName)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(raw[f.RawName])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(val)
+	}
+	b.WriteString("}}")
+	return b.Bytes(), nil
+}
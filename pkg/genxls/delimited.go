@@ -0,0 +1,102 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// tsvEscaper escapes the characters that would break a TSV line, the way
+// PostgreSQL's text format and most TSV readers expect.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writeDelimitedBundle writes one <jsonKey>.tsv or <jsonKey>.csv file per
+// sheet: a header row of column names, then one line per row with the values
+// as normalized cell text (formatCellText).
+func writeDelimitedBundle(out *OutputLayout, format string, sheets []*Sheet) ([]string, error) {
+	var written []string
+	for _, sheet := range sheets {
+		records := make([][]string, 0, len(sheet.Items)+1)
+		header := make([]string, len(sheet.Fields))
+		for i, f := range sheet.Fields {
+			header[i] = f.RawName
+		}
+		records = append(records, header)
+		for _, item := range sheet.Items {
+			record := make([]string, len(sheet.Fields))
+			for i, f := range sheet.Fields {
+				s, err := formatCellText(item[f.RawName])
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", sheet.TypeName, f.RawName, err)
+				}
+				record[i] = s
+			}
+			records = append(records, record)
+		}
+
+		var b bytes.Buffer
+		if format == "csv" {
+			w := csv.NewWriter(&b)
+			if err := w.WriteAll(records); err != nil {
+				return nil, fmt.Errorf("%s: %w", sheet.TypeName, err)
+			}
+		} else {
+			for _, record := range records {
+				for i, s := range record {
+					if i > 0 {
+						b.WriteString("\t")
+					}
+					b.WriteString(tsvEscaper.Replace(s))
+				}
+				b.WriteString("\n")
+			}
+		}
+		outFile, err := out.WriteFile(format, sheet.JSONKey+"."+format, sheet, b.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, outFile)
+	}
+	return written, nil
+}
+
+// formatCellText renders a parsed value the way a cell would hold it: int[]
+// as {1,2,3}, int[][] as {{1,2},{3}}, and floats in their shortest form, so
+// the output can be diffed against the workbook or pasted back into it.
+func formatCellText(v any) (string, error) {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x), nil
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return "nan", nil
+		case math.IsInf(x, 1):
+			return "inf", nil
+		case math.IsInf(x, -1):
+			return "-inf", nil
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case string:
+		return x, nil
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return "{" + strings.Join(parts, ",") + "}", nil
+	case [][]int:
+		parts := make([]string, len(x))
+		for i, inner := range x {
+			parts[i], _ = formatCellText(inner)
+		}
+		return "{" + strings.Join(parts, ",") + "}", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
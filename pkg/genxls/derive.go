@@ -0,0 +1,85 @@
+package genxls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DerivedField is a column the exporter computes from the others, so every
+// consumer gets the same value instead of recomputing it.
+type DerivedField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Value is a template over the row's columns, like @expand cells:
+	// "${damage/interval}" or "${kind}_${level}".
+	Value string `json:"value"`
+	Doc   string `json:"doc,omitempty"`
+}
+
+// addDerivedFields appends the derived columns to sheet and computes them for
+// every row, in order, so later ones can use earlier ones. Numeric and bool
+// columns (true is 1) are expression variables; string and datetime columns
+// are substituted as they are.
+func addDerivedFields(sheet *Sheet, derived []DerivedField) error {
+	for _, d := range derived {
+		// Named and typed like a define-row cell.
+		if m := fieldRe.FindStringSubmatch(d.Name + "#" + d.Type); m == nil || m[1] != d.Name {
+			return fmt.Errorf("derived column %q: invalid name or type %q", d.Name, d.Type)
+		}
+		if findField(sheet.Fields, d.Name) != nil {
+			return fmt.Errorf("derived column %q: the sheet already has that column", d.Name)
+		}
+		goType, ok := mapGoType(d.Type)
+		if !ok {
+			return fmt.Errorf("derived column %q: unsupported type %q%s", d.Name, d.Type, didYouMean(d.Type, SupportedTypes))
+		}
+		if strings.TrimSpace(d.Value) == "" {
+			return fmt.Errorf("derived column %q: empty value", d.Name)
+		}
+		f := Field{
+			RawName:  d.Name,
+			Name:     exportName(d.Name),
+			RawType:  d.Type,
+			GoType:   goType,
+			Col:      -1, // not in the workbook
+			Flag:     FieldFlagAll,
+			Exported: true,
+			Doc:      d.Doc,
+		}
+		for i, item := range sheet.Items {
+			text, err := interpolateExprs(d.Value, rowExprEnv(sheet.Fields, item))
+			if err != nil {
+				return fmt.Errorf("derived column %q, data row %d: %w", d.Name, i+1, err)
+			}
+			v, err := parseCellValue(d.Type, text)
+			if err != nil {
+				return fmt.Errorf("derived column %q, data row %d: %q: %w", d.Name, i+1, text, err)
+			}
+			item[d.Name] = v
+		}
+		sheet.Fields = append(sheet.Fields, f)
+	}
+	return nil
+}
+
+// rowExprEnv exposes one row to interpolateExprs.
+func rowExprEnv(fields []Field, item map[string]any) *exprEnv {
+	env := &exprEnv{vars: make(map[string]float64), strs: make(map[string]string)}
+	for _, f := range fields {
+		switch v := item[f.RawName].(type) {
+		case int:
+			env.vars[f.RawName] = float64(v)
+		case float64:
+			env.vars[f.RawName] = v
+		case bool:
+			if v {
+				env.vars[f.RawName] = 1
+			} else {
+				env.vars[f.RawName] = 0
+			}
+		case string:
+			env.strs[f.RawName] = v
+		}
+	}
+	return env
+}
@@ -0,0 +1,10 @@
+// Package genxls is the exporter behind the genxls command: it loads sheets
+// from workbooks, TSV/CSV files or any SheetSource, checks them, and generates
+// code and data payloads into an ArtifactSink.
+//
+// Export runs a whole export as the command line does. Embedders that need
+// less call the parts: LoadSheets or LoadSources to parse, the Generate*
+// functions to render code, WriteDataPayload to write the payload. Errors are
+// returned, never printed or exited on: load failures are *LoadErrors wrapping
+// *SchemaError and *CellError values, and warnings go to Options.Logger.
+package genxls
@@ -0,0 +1,140 @@
+package genxls
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// threadedCommentMarker starts the note Excel writes next to a threaded
+// comment for older readers; the comment itself follows "Comment:".
+const threadedCommentMarker = "[Threaded comment]"
+
+// commentText returns the text of a cell comment without the "Author:" line
+// Excel puts in front of notes.
+func commentText(c excelize.Comment) string {
+	text := c.Text
+	for _, run := range c.Paragraph {
+		text += run.Text
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.HasPrefix(text, threadedCommentMarker) {
+		_, body, ok := strings.Cut(text, "\nComment:\n")
+		if !ok {
+			return ""
+		}
+		body, _, _ = strings.Cut(body, "\nReply:\n")
+		lines := strings.Split(body, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimSpace(l)
+		}
+		return strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	if c.Author != "" {
+		text = strings.TrimPrefix(text, c.Author+":")
+	}
+	return strings.TrimSpace(text)
+}
+
+// sheetNotes returns the comments of a worksheet by cell reference.
+func sheetNotes(f *excelize.File, sheet string) (map[string]string, error) {
+	comments, err := f.GetComments(sheet)
+	if err != nil {
+		return nil, err
+	}
+	notes := make(map[string]string, len(comments))
+	for _, c := range comments {
+		if text := commentText(c); text != "" {
+			notes[c.Cell] = text
+		}
+	}
+	return notes, nil
+}
+
+// applyFieldDocs sets Doc of every field whose define-row cell has a comment.
+func applyFieldDocs(fields []Field, notes map[string]string, defineRow int) {
+	for i := range fields {
+		cell, err := excelize.CoordinatesToCellName(fields[i].Col+1, defineRow)
+		if err != nil {
+			continue
+		}
+		fields[i].Doc = notes[cell]
+	}
+}
+
+// writeDocComment writes doc as line comments, one per line, e.g. "\t// ".
+func writeDocComment(b *strings.Builder, lead, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		b.WriteString(strings.TrimRight(lead+line, " "))
+		b.WriteString("\n")
+	}
+}
+
+// writeCSDocComment writes doc as a C# XML <summary>.
+func writeCSDocComment(b *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	b.WriteString(indent + "/// <summary>\n")
+	writeDocComment(b, indent+"/// ", html.EscapeString(doc))
+	b.WriteString(indent + "/// </summary>\n")
+}
+
+// writeJSDocComment writes doc as a /** */ block, as TypeScript and Unreal
+// tooling show it.
+func writeJSDocComment(b *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	doc = strings.ReplaceAll(doc, "*/", "*\\/")
+	if !strings.Contains(doc, "\n") {
+		b.WriteString(indent + "/** " + doc + " */\n")
+		return
+	}
+	b.WriteString(indent + "/**\n")
+	writeDocComment(b, indent+" * ", doc)
+	b.WriteString(indent + " */\n")
+}
+
+// GenerateConfigDocs renders CONFIG.md: one table of columns per sheet, with
+// the define-row comments as descriptions.
+func GenerateConfigDocs(rootName string, sheets []*Sheet) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\nGenerated by genxls from the define-row cell comments; edit the workbooks, not this file.\n", rootName)
+	for _, sheet := range sheets {
+		fmt.Fprintf(&b, "\n## %s\n\n", sheet.TypeName)
+		fmt.Fprintf(&b, "Source: `%s`, payload key `%s`", sheet.Origin, sheet.JSONKey)
+		if sheet.Owner != "" {
+			fmt.Fprintf(&b, ", owner %s", sheet.Owner)
+		}
+		b.WriteString(".\n\n")
+		b.WriteString("| Column | Type | Export | Description |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, f := range sheet.Fields {
+			fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n", f.RawName, f.RawType, fieldFlagDoc(f.Flag), markdownCell(f.Doc))
+		}
+	}
+	return b.String()
+}
+
+func fieldFlagDoc(f FieldFlag) string {
+	switch f {
+	case FieldFlagServer:
+		return "server"
+	case FieldFlagClient:
+		return "client"
+	default:
+		return "all"
+	}
+}
+
+// markdownCell makes text safe inside a table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -0,0 +1,89 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseDriftLimit parses a drift rule such as "50%" (or the fraction "0.5")
+// into the largest allowed relative change.
+func parseDriftLimit(s string) (float64, error) {
+	t := strings.TrimSpace(s)
+	pct := strings.HasSuffix(t, "%")
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(t, "%")), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid drift limit %q (expect e.g. 50%%)", s)
+	}
+	if pct {
+		v /= 100
+	}
+	return v, nil
+}
+
+// driftValues records the current value of every drift-limited column by row
+// key, for the next run to compare against.
+func driftValues(sheet *Sheet, keys []string) map[string]map[string]float64 {
+	if len(sheet.Drift) == 0 {
+		return nil
+	}
+	out := make(map[string]map[string]float64, len(sheet.Drift))
+	for _, f := range sheet.Fields {
+		if _, ok := sheet.Drift[f.RawName]; !ok {
+			continue
+		}
+		vals := columnValues(sheet, f)
+		col := make(map[string]float64, len(vals))
+		for i, v := range vals {
+			col[keys[i]] = v
+		}
+		out[f.RawName] = col
+	}
+	return out
+}
+
+// checkDrift reports rows whose drift-limited columns changed by more than
+// their limit since the previous run. Added and removed rows are not drift.
+func checkDrift(prev *RunState, sheets []*Sheet) ([]Problem, error) {
+	var reports []Problem
+	for _, sheet := range sheets {
+		old, ok := prev.Sheets[sheet.JSONKey]
+		if !ok || len(sheet.Drift) == 0 {
+			continue
+		}
+		keys, err := rowKeys(sheet)
+		if err != nil {
+			return nil, err
+		}
+		cur := driftValues(sheet, keys)
+		cols := make([]string, 0, len(cur))
+		for col := range cur {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		for _, col := range cols {
+			limit := sheet.Drift[col]
+			for _, key := range keys {
+				before, ok := old.Values[col][key]
+				if !ok {
+					continue
+				}
+				after := cur[col][key]
+				if before == after {
+					continue
+				}
+				change := math.Inf(1)
+				if before != 0 {
+					change = math.Abs(after-before) / math.Abs(before)
+				}
+				if change > limit {
+					reports = append(reports, problemf(sheet, "%s.%s[%s]: %s -> %s exceeds the %s%% drift limit",
+						sheet.JSONKey, col, key, formatStat(before), formatStat(after), formatStat(limit*100)))
+				}
+			}
+		}
+	}
+	return reports, nil
+}
@@ -0,0 +1,201 @@
+package genxls
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// driveAPI is the Drive v3 endpoint; a variable so it can be pointed at a fake
+// with -ldflags "-X main.driveAPI=...".
+var driveAPI = "https://www.googleapis.com/drive/v3"
+
+const (
+	driveFolderMime      = "application/vnd.google-apps.folder"
+	driveSpreadsheetMime = "application/vnd.google-apps.spreadsheet"
+	xlsxMime             = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// driveConnector reads Google Drive folders (--in drive:<folderId>).
+type driveConnector struct {
+	client *remoteClient
+}
+
+func newDriveConnector(cfg *Config) (remoteConnector, error) {
+	token, err := driveAccessToken(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &driveConnector{client: &remoteClient{
+		every: time.Second / 8,
+		auth:  func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) },
+		// 403 is how Drive reports rate limits besides 429.
+		rateLimited: func(status int, body []byte) bool {
+			return status == http.StatusForbidden && strings.Contains(string(body), "ateLimitExceeded")
+		},
+	}}, nil
+}
+
+// list returns the native spreadsheets and uploaded workbooks of a folder;
+// everything else, subfolders included, is ignored. Uploaded files change
+// with their head revision, native spreadsheets, which have no revisions to
+// download, with their version.
+func (c *driveConnector) list(folderID string) ([]remoteFile, error) {
+	var files []remoteFile
+	pageToken := ""
+	for {
+		q := url.Values{
+			"q":                         {fmt.Sprintf("'%s' in parents and trashed = false", strings.ReplaceAll(folderID, "'", `\'`))},
+			"fields":                    {"nextPageToken,files(id,name,mimeType,headRevisionId,version)"},
+			"pageSize":                  {"1000"},
+			"supportsAllDrives":         {"true"},
+			"includeItemsFromAllDrives": {"true"},
+		}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		data, err := c.client.get(driveAPI + "/files?" + q.Encode())
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			NextPageToken string `json:"nextPageToken"`
+			Files         []struct {
+				ID             string `json:"id"`
+				Name           string `json:"name"`
+				MimeType       string `json:"mimeType"`
+				HeadRevisionID string `json:"headRevisionId"`
+				Version        string `json:"version"`
+			} `json:"files"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("folder %s: %w", folderID, err)
+		}
+		for _, f := range page.Files {
+			switch {
+			case f.MimeType == driveSpreadsheetMime:
+				files = append(files, remoteFile{ID: f.ID, Name: exportedName(f.Name), Revision: "v" + f.Version, Kind: f.MimeType})
+			case f.MimeType != driveFolderMime && isWorkbookName(f.Name):
+				files = append(files, remoteFile{ID: f.ID, Name: f.Name, Revision: f.HeadRevisionID, Kind: f.MimeType})
+			}
+		}
+		if pageToken = page.NextPageToken; pageToken == "" {
+			return files, nil
+		}
+	}
+}
+
+// download returns the content of a workbook, exporting native spreadsheets
+// to .xlsx.
+func (c *driveConnector) download(f remoteFile) ([]byte, error) {
+	u := driveAPI + "/files/" + url.PathEscape(f.ID)
+	if f.Kind == driveSpreadsheetMime {
+		return c.client.get(u + "/export?" + url.Values{"mimeType": {xlsxMime}}.Encode())
+	}
+	return c.client.get(u + "?alt=media&supportsAllDrives=true")
+}
+
+// driveAccessToken returns an OAuth token with read access to Drive: the
+// GOOGLE_OAUTH_ACCESS_TOKEN environment variable (e.g. from `gcloud auth
+// print-access-token`), or else one for the service account whose key file
+// GOOGLE_APPLICATION_CREDENTIALS names, as on CI. The config's "drive" entry
+// can give them as accessToken and credentials.
+func driveAccessToken(cfg *Config) (string, error) {
+	if t := connectorSetting(cfg, "drive", "GOOGLE_OAUTH_ACCESS_TOKEN", "accessToken"); t != "" {
+		return t, nil
+	}
+	keyFile := connectorSetting(cfg, "drive", "GOOGLE_APPLICATION_CREDENTIALS", "credentials")
+	if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") == "" && keyFile != "" && !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(cfg.dir, keyFile) // relative to the config file
+	}
+	if keyFile == "" {
+		return "", errors.New("set GOOGLE_OAUTH_ACCESS_TOKEN or GOOGLE_APPLICATION_CREDENTIALS (a service account key)")
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+	var key struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("%s: %w", keyFile, err)
+	}
+	if key.Type != "service_account" {
+		return "", fmt.Errorf("%s is not a service account key", keyFile)
+	}
+	assertion, err := serviceAccountJWT(key.ClientEmail, key.PrivateKey, key.TokenURI)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", keyFile, err)
+	}
+	resp, err := http.PostForm(key.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var tok struct {
+		AccessToken      string `json:"access_token"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("token: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("token: %s: %s", resp.Status, tok.ErrorDescription)
+	}
+	return tok.AccessToken, nil
+}
+
+// serviceAccountJWT signs the assertion exchanged for a read-only Drive token.
+func serviceAccountJWT(email, privateKey, aud string) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", errors.New("invalid private_key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("private_key is not an RSA key")
+	}
+	now := time.Now().Unix()
+	claims, err := json.Marshal(map[string]any{
+		"iss":   email,
+		"scope": "https://www.googleapis.com/auth/drive.readonly",
+		"aud":   aud,
+		"iat":   now,
+		"exp":   now + 3600,
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,101 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var erlBareAtomRe = regexp.MustCompile(`^[a-z][A-Za-z0-9_@]*$`)
+
+var erlReservedWords = map[string]bool{
+	"after": true, "and": true, "andalso": true, "band": true, "begin": true, "bnot": true,
+	"bor": true, "bsl": true, "bsr": true, "bxor": true, "case": true, "catch": true,
+	"cond": true, "div": true, "else": true, "end": true, "fun": true, "if": true,
+	"let": true, "maybe": true, "not": true, "of": true, "or": true, "orelse": true,
+	"receive": true, "rem": true, "try": true, "when": true, "xor": true,
+}
+
+// GenerateErlangBundle renders the payload as Erlang terms readable with
+// file:consult/1: one {SheetKey, [Row]} tuple per sheet, each row a map with
+// atom keys and binary strings.
+func GenerateErlangBundle(sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	for _, sheet := range sheets {
+		b.WriteString("{")
+		b.WriteString(erlAtom(sheet.JSONKey))
+		b.WriteString(", [")
+		for i, item := range sheet.Items {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n    #{")
+			for j, f := range sheet.Fields {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				v, err := erlValue(item[f.RawName])
+				if err != nil {
+					return "", fmt.Errorf("%s.%s: %w", sheet.TypeName, f.RawName, err)
+				}
+				b.WriteString(erlAtom(f.RawName))
+				b.WriteString(" => ")
+				b.WriteString(v)
+			}
+			b.WriteString("}")
+		}
+		if len(sheet.Items) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("]}.\n")
+	}
+	return b.String(), nil
+}
+
+func erlValue(v any) (string, error) {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x), nil
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return "", fmt.Errorf("erlang terms cannot represent %v", x)
+		}
+		// Erlang floats need digits on both sides of the point and no bare exponent.
+		s := strconv.FormatFloat(x, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case string:
+		s := strings.ReplaceAll(x, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		return `<<"` + s + `"/utf8>>`, nil
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return "[" + strings.Join(parts, ",") + "]", nil
+	case [][]int:
+		parts := make([]string, len(x))
+		for i, inner := range x {
+			parts[i], _ = erlValue(inner)
+		}
+		return "[" + strings.Join(parts, ",") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func erlAtom(s string) string {
+	if erlBareAtomRe.MatchString(s) && !erlReservedWords[s] {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
@@ -0,0 +1,116 @@
+package genxls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Loading sheets fails with these, possibly wrapped (use errors.As), so
+// callers can point at the cell or sheet at fault. Their messages are what the
+// command line prints.
+
+// SchemaError is a sheet that can't be read as a table: a bad header or
+// define row, a bad sheet name, or a name clashing with another sheet's.
+type SchemaError struct {
+	Origin string // e.g. xls/Item.xlsx[Item]
+	Sheet  string
+	Row    int // 1-based sheet row, 0 if not about one row
+	Err    error
+}
+
+func (e *SchemaError) Error() string { return e.Origin + ": " + e.Err.Error() }
+
+func (e *SchemaError) Unwrap() error { return e.Err }
+
+// CellError is a cell that could not be exported as written.
+type CellError struct {
+	Sheet string
+	Row   int // 1-based sheet row
+	Col   int // 1-based sheet column
+	Field string
+	Err   error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("row %d col %d (%s): %v", e.Row, e.Col, e.Field, e.Err)
+}
+
+func (e *CellError) Unwrap() error { return e.Err }
+
+// DuplicateKeyError is a key used twice: a sheet key shared by two sheets, or
+// with --merge-sheets a primary key in two merged sheets (Field set).
+type DuplicateKeyError struct {
+	Key       string
+	Field     string // the primary key column; "" for sheet keys
+	Sheet     string // origin of the second use
+	Row       int    // its 1-based sheet row, for primary keys
+	PrevSheet string // origin of the first use
+	PrevRow   int
+	Hint      string // what to do about a sheet key, on lines of its own
+}
+
+func (e *DuplicateKeyError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("duplicate sheet key %q: %s and %s%s", e.Key, e.PrevSheet, e.Sheet, e.Hint)
+	}
+	return fmt.Sprintf("--merge-sheets: %s %s is in both %s (row %d) and %s (row %d)", e.Field, e.Key, e.PrevSheet, e.PrevRow, e.Sheet, e.Row)
+}
+
+// LoadErrors is every sheet and cell that failed to load, so one run reports
+// them all instead of stopping at the first.
+type LoadErrors struct {
+	Errs []error // each prefixed with its sheet's origin
+	// Origins holds the origin of each of Errs, for grouping.
+	Origins []string
+	Max     int // errors to print; 0: all
+}
+
+func (e *LoadErrors) add(origin string, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			e.add(origin, err)
+		}
+		return
+	}
+	e.Errs = append(e.Errs, err)
+	e.Origins = append(e.Origins, origin)
+}
+
+// Error lists the errors grouped by origin, in the order the sheets were read;
+// a single error reads as it always has.
+func (e *LoadErrors) Error() string {
+	if len(e.Errs) == 1 {
+		return e.Errs[0].Error()
+	}
+	var order []string
+	byOrigin := make(map[string][]string)
+	shown := len(e.Errs)
+	if e.Max > 0 && shown > e.Max {
+		shown = e.Max
+	}
+	for i, err := range e.Errs[:shown] {
+		o := e.Origins[i]
+		if _, ok := byOrigin[o]; !ok {
+			order = append(order, o)
+		}
+		byOrigin[o] = append(byOrigin[o], strings.TrimPrefix(err.Error(), o+": "))
+	}
+	sheets := make(map[string]bool)
+	for _, o := range e.Origins {
+		sheets[o] = true
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d errors in %d sheets:", len(e.Errs), len(sheets))
+	for _, o := range order {
+		b.WriteString("\n" + o + ":")
+		for _, msg := range byOrigin[o] {
+			b.WriteString("\n  " + msg)
+		}
+	}
+	if n := len(e.Errs) - shown; n > 0 {
+		fmt.Fprintf(&b, "\n... and %d more (--max-errors %d)", n, e.Max)
+	}
+	return b.String()
+}
+
+func (e *LoadErrors) Unwrap() []error { return e.Errs }
@@ -0,0 +1,56 @@
+package genxls
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// excludePatterns returns the column name patterns to drop from every sheet:
+// the comma-separated --exclude list and the config's "exclude". Patterns are
+// path.Match globs like *_memo or tmp_*, matched ignoring case.
+func excludePatterns(flagValue string, cfg *Config) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(flagValue, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	if cfg != nil {
+		patterns = append(patterns, cfg.Exclude...)
+	}
+	for i, p := range patterns {
+		patterns[i] = strings.ToLower(p)
+		if _, err := path.Match(patterns[i], ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", p, err)
+		}
+	}
+	return patterns, nil
+}
+
+// excludeColumns drops the fields whose name matches a pattern. The primary
+// key can't be excluded.
+func excludeColumns(fields []Field, patterns []string) ([]Field, error) {
+	if len(patterns) == 0 {
+		return fields, nil
+	}
+	kept := fields[:0]
+	for i, f := range fields {
+		name := strings.ToLower(f.RawName)
+		excluded := false
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, name); ok {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, f)
+			continue
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("primary key %q matches an exclude pattern", f.RawName)
+		}
+	}
+	return kept, nil
+}
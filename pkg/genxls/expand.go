@@ -0,0 +1,440 @@
+package genxls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	rand "math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A directive row holds "@expand FROM..TO [step S] [seed K] [: KEY]" in its
+// primary key cell and expands into one row per n in FROM..TO. In every cell of
+// the row, ${expr} is replaced by the value of expr for that n, e.g.
+// "${n*100+50}" or "Level ${n}". KEY is the primary key template (default ${n}).
+var expandDirectiveRe = regexp.MustCompile(`^@expand\s+(-?\d+)\s*\.\.\s*(-?\d+)(?:\s+step\s+(\d+))?(?:\s+seed\s+(-?\d+))?\s*(?::\s*(.*))?$`)
+
+// maxExpandRows bounds a single directive so a typo can't produce millions of rows.
+const maxExpandRows = 100000
+
+// expandDirectiveRow returns the rows a sheet row stands for: the row itself,
+// or the expansion of a directive row. rowNum is the 1-based sheet row, the
+// default seed of rand().
+func expandDirectiveRow(row []string, keyCol, rowNum int) ([][]string, error) {
+	if keyCol < 0 || keyCol >= len(row) {
+		return [][]string{row}, nil
+	}
+	cell := strings.TrimSpace(row[keyCol])
+	if !strings.HasPrefix(cell, "@expand") {
+		return [][]string{row}, nil
+	}
+	m := expandDirectiveRe.FindStringSubmatch(cell)
+	if m == nil {
+		return nil, fmt.Errorf("invalid directive %q (expect @expand FROM..TO [step S] [seed K] [: KEY])", cell)
+	}
+	from, _ := strconv.Atoi(m[1])
+	to, _ := strconv.Atoi(m[2])
+	step := 1
+	if m[3] != "" {
+		step, _ = strconv.Atoi(m[3])
+	}
+	seed := int64(rowNum)
+	if m[4] != "" {
+		seed, _ = strconv.ParseInt(m[4], 10, 64)
+	}
+	key := "${n}"
+	if m[5] != "" {
+		key = m[5]
+	}
+	if step <= 0 || to < from {
+		return nil, fmt.Errorf("invalid directive %q: empty range", cell)
+	}
+	if (to-from)/step+1 > maxExpandRows {
+		return nil, fmt.Errorf("directive %q expands to more than %d rows", cell, maxExpandRows)
+	}
+
+	var out [][]string
+	for n, i := from, 0; n <= to; n, i = n+step, i+1 {
+		env := exprEnv{
+			vars: map[string]float64{"n": float64(n), "i": float64(i)},
+			rng:  rand.New(rand.NewPCG(uint64(seed), uint64(n))),
+		}
+		expanded := make([]string, len(row))
+		for c, s := range row {
+			if c == keyCol {
+				s = key
+			}
+			v, err := interpolateExprs(s, &env)
+			if err != nil {
+				return nil, fmt.Errorf("n=%d col %d: %w", n, c+1, err)
+			}
+			expanded[c] = v
+		}
+		out = append(out, expanded)
+	}
+	return out, nil
+}
+
+// interpolateExprs replaces every ${expr} in s.
+func interpolateExprs(s string, env *exprEnv) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+		end += start
+		expr := s[start+2 : end]
+		b.WriteString(s[:start])
+		s = s[end+1:]
+		if v, ok := env.strs[strings.TrimSpace(expr)]; ok {
+			b.WriteString(v)
+			continue
+		}
+		v, err := evalExpr(expr, env)
+		if err != nil {
+			return "", fmt.Errorf("${%s}: %w", expr, err)
+		}
+		b.WriteString(formatExprValue(v))
+	}
+}
+
+func formatExprValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type exprEnv struct {
+	vars map[string]float64
+	strs map[string]string // template parameters, substituted verbatim by ${name}
+	rng  *rand.Rand
+}
+
+// exprParser evaluates arithmetic over numbers, variables, + - * / % ^,
+// parentheses and a few functions, as it parses. Comparisons (== != < <= >
+// >=, also between strings in quotes or string variables), && || and ! yield
+// 1 for true and 0 for false.
+type exprParser struct {
+	src string
+	pos int
+	env *exprEnv
+}
+
+func evalExpr(src string, env *exprEnv) (float64, error) {
+	p := &exprParser{src: src, env: env}
+	v, err := p.or()
+	if err != nil {
+		return 0, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return 0, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("result is not a finite number")
+	}
+	return v, nil
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *exprParser) accept(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptOp is accept for operators of more than one byte.
+func (p *exprParser) acceptOp(op string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], op) {
+		p.pos += len(op)
+		return true
+	}
+	return false
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *exprParser) or() (float64, error) {
+	v, err := p.and()
+	for err == nil && p.acceptOp("||") {
+		var r float64
+		r, err = p.and()
+		v = truth(v != 0 || r != 0)
+	}
+	return v, err
+}
+
+func (p *exprParser) and() (float64, error) {
+	v, err := p.not()
+	for err == nil && p.acceptOp("&&") {
+		var r float64
+		r, err = p.not()
+		v = truth(v != 0 && r != 0)
+	}
+	return v, err
+}
+
+func (p *exprParser) not() (float64, error) {
+	if p.skipSpace(); strings.HasPrefix(p.src[p.pos:], "!") && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		v, err := p.not()
+		return truth(v == 0), err
+	}
+	return p.compare()
+}
+
+func (p *exprParser) compareOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.acceptOp(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *exprParser) compare() (float64, error) {
+	if l, ok, err := p.str(); ok || err != nil {
+		if err != nil {
+			return 0, err
+		}
+		op := p.compareOp()
+		if op != "==" && op != "!=" {
+			return 0, fmt.Errorf("strings compare with == and != only, not %q", op)
+		}
+		r, ok, err := p.str()
+		if err == nil && !ok {
+			err = errors.New("a string compares with a string only")
+		}
+		return truth((l == r) == (op == "==")), err
+	}
+	l, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	op := p.compareOp()
+	if op == "" {
+		return l, nil
+	}
+	r, err := p.sum()
+	switch op {
+	case "==":
+		return truth(l == r), err
+	case "!=":
+		return truth(l != r), err
+	case "<":
+		return truth(l < r), err
+	case "<=":
+		return truth(l <= r), err
+	case ">":
+		return truth(l > r), err
+	default:
+		return truth(l >= r), err
+	}
+}
+
+// str reads a string operand, a literal in double quotes or a string
+// variable; ok is false, with nothing read, for anything else.
+func (p *exprParser) str() (s string, ok bool, err error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		end := strings.IndexByte(p.src[p.pos+1:], '"')
+		if end < 0 {
+			return "", true, errors.New("unterminated string")
+		}
+		p.pos += end + 2
+		return p.src[start+1 : p.pos-1], true, nil
+	}
+	for p.pos < len(p.src) && isIdentByte(p.src[p.pos], p.pos > start) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if v, isStr := p.env.strs[name]; isStr && name != "" && !p.accept('(') {
+		if _, isNum := p.env.vars[name]; !isNum {
+			return v, true, nil
+		}
+	}
+	p.pos = start
+	return "", false, nil
+}
+
+func (p *exprParser) sum() (float64, error) {
+	v, err := p.product()
+	for err == nil {
+		var r float64
+		switch {
+		case p.accept('+'):
+			r, err = p.product()
+			v += r
+		case p.accept('-'):
+			r, err = p.product()
+			v -= r
+		default:
+			return v, nil
+		}
+	}
+	return 0, err
+}
+
+func (p *exprParser) product() (float64, error) {
+	v, err := p.power()
+	for err == nil {
+		var r float64
+		switch {
+		case p.accept('*'):
+			r, err = p.power()
+			v *= r
+		case p.accept('/'):
+			if r, err = p.power(); err == nil && r == 0 {
+				err = errors.New("division by zero")
+			}
+			v /= r
+		case p.accept('%'):
+			if r, err = p.power(); err == nil && r == 0 {
+				err = errors.New("division by zero")
+			}
+			v = math.Mod(v, r)
+		default:
+			return v, nil
+		}
+	}
+	return 0, err
+}
+
+func (p *exprParser) power() (float64, error) {
+	v, err := p.unary()
+	if err != nil || !p.accept('^') {
+		return v, err
+	}
+	r, err := p.power() // right-associative
+	return math.Pow(v, r), err
+}
+
+func (p *exprParser) unary() (float64, error) {
+	if p.accept('-') {
+		v, err := p.unary()
+		return -v, err
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (float64, error) {
+	if p.accept('(') {
+		v, err := p.or()
+		if err == nil && !p.accept(')') {
+			err = errors.New("missing )")
+		}
+		return v, err
+	}
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	if p.pos > start {
+		return strconv.ParseFloat(p.src[start:p.pos], 64)
+	}
+	for p.pos < len(p.src) && isIdentByte(p.src[p.pos], p.pos > start) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "" {
+		if p.pos < len(p.src) {
+			return 0, fmt.Errorf("unexpected %q", p.src[p.pos:])
+		}
+		return 0, errors.New("unexpected end of expression")
+	}
+	if !p.accept('(') {
+		v, ok := p.env.vars[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown or non-numeric variable %q", name)
+		}
+		return v, nil
+	}
+	var args []float64
+	if !p.accept(')') {
+		for {
+			v, err := p.or()
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, v)
+			if p.accept(')') {
+				break
+			}
+			if !p.accept(',') {
+				return 0, fmt.Errorf("missing ) after arguments of %s", name)
+			}
+		}
+	}
+	return p.env.call(name, args)
+}
+
+func isIdentByte(c byte, inner bool) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || inner && c >= '0' && c <= '9'
+}
+
+func (env *exprEnv) call(name string, args []float64) (float64, error) {
+	want := map[string]int{"floor": 1, "ceil": 1, "round": 1, "sqrt": 1, "abs": 1, "min": 2, "max": 2, "pow": 2, "rand": 2, "if": 3}
+	n, ok := want[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+	if len(args) != n {
+		return 0, fmt.Errorf("%s takes %d arguments, got %d", name, n, len(args))
+	}
+	switch name {
+	case "floor":
+		return math.Floor(args[0]), nil
+	case "ceil":
+		return math.Ceil(args[0]), nil
+	case "round":
+		return math.Round(args[0]), nil
+	case "sqrt":
+		return math.Sqrt(args[0]), nil
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "min":
+		return math.Min(args[0], args[1]), nil
+	case "max":
+		return math.Max(args[0], args[1]), nil
+	case "pow":
+		return math.Pow(args[0], args[1]), nil
+	case "if":
+		if args[0] != 0 {
+			return args[1], nil
+		}
+		return args[2], nil
+	default: // rand: integer in [lo, hi]
+		if env.rng == nil {
+			return 0, errors.New("rand() is only available in @expand rows")
+		}
+		lo, hi := math.Ceil(args[0]), math.Floor(args[1])
+		if hi < lo {
+			return 0, fmt.Errorf("rand(%g, %g): empty range", args[0], args[1])
+		}
+		return lo + float64(env.rng.Int64N(int64(hi-lo)+1)), nil
+	}
+}
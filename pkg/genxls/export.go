@@ -0,0 +1,694 @@
+package genxls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Export runs an export as the command line does once its flags are parsed:
+// load the workbooks opts.InPath names (or opts.Sources), check them and
+// write every artifact opts asks for. cfg is the config file (see
+// LoadConfig); nil means none. Cancelling ctx stops the run between sheets
+// and artifacts.
+func Export(ctx context.Context, opts Options, cfg *Config) (err error) {
+	log := opts.logger()
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if opts.Strict && opts.Lenient {
+		return errors.New("--strict and --lenient are mutually exclusive")
+	}
+	if opts.ScrubServer && opts.Flag != "client" {
+		return errors.New("--scrub-server requires --flag client")
+	}
+	if opts.Anonymize && (opts.Only != "" || opts.Changelog != "" || opts.UpdateLock || opts.DebugData) {
+		return errors.New("--anonymize can't be combined with --only, --changelog, --update-lock or --debug-data")
+	}
+	if opts.InPath == "" {
+		opts.InPath = "xls"
+	}
+	var inPaths []string
+	if opts.Sources == nil {
+		if inPaths, err = ResolveInputPaths(opts.InPath, cfg); err != nil {
+			return err
+		}
+	}
+	if opts.FloatToInt, err = parseFloatToIntPolicy(opts.FloatToInt); err != nil {
+		return err
+	}
+	langs, err := parseLangs(opts.Lang)
+	if err != nil {
+		return err
+	}
+	dataFormat, err := ParseDataFormat(opts.DataFormat)
+	if err != nil {
+		return err
+	}
+	if opts.GoEmbed && (!langs["go"] || !opts.JSON || dataFormat != "json") {
+		return errors.New("--go-embed requires the go target and the all.json payload (--json, --data-format json)")
+	}
+	if opts.Loader != "" && opts.Loader != "go" {
+		return fmt.Errorf("invalid --loader %q (expect go)", opts.Loader)
+	}
+	if opts.PbData && !langs["proto"] {
+		return errors.New("--pb-data requires the proto target")
+	}
+	if opts.PbGRPC && !langs["proto"] {
+		return errors.New("--pb-grpc requires the proto target")
+	}
+	if opts.Loader == "go" && !langs["go"] {
+		return errors.New("--loader go requires the go target")
+	}
+	if opts.GoPrometheus && (!langs["go"] || !opts.GoAccessor) {
+		return errors.New("--go-prometheus requires the go target and --go-accessor")
+	}
+	if opts.Flag != "" && opts.Flag != "server" && opts.Flag != "client" && opts.Flag != "both" {
+		return fmt.Errorf("invalid --flag %q (expect server|client|both)", opts.Flag)
+	}
+	if opts.Flag == "both" && (opts.Only != "" || opts.MongoURI != "" || (langs["go"] && len(cfg.GoPackages) > 0)) {
+		// Their outputs are not under --out, so both sides would write them.
+		return errors.New("--flag both can't be combined with --only, --mongo-uri or config goPackages")
+	}
+	if opts.Only != "" && (opts.Sparse || opts.Changelog != "" || opts.LastGreen != "" || opts.PublishSchema != "" || opts.VerifyAgainst != "") {
+		return errors.New("--only can't be combined with --sparse, --changelog, --last-green, --publish-schema or --verify-against")
+	}
+	if opts.DebugData && (opts.Sparse || (dataFormat != "json" && dataFormat != "jsonl")) {
+		return errors.New("--debug-data requires --data-format json or jsonl and can't be combined with --sparse")
+	}
+	if opts.Sparse && dataFormat != "json" {
+		return fmt.Errorf("--sparse requires --data-format json, got %s", dataFormat)
+	}
+	if opts.ChunkRows < 0 {
+		return fmt.Errorf("invalid --chunk-rows %d", opts.ChunkRows)
+	}
+	if opts.ChunkRows > 0 && (dataFormat != "json" || opts.Sparse || opts.GoEmbed || opts.Only != "") {
+		return errors.New("--chunk-rows requires --data-format json and can't be combined with --sparse, --go-embed or --only")
+	}
+	if len(inPaths) == 0 && len(opts.Sources) == 0 {
+		return errors.New("no input files")
+	}
+	maxPayload, err := ParseByteSize(opts.MaxPayload)
+	if err != nil {
+		return fmt.Errorf("--max-payload: %w", err)
+	}
+	if opts.MergeSheets && (opts.DebugData || opts.Provenance || opts.Annotate != "") {
+		// They attribute every row of a sheet to a single workbook.
+		return errors.New("--merge-sheets can't be combined with --debug-data, --provenance or --annotate")
+	}
+	if opts.MongoURI != "" && !opts.Mongo {
+		return errors.New("--mongo-uri requires --mongo")
+	}
+	if opts.Sink != "" && (opts.Only != "" || opts.MongoURI != "") {
+		// Both read the artifacts back from --out.
+		return errors.New("--sink can't be combined with --only or --mongo-uri")
+	}
+	if opts.Artifacts != nil && (opts.Only != "" || opts.MongoURI != "") {
+		return errors.New("Options.Artifacts can't be combined with --only or --mongo-uri")
+	}
+	if opts.Sink != "" && langs["go"] && len(cfg.GoPackages) > 0 {
+		// Archives and URLs hold paths relative to --out; goPackages write outside it.
+		return errors.New("--sink can't be combined with config goPackages")
+	}
+	if opts.LimitMode != "error" && opts.LimitMode != "warn" {
+		return fmt.Errorf("invalid --limit-mode %q (expect error|warn)", opts.LimitMode)
+	}
+
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockOutDir(ctx, opts.OutDir, opts.WaitLock, log)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var asOf time.Time
+	if opts.AsOf != "" {
+		if asOf, err = parseAsOf(opts.AsOf, cfg.zones); err != nil {
+			return fmt.Errorf("--as-of: %w", err)
+		}
+	}
+
+	rootName := "AllConfig"
+	dataName := "all"
+
+	// Aggregated output:
+	// - generate one go.gen.go/Pb.gen.Pb/ts.gen.ts
+	// - generate one all.json with keys based on sheet name (pluralized)
+	sources := opts.Sources
+	if sources == nil {
+		sources = FileSources(inPaths, opts, cfg)
+	}
+	sheets, err := LoadSources(ctx, sources, opts, cfg)
+	if err != nil {
+		return err
+	}
+	if err := ApplyConfig(cfg, sheets, opts.Only != ""); err != nil {
+		return err
+	}
+	if problems := checkWindows(sheets); len(problems) > 0 {
+		return errors.New("invalid active windows:\n" + FormatProblems(problems))
+	}
+	if problems := checkRefs(sheets); len(problems) > 0 {
+		return errors.New("broken references:\n" + FormatProblems(problems))
+	}
+	if opts.AsOf != "" {
+		dropped := applyAsOf(sheets, asOf)
+		for _, sheet := range sheets {
+			if n, ok := dropped[sheet]; ok {
+				log.Info(fmt.Sprintf("%s: %d rows inactive as of %s", sheet.Origin, n, cfg.zones.format(asOf)), "sheet", sheet.JSONKey)
+			}
+		}
+	}
+	if opts.Bundle != "" {
+		if sheets, err = filterBundle(sheets, opts.Bundle); err != nil {
+			return err
+		}
+		rootName = bundleRootName(opts.Bundle)
+		dataName = strings.ToLower(opts.Bundle)
+	}
+	if opts.Sparse {
+		if err := markSparseSheets(sheets); err != nil {
+			return err
+		}
+	}
+	markChunkedSheets(sheets, opts.ChunkRows)
+	var roots []Root
+	if opts.Bundle == "" && opts.Only == "" {
+		if roots, err = configRoots(cfg, sheets, rootName, dataName); err != nil {
+			return err
+		}
+	}
+	if len(roots) > 0 && (opts.Sparse || opts.ChunkRows > 0 || isPerSheetFormat(dataFormat)) {
+		return fmt.Errorf("config roots can't be combined with --sparse, --chunk-rows or --data-format %s", dataFormat)
+	}
+	sink := opts.Artifacts
+	if sink == nil {
+		if sink, err = NewArtifactSink(opts.Sink, opts.OutDir); err != nil {
+			return err
+		}
+		if a, ok := sink.(artifactAborter); ok {
+			defer func() {
+				if err != nil {
+					a.Abort()
+				}
+			}()
+		}
+	}
+	out := &OutputLayout{
+		OutDir:   opts.OutDir,
+		Template: opts.OutTemplate,
+		Targets:  cfg.Outputs,
+		Bundle:   opts.Bundle,
+		DataName: dataName,
+		RootName: rootName,
+		Sink:     sink,
+		Context:  ctx,
+	}
+
+	schemaSet := buildSchemaSet(sheets)
+	if opts.VerifyAgainst != "" {
+		pinned, err := loadPinnedSchemas(opts.VerifyAgainst)
+		if err != nil {
+			return err
+		}
+		if err := verifySchemaCompat(schemaSet, pinned); err != nil {
+			return err
+		}
+	}
+
+	limits := Limits{MaxRows: opts.MaxRows, MaxStringLen: opts.MaxStringLen, MaxPayloadBytes: maxPayload}
+	problems, err := checkLimits(limits, sheets)
+	if err != nil {
+		return err
+	}
+
+	var hints []Problem
+	if opts.Stats {
+		if opts.OutlierFactor <= 1 {
+			return fmt.Errorf("invalid --outlier-factor %g (expect > 1)", opts.OutlierFactor)
+		}
+		stats := make([][]ColumnStats, len(sheets))
+		for i, sheet := range sheets {
+			stats[i] = numericColumnStats(sheet)
+			hints = append(hints, findOutliers(sheet, stats[i], opts.OutlierFactor)...)
+		}
+		if err := printColumnStats(opts.report(), sheets, stats); err != nil {
+			return err
+		}
+		for _, h := range hints {
+			log.Warn(fmt.Sprint("possible outlier: ", h))
+		}
+	}
+
+	if opts.Annotate != "" {
+		bad := badCellProblems(sheets)
+		files, err := writeAnnotatedWorkbooks(opts.Annotate, slices.Concat(bad, problems, hints), cfg, opts.Password)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			log.Info("annotated "+f, "path", f)
+		}
+		if len(bad) > 0 && !opts.Lenient {
+			return errors.New("invalid cells (see the annotated copies):\n" + FormatProblems(bad))
+		}
+	}
+
+	if len(problems) > 0 {
+		if opts.LimitMode == "error" {
+			return errors.New("export limits exceeded:\n" + FormatProblems(problems))
+		}
+		for _, p := range problems {
+			log.Warn(fmt.Sprint(p))
+		}
+	}
+
+	prevState, err := loadRunState(statePath(opts.OutDir))
+	if err != nil {
+		return err
+	}
+	if reports := checkColumnOrder(prevState, sheets); len(reports) > 0 {
+		for _, r := range reports {
+			log.Warn(fmt.Sprint(r))
+		}
+		if opts.FailOnReorder {
+			return errors.New("column order changed since the last run (see warnings above)")
+		}
+	}
+	drift, err := checkDrift(prevState, sheets)
+	if err != nil {
+		return err
+	}
+	if len(drift) > 0 {
+		if !opts.AllowDrift {
+			return errors.New("values drifted beyond their limits since the last run (rerun with --allow-drift to accept):\n" + FormatProblems(drift))
+		}
+		for _, d := range drift {
+			log.Warn(fmt.Sprint(d))
+		}
+	}
+	lock, err := loadLock(opts.LockFile)
+	if err != nil {
+		return err
+	}
+	partial := opts.Only != "" || opts.Bundle != ""
+	if lock != nil && !opts.UpdateLock {
+		for _, side := range exportSides(opts.Flag, sheets) {
+			if problems := checkLock(lock, lockSection(side.Flag), side.Sheets, partial, langs["proto"]); len(problems) > 0 {
+				return fmt.Errorf("schemas differ from %s (approve with --update-lock):\n%s", opts.LockFile, FormatProblems(problems))
+			}
+		}
+	}
+	ids, err := LoadIDRanges(opts.IDsFile)
+	if err != nil {
+		return err
+	}
+	if problems := CheckIDRanges(ids, sheets); len(problems) > 0 {
+		return fmt.Errorf("id collisions (see %s and genxls next-id):\n%s", opts.IDsFile, FormatProblems(problems))
+	}
+	if problems := checkIDBounds(sheets); len(problems) > 0 {
+		return errors.New("ids outside their sheet's range (rows pasted into the wrong sheet?):\n" + FormatProblems(problems))
+	}
+	if opts.Only != "" {
+		return runOnly(opts, cfg, out, langs, dataFormat, sheets, prevState)
+	}
+	if opts.Anonymize {
+		if err := anonymizeSheets(sheets, opts.AnonymizeKey); err != nil {
+			return err
+		}
+	}
+
+	sides := exportSides(opts.Flag, sheets)
+	// Before generating: new proto columns take their numbers from the
+	// updated lock.
+	if opts.UpdateLock {
+		for _, side := range sides {
+			lock = updateLock(lock, lockSection(side.Flag), side.Sheets, partial, langs["proto"])
+		}
+	}
+	for _, side := range sides {
+		sheets, out, roots := side.Sheets, out.sideLayout(side), roots
+		if side.Dir != "" && len(roots) > 0 {
+			if roots, err = configRoots(cfg, sheets, rootName, dataName); err != nil {
+				return err
+			}
+		}
+
+		// Generate aggregated code
+		if langs["go"] {
+			goCode, err := GenerateGoBundle(opts.Pkg, rootName, sheets, opts.GoAccessor, opts.Loader == "go")
+			if err != nil {
+				return err
+			}
+			goCode += goRootTypes(roots)
+			outFile, err := out.WriteFile("go", "go.gen.go", nil, []byte(goCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+			if opts.GoEmbed {
+				embedFile, err := out.Path("go", "data.gen.go", nil)
+				if err != nil {
+					return err
+				}
+				dataFile, err := out.Path("json", dataName+".json", nil)
+				if err != nil {
+					return err
+				}
+				rel, err := embedPath(embedFile, dataFile)
+				if err != nil {
+					return err
+				}
+				if err := out.write(embedFile, []byte(generateGoEmbed(opts.Pkg, rootName, rel, opts.GoAccessor, opts.Loader == "go"))); err != nil {
+					return err
+				}
+				log.Info("generated "+embedFile, "path", embedFile)
+			}
+			if opts.GoPrometheus {
+				promFile, err := out.WriteFile("go", "prometheus.gen.go", nil, []byte(generateGoPrometheus(opts.Pkg)))
+				if err != nil {
+					return err
+				}
+				log.Info("generated "+promFile, "path", promFile)
+			}
+			files, err := writeGoPackages(cfg, out, sheets, rootName, dataName, opts.GoEmbed, opts.GoAccessor, opts.Loader == "go", opts.GoPrometheus)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				log.Info("generated "+f, "path", f)
+			}
+		}
+		if langs["Pb"] {
+			csCode, err := GenerateCSBundle(rootName, sheets)
+			if err != nil {
+				return err
+			}
+			csCode += csRootTypes(roots)
+			outFile, err := out.WriteFile("Pb", "Pb.gen.Pb", nil, []byte(csCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+			if opts.CSStubs != "" {
+				stubs, err := writeCSStubs(opts.CSStubs, rootName, sheets)
+				if err != nil {
+					return err
+				}
+				for _, f := range stubs {
+					log.Info("created "+f, "path", f)
+				}
+			}
+		}
+		if langs["ts"] {
+			tsCode, err := GenerateTSBundle(rootName, sheets, opts.TSGuards)
+			if err != nil {
+				return err
+			}
+			tsCode += tsRootTypes(roots)
+			outFile, err := out.WriteFile("ts", "ts.gen.ts", nil, []byte(tsCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+		if langs["gd"] {
+			gdCode, err := GenerateGDBundle(rootName, sheets)
+			if err != nil {
+				return err
+			}
+			outFile, err := out.WriteFile("gd", "gd.gen.gd", nil, []byte(gdCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+		if langs["dart"] {
+			dartCode, err := GenerateDartBundle(rootName, sheets)
+			if err != nil {
+				return err
+			}
+			outFile, err := out.WriteFile("dart", "dart.gen.dart", nil, []byte(dartCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+		if langs["capnp"] {
+			schema, err := GenerateCapnpSchema(rootName, sheets)
+			if err != nil {
+				return err
+			}
+			schemaFile, err := out.WriteFile("capnp", "capnp.gen.capnp", nil, []byte(schema))
+			if err != nil {
+				return err
+			}
+			data, err := encodeCapnpPayload(sheets)
+			if err != nil {
+				return err
+			}
+			dataFile, err := out.WriteFile("capnp", dataName+".capnp.bin", nil, data)
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+schemaFile, "path", schemaFile)
+			log.Info("generated "+dataFile, "path", dataFile)
+		}
+		if langs["proto"] {
+			applyProtoNumbers(lock[lockSection(side.Flag)], sheets)
+			schema, err := GenerateProtoSchema(opts.Pkg, rootName, sheets)
+			if err != nil {
+				return err
+			}
+			if opts.PbGRPC {
+				service, err := protoService(opts.Pkg, rootName, sheets)
+				if err != nil {
+					return err
+				}
+				schema += service
+			}
+			schemaFile, err := out.WriteFile("proto", opts.Pkg+".proto", nil, []byte(schema))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+schemaFile, "path", schemaFile)
+			if opts.PbData {
+				data, err := encodeProtoPayload(sheets)
+				if err != nil {
+					return err
+				}
+				dataFile, err := out.WriteFile("proto", dataName+".pb", nil, data)
+				if err != nil {
+					return err
+				}
+				log.Info("generated "+dataFile, "path", dataFile)
+			}
+			if opts.PbGRPC {
+				serverFile, err := out.WriteFile("proto", "grpc.gen.go", nil, []byte(generateGoGRPCServer(opts.Pkg, rootName, sheets)))
+				if err != nil {
+					return err
+				}
+				log.Info("generated "+serverFile, "path", serverFile)
+			}
+		}
+		if langs["ue"] {
+			ueCode, err := GenerateUEBundle(sheets)
+			if err != nil {
+				return err
+			}
+			headerFile, err := out.WriteFile("ue", "ue.gen.h", nil, []byte(ueCode))
+			if err != nil {
+				return err
+			}
+			outFiles := []string{headerFile}
+			for _, sheet := range sheets {
+				data, err := generateUECSV(sheet.Fields, sheet.Items)
+				if err != nil {
+					return fmt.Errorf("%s: %w", sheet.TypeName, err)
+				}
+				csvFile, err := out.WriteFile("ue", sheet.TypeName+".csv", sheet, data)
+				if err != nil {
+					return err
+				}
+				outFiles = append(outFiles, csvFile)
+			}
+			for _, f := range outFiles {
+				log.Info("generated "+f, "path", f)
+			}
+		}
+		if langs["php"] {
+			phpCode, err := GeneratePHPBundle(sheets)
+			if err != nil {
+				return err
+			}
+			outFile, err := out.WriteFile("php", "php.gen.php", nil, []byte(phpCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+		if langs["erl"] {
+			erlCode, err := GenerateErlangBundle(sheets)
+			if err != nil {
+				return err
+			}
+			outFile, err := out.WriteFile("erl", "erl.gen.config", nil, []byte(erlCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+		if langs["lua"] {
+			luaCode, err := GenerateLuaBundle(rootName, sheets)
+			if err != nil {
+				return err
+			}
+			outFile, err := out.WriteFile("lua", "lua.gen.lua", nil, []byte(luaCode))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+
+		if opts.JSON {
+			files, err := WriteDataPayload(out, dataFormat, sheets)
+			if err != nil {
+				return err
+			}
+			for _, r := range roots {
+				rootFiles, err := WriteDataPayload(out.rootLayout(r), dataFormat, r.Sheets)
+				if err != nil {
+					return err
+				}
+				files = append(files, rootFiles...)
+			}
+			for _, f := range files {
+				log.Info("generated "+f, "path", f)
+			}
+		}
+		if opts.Parquet {
+			files, err := writeParquetBundle(out, sheets)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				log.Info("generated "+f, "path", f)
+			}
+		}
+		if opts.Avro {
+			files, err := writeAvroBundle(out, sheets)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				log.Info("generated "+f, "path", f)
+			}
+		}
+		if opts.Redis {
+			data, err := GenerateRedisBundle(sheets)
+			if err != nil {
+				return err
+			}
+			outFile, err := out.WriteFile("redis", "redis.gen.resp", nil, data)
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+		if opts.Mongo {
+			files, err := writeMongoBundle(out, sheets)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				log.Info("generated "+f, "path", f)
+			}
+			if opts.MongoURI != "" {
+				if err := mongoImport(opts.MongoURI, sheets, files); err != nil {
+					return err
+				}
+				log.Info(fmt.Sprintf("imported %d collections", len(sheets)))
+			}
+		}
+		if opts.Provenance {
+			outFile, err := writeProvenance(out, sheets)
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+		if opts.Docs {
+			outFile, err := out.WriteFile("docs", "CONFIG.md", nil, []byte(GenerateConfigDocs(rootName, sheets)))
+			if err != nil {
+				return err
+			}
+			log.Info("generated "+outFile, "path", outFile)
+		}
+	}
+
+	if c, ok := sink.(io.Closer); ok && opts.Artifacts == nil {
+		if err := c.Close(); err != nil {
+			return err
+		}
+		log.Info("wrote "+opts.Sink, "path", opts.Sink)
+	}
+	if opts.PublishSchema != "" {
+		dest, err := publishSchemaSet(opts.PublishSchema, schemaSet)
+		if err != nil {
+			return err
+		}
+		log.Info("published schema to "+dest, "path", dest)
+	}
+
+	curState, err := buildRunState(sheets)
+	if err != nil {
+		return err
+	}
+	if opts.Changelog != "" {
+		if entry := buildChangelogEntry(prevState, curState, sheets, time.Now()); entry != "" {
+			if err := prependChangelog(opts.Changelog, entry); err != nil {
+				return err
+			}
+			log.Info("updated "+opts.Changelog, "path", opts.Changelog)
+		}
+	}
+	// Scrambled values would show up as drift in the next real run.
+	if !opts.Anonymize {
+		if err := saveRunState(statePath(opts.OutDir), curState); err != nil {
+			return err
+		}
+	}
+	if opts.UpdateLock {
+		if err := saveLock(opts.LockFile, lock); err != nil {
+			return err
+		}
+		log.Info("updated "+opts.LockFile, "path", opts.LockFile)
+	}
+	if opts.LastGreen != "" {
+		prev, err := loadRunSummary(opts.LastGreen)
+		if err != nil {
+			return err
+		}
+		if err := printLastGreenDiff(opts.report(), prev, curState, sheets); err != nil {
+			return err
+		}
+		if !opts.Anonymize {
+			if err := saveRunSummary(opts.LastGreen, buildRunSummary(curState, time.Now())); err != nil {
+				return err
+			}
+		}
+	}
+	if opts.Verbose {
+		if err := printRunSummary(opts.report(), sheets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Feishu and Lark are the same suite under two domains; variables so they
+// can be pointed at a fake with -ldflags.
+var (
+	feishuAPI = "https://open.feishu.cn/open-apis"
+	larkAPI   = "https://open.larksuite.com/open-apis"
+)
+
+// feishuConnector reads Feishu/Lark Drive folders (--in feishu:<folderToken>,
+// --in lark:<folderToken>).
+type feishuConnector struct {
+	api    string
+	client *remoteClient
+}
+
+// feishuResponse is the envelope of every Feishu API response.
+type feishuResponse struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// feishuRateLimited is the code of "request trigger frequency limit".
+const feishuRateLimited = 99991400
+
+// newFeishuConnector authenticates with <SCHEME>_ACCESS_TOKEN (a tenant or
+// user access token), or else gets a tenant token for the app whose
+// <SCHEME>_APP_ID and <SCHEME>_APP_SECRET are given; the config's entry can
+// hold accessToken, appId and appSecret instead.
+func newFeishuConnector(cfg *Config, scheme, api string) (remoteConnector, error) {
+	prefix := strings.ToUpper(scheme) + "_"
+	token := connectorSetting(cfg, scheme, prefix+"ACCESS_TOKEN", "accessToken")
+	c := &feishuConnector{api: api, client: &remoteClient{
+		every: time.Second / 5,
+		rateLimited: func(status int, body []byte) bool {
+			var r feishuResponse
+			return json.Unmarshal(body, &r) == nil && r.Code == feishuRateLimited
+		},
+	}}
+	if token == "" {
+		appID := connectorSetting(cfg, scheme, prefix+"APP_ID", "appId")
+		secret := connectorSetting(cfg, scheme, prefix+"APP_SECRET", "appSecret")
+		if appID == "" || secret == "" {
+			return nil, fmt.Errorf("set %sACCESS_TOKEN, or %sAPP_ID and %sAPP_SECRET", prefix, prefix, prefix)
+		}
+		data, err := c.call(http.MethodPost, "/auth/v3/tenant_access_token/internal", map[string]string{"app_id": appID, "app_secret": secret})
+		if err != nil {
+			return nil, err
+		}
+		// This endpoint puts the token next to code instead of in data.
+		var tok struct {
+			TenantAccessToken string `json:"tenant_access_token"`
+		}
+		if err := json.Unmarshal(data, &tok); err != nil {
+			return nil, fmt.Errorf("tenant token: %w", err)
+		}
+		token = tok.TenantAccessToken
+	}
+	c.client.auth = func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	return c, nil
+}
+
+// call sends an API request and returns the raw response after checking its
+// code.
+func (c *feishuConnector) call(method, path string, body any) ([]byte, error) {
+	data, err := c.client.do(method, c.api+path, body)
+	if err != nil {
+		return nil, err
+	}
+	var r feishuResponse
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.Code != 0 {
+		return nil, fmt.Errorf("%s: %s (code %d)", path, r.Msg, r.Code)
+	}
+	return data, nil
+}
+
+// callData is call decoding the response's data into v.
+func (c *feishuConnector) callData(method, path string, body, v any) error {
+	data, err := c.call(method, path, body)
+	if err != nil {
+		return err
+	}
+	var r feishuResponse
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// list returns the native sheets and uploaded workbooks of a folder, which
+// change with their modification time.
+func (c *feishuConnector) list(folder string) ([]remoteFile, error) {
+	var files []remoteFile
+	pageToken := ""
+	for {
+		q := url.Values{"folder_token": {folder}, "page_size": {"200"}}
+		if pageToken != "" {
+			q.Set("page_token", pageToken)
+		}
+		var page struct {
+			Files []struct {
+				Token        string `json:"token"`
+				Name         string `json:"name"`
+				Type         string `json:"type"`
+				ModifiedTime string `json:"modified_time"`
+			} `json:"files"`
+			HasMore       bool   `json:"has_more"`
+			NextPageToken string `json:"next_page_token"`
+		}
+		if err := c.callData(http.MethodGet, "/drive/v1/files?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, f := range page.Files {
+			switch {
+			case f.Type == "sheet":
+				files = append(files, remoteFile{ID: f.Token, Name: exportedName(f.Name), Revision: f.ModifiedTime, Kind: f.Type})
+			case f.Type == "file" && isWorkbookName(f.Name):
+				files = append(files, remoteFile{ID: f.Token, Name: f.Name, Revision: f.ModifiedTime, Kind: f.Type})
+			}
+		}
+		if pageToken = page.NextPageToken; !page.HasMore || pageToken == "" {
+			return files, nil
+		}
+	}
+}
+
+// download fetches an uploaded workbook, or exports a sheet to .xlsx through
+// an export task.
+func (c *feishuConnector) download(f remoteFile) ([]byte, error) {
+	if f.Kind == "file" {
+		return c.client.get(c.api + "/drive/v1/files/" + url.PathEscape(f.ID) + "/download")
+	}
+	var task struct {
+		Ticket string `json:"ticket"`
+	}
+	req := map[string]string{"file_extension": "xlsx", "token": f.ID, "type": "sheet"}
+	if err := c.callData(http.MethodPost, "/drive/v1/export_tasks", req, &task); err != nil {
+		return nil, err
+	}
+	var fileToken string
+	err := pollExport(f.Name, func() (bool, error) {
+		var status struct {
+			Result struct {
+				JobStatus   int    `json:"job_status"`
+				JobErrorMsg string `json:"job_error_msg"`
+				FileToken   string `json:"file_token"`
+			} `json:"result"`
+		}
+		path := "/drive/v1/export_tasks/" + url.PathEscape(task.Ticket) + "?" + url.Values{"token": {f.ID}}.Encode()
+		if err := c.callData(http.MethodGet, path, nil, &status); err != nil {
+			return false, err
+		}
+		switch r := status.Result; r.JobStatus {
+		case 0:
+			fileToken = r.FileToken
+			return true, nil
+		case 1, 2: // initializing, processing
+			return false, nil
+		default:
+			return false, errors.New("export failed: " + r.JobErrorMsg)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.client.get(c.api + "/drive/v1/export_tasks/file/" + url.PathEscape(fileToken) + "/download")
+}
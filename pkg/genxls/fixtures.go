@@ -0,0 +1,65 @@
+package genxls
+
+import (
+	"errors"
+	"math"
+	rand "math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FixtureValue returns a value for row i of column f with the same Go type the
+// parser produces. Key values are derived from i so they never collide.
+func FixtureValue(f Field, i int, key bool, rng *rand.Rand) (any, error) {
+	switch strings.ToLower(f.RawType) {
+	case "int", "int32", "int64":
+		if key {
+			return i + 1, nil
+		}
+		return rng.IntN(1000), nil
+	case "float", "float32", "float64":
+		if key {
+			return float64(i + 1), nil
+		}
+		return math.Round(rng.Float64()*100000) / 100, nil
+	case "bool":
+		if key {
+			return i == 1, nil
+		}
+		return rng.IntN(2) == 1, nil
+	case "string":
+		if key {
+			return f.RawName + "_" + strconv.Itoa(i+1), nil
+		}
+		return f.RawName + "_" + strconv.Itoa(rng.IntN(1000)), nil
+	case "datetime":
+		// Windows end a year after they start, so __start/__end rows stay valid.
+		t := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, rng.IntN(365))
+		if key {
+			t = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i)
+		}
+		if f.RawName == windowEndColumn {
+			t = t.AddDate(1, 0, 0)
+		}
+		return formatDateTime(t), nil
+	case "int[]":
+		return fixtureInts(rng), nil
+	case "int[][]":
+		out := make([][]int, rng.IntN(3))
+		for k := range out {
+			out[k] = fixtureInts(rng)
+		}
+		return out, nil
+	default:
+		return nil, errors.New("unsupported type " + strconv.Quote(f.RawType))
+	}
+}
+
+func fixtureInts(rng *rand.Rand) []int {
+	out := make([]int, rng.IntN(4))
+	for k := range out {
+		out[k] = rng.IntN(100)
+	}
+	return out
+}
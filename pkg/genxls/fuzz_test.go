@@ -0,0 +1,84 @@
+package genxls
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fuzzSeeds are what designers type, and what has broken the parsers before.
+var fuzzSeeds = []string{
+	"", "0", "-1", "42", "3.14", "1,234", "0x1F", "true", "TRUE", "yes",
+	"2147483648", "-2147483649", "9223372036854775807", "9223372036854775808", "1e309", "-1e309", "NaN", "Inf",
+	"99999999999999999999999999999999", "{99999999999999999999}", "1e18", "45413.5", "2958465.9999",
+	"{}", "{1,2,3}", "1,2,3", "{{1,2},{3}}", `"{1}"`, "{1,{2}}", "[1]", "{-0}", "{01}", "{ 1 , 2 }", "{1,}",
+	"{1, 2}", "{{{1}}}", strings.Repeat("{", 64) + "1" + strings.Repeat("}", 64), strings.Repeat("{", 10000),
+	"2024-05-01 10:00", "2024-05-01T10:00:00+08:00",
+	"\xff\xfe", "caf\xc3", "{1,\xff}", "名前", "　", "\x00",
+}
+
+func FuzzParseCellValue(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		s = strings.TrimSpace(s) // as readHorizontalItems does
+		for _, typ := range SupportedTypes {
+			field := Field{RawName: "x", RawType: typ}
+			v, err := parseFieldValue(field, s)
+			if err == nil {
+				if _, jerr := json.Marshal(v); jerr != nil {
+					t.Fatalf("%s: %q parses to %#v, which JSON can't encode: %v", typ, s, v, jerr)
+				}
+			}
+			cache := getCellCache(1)
+			cv, cerr := cache.parse(0, field, s)
+			putCellCache(cache)
+			if (err == nil) != (cerr == nil) || err == nil && !reflect.DeepEqual(v, cv) {
+				t.Fatalf("%s: %q: parser gives %#v, %v but the cell cache %#v, %v", typ, s, v, err, cv, cerr)
+			}
+		}
+	})
+}
+
+func FuzzParseBraceArrayJSON(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var list []int
+		if err := parseBraceArrayJSON(s, &list); err == nil {
+			if _, err := parseCellValue("int[]", s); err != nil && strings.TrimSpace(s) != "" {
+				t.Fatalf("%q decodes as %v, but the int[] cell is rejected: %v", s, list, err)
+			}
+		}
+		var nested [][]int
+		_ = parseBraceArrayJSON(s, &nested)
+	})
+}
+
+func FuzzDefineRow(f *testing.F) {
+	for _, s := range []string{
+		"id#int", "drops#int[],s", "name # string , c", "x#int,round", "x#float,exact", "a#comment", "cd#float,unit(s->ms)",
+		"r#ref:Item.cid", "x#int,", "#int", "x#", "x#int,unit(", "x#int[][][]", "\xff#int", "名前#string",
+	} {
+		f.Add(s)
+	}
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, row := range [][]string{{s}, {"id#int", s}} {
+			fields, err := ParseFieldsFromDefineRow([][]string{row}, 1, "")
+			if err != nil {
+				continue
+			}
+			for _, fd := range fields {
+				if fd.Ref == nil && !fieldRe.MatchString(fd.RawName+"#"+fd.RawType) {
+					t.Fatalf("%q: accepted field %q of type %q, which doesn't read back", s, fd.RawName, fd.RawType)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package genxls
+
+import (
+	"fmt"
+	"strings"
+)
+
+func mapGDType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return "int", true
+	case "int[]":
+		return "Array[int]", true
+	case "int[][]":
+		// GDScript has no nested typed arrays; elements are Array[int].
+		return "Array[Array]", true
+	case "float", "float32", "float64":
+		return "float", true
+	case "bool":
+		return "bool", true
+	case "string", "datetime":
+		return "String", true
+	default:
+		return "", false
+	}
+}
+
+// gdFieldAssign returns the statements copying the f.RawName entry of d
+// into o. Godot's JSON parser yields floats for every number, so ints
+// are converted explicitly.
+func gdFieldAssign(f Field) (string, error) {
+	get := "d.get(\"" + f.RawName + "\""
+	switch strings.ToLower(f.RawType) {
+	case "int", "int32", "int64":
+		return "\t\to." + f.RawName + " = int(" + get + ", 0))\n", nil
+	case "int[]":
+		return "\t\tfor v in " + get + ", []):\n" +
+			"\t\t\to." + f.RawName + ".append(int(v))\n", nil
+	case "int[][]":
+		return "\t\tfor row in " + get + ", []):\n" +
+			"\t\t\tvar inner: Array[int] = []\n" +
+			"\t\t\tfor v in row:\n" +
+			"\t\t\t\tinner.append(int(v))\n" +
+			"\t\t\to." + f.RawName + ".append(inner)\n", nil
+	case "float", "float32", "float64":
+		return "\t\to." + f.RawName + " = float(" + get + ", 0.0))\n", nil
+	case "bool":
+		return "\t\to." + f.RawName + " = bool(" + get + ", false))\n", nil
+	case "string", "datetime":
+		return "\t\to." + f.RawName + " = str(" + get + ", \"\"))\n", nil
+	default:
+		return "", fmt.Errorf("unsupported type %q", f.RawType)
+	}
+}
+
+func GenerateGDBundle(rootName string, sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	b.WriteString("class_name ")
+	b.WriteString(rootName)
+	b.WriteString("\nextends RefCounted\n\n")
+
+	for _, sheet := range sheets {
+		fields := sheet.Fields
+		b.WriteString("class ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(":\n")
+		for _, f := range fields {
+			gdType, ok := mapGDType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("unsupported type %q", f.RawType)
+			}
+			writeDocComment(&b, "\t## ", f.Doc)
+			b.WriteString("\tvar ")
+			b.WriteString(f.RawName)
+			b.WriteString(": ")
+			b.WriteString(gdType)
+			if strings.HasPrefix(gdType, "Array") {
+				b.WriteString(" = []")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n\tstatic func from_dict(d: Dictionary) -> ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(":\n\t\tvar o := ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(".new()\n")
+		for _, f := range fields {
+			stmt, err := gdFieldAssign(f)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(stmt)
+		}
+		b.WriteString("\t\treturn o\n\n")
+	}
+
+	for _, sheet := range sheets {
+		b.WriteString("var ")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString(": Array[")
+		b.WriteString(sheet.TypeName)
+		b.WriteString("] = []\n")
+	}
+
+	b.WriteString("\nstatic func from_dict(data: Dictionary) -> ")
+	b.WriteString(rootName)
+	b.WriteString(":\n\tvar cfg := ")
+	b.WriteString(rootName)
+	b.WriteString(".new()\n")
+	for _, sheet := range sheets {
+		if sheet.Sparse {
+			b.WriteString("\tfor d in _expand_sparse(data.get(\"")
+			b.WriteString(sheet.JSONKey)
+			b.WriteString("\", [])):\n\t\tcfg.")
+		} else {
+			b.WriteString("\tfor d in data.get(\"")
+			b.WriteString(sheet.JSONKey)
+			b.WriteString("\", []):\n\t\tcfg.")
+		}
+		b.WriteString(sheet.JSONKey)
+		b.WriteString(".append(")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(".from_dict(d))\n")
+	}
+	b.WriteString("\treturn cfg\n\n")
+
+	b.WriteString("static func load_json(path: String) -> ")
+	b.WriteString(rootName)
+	b.WriteString(":\n")
+	b.WriteString("\tvar data = JSON.parse_string(FileAccess.get_file_as_string(path))\n")
+	b.WriteString("\tif typeof(data) != TYPE_DICTIONARY:\n")
+	b.WriteString("\t\tpush_error(\"invalid config json: \" + path)\n")
+	b.WriteString("\t\treturn null\n")
+	if hasChunkedSheets(sheets) {
+		b.WriteString("\tif not _stitch_chunks(path, data):\n")
+		b.WriteString("\t\treturn null\n")
+	}
+	b.WriteString("\treturn from_dict(data)\n")
+	b.WriteString(gdSingletonFuncs(sheets))
+	if hasSparseSheets(sheets) {
+		b.WriteString(gdSparseLoader)
+	}
+	if hasChunkedSheets(sheets) {
+		b.WriteString(gdChunkLoader)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,1555 @@
+package genxls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+type Orientation int
+
+const (
+	OrientationHorizontal Orientation = iota
+	OrientationVertical
+)
+
+type FieldFlag int
+
+const (
+	FieldFlagAll FieldFlag = iota
+	FieldFlagServer
+	FieldFlagClient
+	FieldFlagNone
+)
+
+type Field struct {
+	RawName   string
+	Name      string
+	RawType   string
+	GoType    string
+	Col       int
+	Flag      FieldFlag
+	Exported  bool
+	IsComment bool
+	// JSONString serializes an integer column as a JSON string (",str" option
+	// or --int64-as-string).
+	JSONString bool
+	// SortKey marks a column rows are sorted by (",sort" option).
+	SortKey bool
+	// Key marks the column --loader go indexes a sheet by (",key" option);
+	// without one it is the first.
+	Key    bool
+	Coerce Coercion
+	// Ref is set for ref columns (#ref:Sheet.column), whose type is the
+	// target column's once LoadSources has resolved it.
+	Ref *FieldRef
+	// Unit is the unit values are exported in (",unit(ms)"), for docs.
+	Unit string
+	// Scrubbed keeps a server-only column in a client export with every value
+	// replaced by its zero value (--scrub-server).
+	Scrubbed bool
+	// Doc is the comment on the define-row cell, for generated doc comments
+	// and CONFIG.md.
+	Doc string
+	// ProtoField is the proto field number the schema lock pins; 0 numbers
+	// the column by its position.
+	ProtoField int
+}
+
+// Sheet is one parsed worksheet. TypeName is the generated type name (with
+// --type-prefix/--type-suffix applied); FieldName and JSONKey are derived from
+// the plain sheet name so prefixes never leak into the payload.
+type Sheet struct {
+	Origin    string // file[sheet] the rows were read from
+	Name      string // sheet name as written in the workbook
+	File      string // input file path
+	TypeName  string // e.g. Item or CfgItem
+	FieldName string // root struct field, e.g. Items
+	JSONKey   string // payload key, e.g. items
+	Fields    []Field
+	Items     []map[string]any
+	Rows      []int // 1-based source row of each item
+	Sparse    bool  // JSON payload is a base row plus per-row overrides (--sparse)
+	ChunkRows int   // rows per chunk file when split out of all.json (--chunk-rows); 0: not chunked
+	Bundles   []string
+	Cells     [][]string           // raw sheet grid, kept for --debug-data only
+	Drift     map[string]float64   // column -> max relative change between runs
+	Owner     string               // from the config file, for routing problems
+	GoMethods []*template.Template // config goMethods, rendered into go.gen.go
+	BadCells  []*CellError         // cells replaced by zero values (--lenient, --annotate)
+	IDRange   *IDBlock             // primary keys must fall in From..To (config "ids")
+	// ModifiedBy and Modified come from the workbook's core properties.
+	ModifiedBy string
+	Modified   string
+	// OldJSONKey also holds the rows in the JSON payload while consumers move
+	// off a renamed sheet's old key (config renamedFrom with bothKeys).
+	OldJSONKey string
+	// Vertical sheets (A1=2) are key-value settings exported as one row; see
+	// verticalGrid.
+	Vertical bool
+	// ProtoField is the sheet's field number in the proto root message, as
+	// the schema lock pins it; 0 numbers it by its position.
+	ProtoField int
+}
+
+// buildJSONPayload returns the aggregated payload keyed by sheet JSON key.
+func buildJSONPayload(sheets []*Sheet) map[string]any {
+	payload := make(map[string]any, len(sheets))
+	for _, sheet := range sheets {
+		if sheet.ChunkRows > 0 {
+			continue // written to chunk files, see writeChunkedSheets
+		}
+		if sheet.Sparse {
+			payload[sheet.JSONKey] = sparsePayload(sheet)
+		} else if sheet.Cells != nil {
+			payload[sheet.JSONKey] = withRawCells(sheet, jsonItems(sheet))
+		} else {
+			payload[sheet.JSONKey] = jsonItems(sheet)
+		}
+		if sheet.OldJSONKey != "" {
+			payload[sheet.OldJSONKey] = payload[sheet.JSONKey]
+		}
+	}
+	return payload
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func pluralizeTypeName(typeName string) string {
+	if typeName == "" {
+		return typeName
+	}
+	// Minimal pluralization for config names: Item->Items, Quest->Quests
+	if strings.HasSuffix(typeName, "s") || strings.HasSuffix(typeName, "x") || strings.HasSuffix(typeName, "z") || strings.HasSuffix(typeName, "ch") || strings.HasSuffix(typeName, "sh") {
+		return typeName + "es"
+	}
+	return typeName + "s"
+}
+
+// ResolveInputPaths lists the workbooks --in names. cfg, which may be nil,
+// holds the connector settings of remote folders.
+func ResolveInputPaths(in string, cfg *Config) ([]string, error) {
+	if _, err := os.Stat(in); err != nil {
+		in = cleanInputPath(in)
+	}
+	if in == "" {
+		return nil, errors.New("empty --in")
+	}
+	if manifest, ok := strings.CutPrefix(in, "@"); ok {
+		return readInputManifest(manifest, cfg)
+	}
+	if scheme, folder, ok := remoteInput(in); ok {
+		return syncRemoteFolder(scheme, folder, cfg)
+	}
+	in = longPath(in)
+	// If it's already an existing path, keep it.
+	if st, err := os.Stat(in); err == nil {
+		if st.IsDir() && !strings.EqualFold(filepath.Ext(in), ".numbers") {
+			return listExcelFiles(in)
+		}
+		return []string{in}, nil
+	}
+	if !isRelativeInput(in) {
+		return nil, fmt.Errorf("input file not found: %s", in)
+	}
+
+	// If user passed just a filename (or a relative path that doesn't exist), try ./xls/<name>.
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	candidate := filepath.Join(wd, "xls", filepath.Base(in))
+	if st, err := os.Stat(candidate); err == nil {
+		if st.IsDir() {
+			return listExcelFiles(candidate)
+		}
+		return []string{candidate}, nil
+	}
+
+	return nil, fmt.Errorf("input file not found: %s (also tried %s)", in, candidate)
+}
+
+func listExcelFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		// Numbers documents (files or package directories) are listed so they
+		// fail with a hint to convert them instead of being silently skipped.
+		if ext == ".numbers" {
+			out = append(out, filepath.Join(dir, name))
+			continue
+		}
+		if e.IsDir() {
+			continue
+		}
+		// .xlsm is read like .xlsx; its VBA project is never looked at.
+		if ext != ".xlsx" && ext != ".xlsm" && ext != ".xls" {
+			continue
+		}
+		out = append(out, filepath.Join(dir, name))
+	}
+	sort.Strings(out)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no .xls/.xlsx/.xlsm files in %s", dir)
+	}
+	return out, nil
+}
+
+func readRowsAuto(path string) ([][]string, error) {
+	f, err := excelize.OpenFile(path)
+	if err == nil {
+		defer func() { _ = f.Close() }()
+		list := f.GetSheetList()
+		if len(list) == 0 {
+			return nil, fmt.Errorf("%s: xlsx has no sheets", path)
+		}
+		rows, err := f.GetRows(list[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return rows, nil
+	}
+	rows, err2 := readTSVRows(path)
+	if err2 != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return rows, nil
+}
+
+func readTSVRows(path string) ([][]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rows := parseTSV(b)
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: empty file", path)
+	}
+	return rows, nil
+}
+
+// parseTSV splits tab-separated text into rows, skipping blank lines.
+func parseTSV(b []byte) [][]string {
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	lines := strings.Split(s, "\n")
+	var rows [][]string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	return rows
+}
+
+type HeaderSpec struct {
+	HeaderRows  int
+	Orientation Orientation
+	DefineRow   int // 1-based row number in sheet
+}
+
+type Options struct {
+	InPath        string
+	OutDir        string
+	OutTemplate   string
+	Sink          string
+	Flag          string
+	ScrubServer   bool
+	Anonymize     bool
+	AnonymizeKey  string
+	Lang          string
+	Pkg           string
+	JSON          bool
+	DataFormat    string
+	Parquet       bool
+	Avro          bool
+	Redis         bool
+	Mongo         bool
+	MongoURI      string
+	Provenance    bool
+	Docs          bool
+	Int64AsString bool
+	TypePrefix    string
+	TypeSuffix    string
+	PublishSchema string
+	VerifyAgainst string
+	FailOnReorder bool
+	SortRows      bool
+	Strict        bool
+	Lenient       bool
+	MaxErrors     int   // load errors to print, 0: all
+	StreamSize    int64 // workbooks this large are read row by row (--stream-threshold); 0: never
+	Annotate      string
+	Sparse        bool
+	ChunkRows     int
+	GoEmbed       bool
+	GoAccessor    bool
+	PbData        bool
+	PbGRPC        bool
+	Loader        string
+	GoPrometheus  bool
+	TSGuards      bool
+	CSStubs       string
+	Changelog     string
+	LastGreen     string
+	Config        string
+	Bundle        string
+	Only          string
+	MergeSheets   bool
+	NamespaceFile bool
+	Exclude       string
+	DebugData     bool
+	Stats         bool
+	Password      string
+	LockFile      string
+	IDsFile       string
+	UpdateLock    bool
+	AllowDrift    bool
+	OutlierFactor float64
+	FloatToInt    string
+	MaxRows       int
+	MaxStringLen  int
+	MaxPayload    string
+	LimitMode     string
+	AsOf          string
+	Verbose       bool
+	WaitLock      time.Duration // how long to wait for another run's lock on --out
+	Logger        *slog.Logger  // nil: warnings to stderr
+	Report        io.Writer     // --stats, --last-green and -v reports; nil: stderr
+	Sources       []SheetSource // Export reads these instead of InPath when set
+	Artifacts     ArtifactSink  // Export writes here instead of Sink when set
+}
+
+// DefaultOptions returns the options of a command line run without flags.
+func DefaultOptions() Options {
+	return Options{
+		OutDir:     ".",
+		Lang:       "all",
+		Pkg:        "config",
+		JSON:       true,
+		DataFormat: "json",
+		MaxErrors:  50,
+		FloatToInt: "none",
+		LimitMode:  "error",
+		LockFile:   DefaultLockFile,
+		IDsFile:    DefaultIDsFile,
+	}
+}
+
+// LoadSheets parses every sheet of the input files, in discovery order. cfg
+// may be nil.
+func LoadSheets(ctx context.Context, inPaths []string, opts Options, cfg *Config) ([]*Sheet, error) {
+	return LoadSources(ctx, FileSources(inPaths, opts, cfg), opts, cfg)
+}
+
+// LoadSources parses every sheet of the sources, in order, until ctx is done.
+// cfg may be nil. Sheets that can't be exported fail with a *SchemaError,
+// *CellError or *DuplicateKeyError, wrapped.
+func LoadSources(ctx context.Context, sources []SheetSource, opts Options, cfg *Config) ([]*Sheet, error) {
+	log := opts.logger()
+	sheets := make([]*Sheet, 0, 8)      // stable output order
+	seenKeys := make(map[string]*Sheet) // jsonKey -> sheet
+	// Type names also become file and class names, so they must differ even
+	// ignoring case: lower-cased type name -> origin.
+	seenTypes := make(map[string]string)
+	exclude, err := excludePatterns(opts.Exclude, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var skipped []*Sheet // left out by --only, kept as ref targets
+
+	// rows holds the sheet's rows, or only its first rows when the rest come
+	// from more.
+	addSheet := func(file, origin, sheetName string, rows [][]string, more rowIterator, notes map[string]string, props *excelize.DocProperties) error {
+		if opts.Only != "" && !sheetNameMatches(file, sheetName, opts.Only, opts) {
+			if sheet := refTargetStub(file, origin, sheetName, rows, opts, cfg); sheet != nil {
+				skipped = append(skipped, sheet)
+			}
+			return nil
+		}
+		owner := cfg.ownerOf(file, sheetName, opts)
+		var modifiedBy, modified string
+		if props != nil {
+			modifiedBy, modified = props.LastModifiedBy, props.Modified
+		}
+		fail := func(err error) error {
+			if a := annotation(owner, modifiedBy); a != "" {
+				err = fmt.Errorf("%w%s", err, a)
+			}
+			return err
+		}
+		schemaErr := func(row int, err error) error {
+			return fail(&SchemaError{Origin: origin, Sheet: sheetName, Row: row, Err: err})
+		}
+		spec, err := detectHeaderSpec(rows)
+		if err != nil {
+			return schemaErr(0, err)
+		}
+		vertical := spec.Orientation == OrientationVertical
+		grid := rows
+		if vertical {
+			grid, notes = verticalGrid(rows, spec.DefineRow, notes)
+		}
+		exportFlag := opts.fieldFlag()
+		if opts.ScrubServer {
+			exportFlag = ""
+		}
+		fields, err := ParseFieldsFromDefineRow(grid, spec.DefineRow, exportFlag)
+		if err == nil {
+			fields, err = excludeColumns(fields, exclude)
+		}
+		if err != nil {
+			return schemaErr(spec.DefineRow, err)
+		}
+		applyFieldDocs(fields, notes, spec.DefineRow)
+		for i := range fields {
+			if fields[i].Unit != "" {
+				fields[i].Doc = strings.TrimSpace(fields[i].Doc + "\n" + unitDoc(fields[i]))
+			}
+		}
+		if opts.ScrubServer {
+			for i := range fields {
+				fields[i].Scrubbed = fields[i].Flag == FieldFlagServer
+			}
+		}
+		if opts.FloatToInt != "" {
+			for i := range fields {
+				if isIntType(fields[i].RawType) && fields[i].Coerce.FloatToInt == "" {
+					fields[i].Coerce.FloatToInt = opts.FloatToInt
+				}
+			}
+		}
+		if zones := cfg.dateTimeZones(); zones != nil {
+			for i := range fields {
+				if strings.ToLower(fields[i].RawType) == "datetime" {
+					fields[i].Coerce.Zones = zones
+				}
+			}
+		}
+		if opts.Int64AsString {
+			for i := range fields {
+				if strings.ToLower(fields[i].RawType) == "int64" {
+					fields[i].setJSONString()
+				}
+			}
+		}
+		tail := sliceRows(grid[spec.DefineRow:])
+		var data rowIterator = &tail
+		if more != nil {
+			data = &chainRows{&tail, more}
+		}
+		mode := CellModeDefault
+		switch {
+		case opts.Strict:
+			mode = CellModeStrict
+			last, err := strictDefineRow(grid[spec.DefineRow-1], spec.DefineRow)
+			if err != nil {
+				return schemaErr(0, err)
+			}
+			data = &strictRows{rowIterator: data, row: spec.DefineRow + 1, last: last}
+		case opts.Lenient:
+			mode = CellModeLenient
+		}
+		// Bad cells are collected in every mode; without --lenient or
+		// --annotate the sheet then fails with all of them.
+		var badCells []*CellError
+		bad := func(e *CellError) {
+			e.Sheet = sheetName
+			if vertical {
+				e.Row, e.Col = verticalCell(spec.DefineRow, e.Col)
+			}
+			if opts.Lenient {
+				log.Warn(fmt.Sprintf("%s: %v, using zero value%s", origin, e, annotation(owner, modifiedBy)), "sheet", sheetName, "row", e.Row, "col", e.Col)
+			}
+			badCells = append(badCells, e)
+		}
+		items, rowNums, err := readItems(data, spec.DefineRow+1, fields, mode, true, bad)
+		if err == nil && len(badCells) > 0 && !opts.Lenient && opts.Annotate == "" {
+			errs := make([]error, len(badCells))
+			for i, e := range badCells {
+				errs[i] = fail(fmt.Errorf("%s: %w", origin, e))
+			}
+			return errors.Join(errs...)
+		}
+		if err != nil {
+			var cellErr *CellError
+			if !errors.As(err, &cellErr) {
+				return schemaErr(0, err)
+			}
+			cellErr.Sheet = sheetName
+			if vertical {
+				cellErr.Row, cellErr.Col = verticalCell(spec.DefineRow, cellErr.Col)
+			}
+			return fail(fmt.Errorf("%s: %w", origin, err))
+		}
+		if vertical {
+			for i := range rowNums {
+				rowNums[i] = spec.DefineRow
+			}
+		}
+
+		baseName := sheetBaseName(file, sheetName, opts)
+		if baseName == "" {
+			return schemaErr(0, errors.New("empty sheet name"))
+		}
+		typeName := opts.TypePrefix + baseName + opts.TypeSuffix
+		fieldName := pluralizeTypeName(baseName)
+		jsonKey := lowerFirst(fieldName)
+		customKey, oldKey := cfg.jsonKeysOf(file, sheetName, opts)
+		if customKey != "" {
+			jsonKey = customKey
+		}
+		sheet := &Sheet{
+			Origin:     origin,
+			Name:       sheetName,
+			TypeName:   typeName,
+			FieldName:  fieldName,
+			JSONKey:    jsonKey,
+			OldJSONKey: oldKey,
+			File:       file,
+			Fields:     fields,
+			Items:      items,
+			Rows:       rowNums,
+			Bundles:    sheetMarkerBundles(rows, spec),
+			Owner:      owner,
+			BadCells:   badCells,
+			ModifiedBy: modifiedBy,
+			Modified:   modified,
+			Vertical:   vertical,
+		}
+		if opts.DebugData {
+			sheet.Cells = rows
+		}
+		if prev, ok := seenKeys[jsonKey]; ok {
+			if !opts.MergeSheets {
+				return fail(duplicateSheetError(prev, sheet))
+			}
+			if err := mergeSheet(prev, sheet); err != nil {
+				return fail(err)
+			}
+			log.Warn(fmt.Sprintf("merged the rows of %s into sheet %q (--merge-sheets)", origin, jsonKey), "sheet", jsonKey)
+			SortSheetRows(prev, opts.SortRows)
+			return nil
+		}
+		if prev, ok := seenTypes[strings.ToLower(typeName)]; ok {
+			return schemaErr(0, fmt.Errorf("type name collision: %s and this sheet both become type %s (type names must differ ignoring case); rename one of the sheets",
+				prev, typeName))
+		}
+		if prev, ok := seenKeys[oldKey]; ok && oldKey != "" {
+			return schemaErr(0, fmt.Errorf("old sheet key %q (renamedFrom) is used by %s", oldKey, prev.Origin))
+		}
+		for _, prev := range sheets {
+			if prev.OldJSONKey != "" && prev.OldJSONKey == jsonKey {
+				return schemaErr(0, fmt.Errorf("sheet key %q is the old key of %s (renamedFrom)", jsonKey, prev.Origin))
+			}
+		}
+		seenKeys[jsonKey] = sheet
+		seenTypes[strings.ToLower(typeName)] = origin
+		SortSheetRows(sheet, opts.SortRows)
+		sheets = append(sheets, sheet)
+		return nil
+	}
+
+	failed := &LoadErrors{Max: opts.MaxErrors}
+	for _, src := range sources {
+		sheetNames, err := src.Sheets()
+		if err != nil {
+			failed.add(src.Name(), err)
+		}
+		var props *excelize.DocProperties
+		if ps, ok := src.(propsSource); ok {
+			props = ps.DocProps()
+		}
+		for _, sheet := range sheetNames {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			origin := sourceOrigin(src, sheet)
+			rows, more, err := sheetHead(src, sheet, opts)
+			if err != nil {
+				failed.add(origin, fmt.Errorf("%s: %w", origin, err))
+				continue
+			}
+			var notes map[string]string
+			if ns, ok := src.(noteSource); ok {
+				notes, err = ns.Notes(sheet)
+			}
+			if err != nil {
+				err = fmt.Errorf("%s: %w", origin, err)
+			} else {
+				err = addSheet(src.Name(), origin, sheet, rows, more, notes, props)
+			}
+			if more != nil {
+				if cerr := more.Close(); err == nil && cerr != nil {
+					err = fmt.Errorf("%s: %w", origin, cerr)
+				}
+			}
+			if err != nil {
+				failed.add(origin, err)
+			}
+		}
+		if c, ok := src.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+	// Refs into failed sheets would only add noise.
+	if len(failed.Errs) > 0 {
+		return nil, failed
+	}
+	if err := resolveRefs(sheets, skipped, opts, log); err != nil {
+		return nil, err
+	}
+	return sheets, nil
+}
+
+// sheetHead returns the rows of a sheet or, when src streams it, only the
+// header rows (detectHeaderSpec looks at the first three) and an iterator
+// over the rest. Vertical sheets and --debug-data need every row and are read
+// whole anyway.
+func sheetHead(src SheetSource, sheet string, opts Options) ([][]string, rowIterator, error) {
+	rs, ok := src.(rowStreamer)
+	if !ok {
+		rows, err := src.Rows(sheet)
+		return rows, nil, err
+	}
+	it, ok, err := rs.streamRows(sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		rows, err := src.Rows(sheet)
+		return rows, nil, err
+	}
+	var head [][]string
+	for len(head) < 3 {
+		row, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = it.Close()
+			return nil, nil, err
+		}
+		head = append(head, row)
+	}
+	if spec, err := detectHeaderSpec(head); opts.DebugData || err == nil && spec.Orientation == OrientationVertical {
+		rows, err := readRows(head, it)
+		if cerr := it.Close(); err == nil {
+			err = cerr
+		}
+		return trimGhostCells(rows), nil, err
+	}
+	return head, it, nil
+}
+
+// knownLangs lists the --lang targets in output order. Names are matched
+// case-insensitively.
+var knownLangs = []string{"go", "Pb", "ts", "gd", "ue", "php", "erl", "lua", "dart", "capnp", "proto"}
+
+// allLangs are the targets "all" selects. The later ones (gd, ue, php, erl,
+// lua, dart, capnp, proto) are opt-in, so a default run doesn't start writing
+// files nobody asked for.
+var allLangs = []string{"go", "Pb", "ts"}
+
+// parseLangs reads --lang: comma-separated targets, where "all" (and "", the
+// default) stands for go, Pb and ts only. Opt-in targets must be named, e.g.
+// "all,gd" or "go,proto".
+func parseLangs(s string) (map[string]bool, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		s = "all"
+	}
+	out := make(map[string]bool, len(knownLangs))
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if p == "all" {
+			for _, l := range allLangs {
+				out[l] = true
+			}
+			continue
+		}
+		matched := false
+		for _, l := range knownLangs {
+			if strings.ToLower(l) == p {
+				out[l] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("invalid --lang %q (expect %s|all or comma-separated)", s, strings.Join(knownLangs, "|"))
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("invalid --lang %q (no targets)", s)
+	}
+	return out, nil
+}
+
+func detectHeaderSpec(rows [][]string) (HeaderSpec, error) {
+	if len(rows) >= 3 && rowHasFieldDefs(rows[2]) {
+		ori := OrientationHorizontal
+		a1 := ""
+		if len(rows[0]) > 0 {
+			a1 = strings.TrimSpace(rows[0][0])
+		}
+		if a1 == "2" {
+			ori = OrientationVertical
+		}
+		return HeaderSpec{HeaderRows: 3, Orientation: ori, DefineRow: 3}, nil
+	}
+	if len(rows) >= 2 && rowHasFieldDefs(rows[1]) {
+		return HeaderSpec{HeaderRows: 2, Orientation: OrientationHorizontal, DefineRow: 2}, nil
+	}
+	if len(rows) >= 1 && rowHasFieldDefs(rows[0]) {
+		return HeaderSpec{HeaderRows: 1, Orientation: OrientationHorizontal, DefineRow: 1}, nil
+	}
+	return HeaderSpec{}, errors.New("cannot detect header")
+}
+
+func rowHasFieldDefs(row []string) bool {
+	for _, c := range row {
+		if strings.Contains(c, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+var fieldRe = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*#\s*([^,\s]+)\s*((?:,\s*[A-Za-z]+(?:\([^(),]*\))?\s*)*)$`)
+
+func ParseFieldsFromDefineRow(rows [][]string, defineRow int, exportFlag string) ([]Field, error) {
+	if defineRow <= 0 || defineRow > len(rows) {
+		return nil, fmt.Errorf("define row %d out of range", defineRow)
+	}
+	row := rows[defineRow-1]
+	var fields []Field
+	for colIdx, cell := range row {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+
+		lower := strings.ToLower(cell)
+		if strings.Contains(lower, "#comment") || strings.Contains(lower, "#common") {
+			continue
+		}
+
+		m := fieldRe.FindStringSubmatch(cell)
+		if m == nil {
+			return nil, fmt.Errorf("invalid field def %q at row %d", cell, defineRow)
+		}
+		rawName := m[1]
+		rawType := m[2]
+		if strings.ToLower(rawType) == "comment" || strings.ToLower(rawType) == "common" {
+			continue
+		}
+		ref, isRef, err := parseFieldRef(rawType, defineRow)
+		if err != nil {
+			return nil, fmt.Errorf("%v in field def %q at row %d", err, cell, defineRow)
+		}
+		if isRef {
+			rawType = "string" // until resolveRefs knows the target's type
+		}
+
+		ff := FieldFlagAll
+		jsonString := false
+		sortKey := false
+		key := false
+		unit := ""
+		var coerce Coercion
+		for _, opt := range strings.Split(m[3], ",")[1:] {
+			opt = strings.TrimSpace(opt)
+			lopt := strings.ToLower(opt)
+			if arg, ok := strings.CutPrefix(opt, "unit("); ok {
+				if !isIntType(rawType) && !isFloatType(rawType) && strings.ToLower(rawType) != "int[]" {
+					return nil, fmt.Errorf("option \"unit\" in field def %q at row %d requires an int, float or int[] type", cell, defineRow)
+				}
+				var err error
+				if unit, coerce.Unit, err = parseUnitOption(strings.TrimSuffix(arg, ")")); err != nil {
+					return nil, fmt.Errorf("option %q in field def %q at row %d: %w", opt, cell, defineRow, err)
+				}
+				continue
+			}
+			switch lopt {
+			case "s":
+				ff = FieldFlagServer
+			case "c":
+				ff = FieldFlagClient
+			case "str":
+				jsonString = true
+			case "sort":
+				sortKey = true
+			case "key":
+				if strings.HasSuffix(rawType, "[]") {
+					return nil, fmt.Errorf("option \"key\" in field def %q at row %d requires a scalar type", cell, defineRow)
+				}
+				key = true
+			case "exact", "round", "floor", "ceil", "trunc":
+				if !isIntType(rawType) {
+					return nil, fmt.Errorf("option %q in field def %q at row %d requires an int type", lopt, cell, defineRow)
+				}
+				if coerce.FloatToInt != "" && coerce.FloatToInt != lopt {
+					return nil, fmt.Errorf("options %q and %q in field def %q at row %d conflict", coerce.FloatToInt, lopt, cell, defineRow)
+				}
+				coerce.FloatToInt = lopt
+			case "thousands":
+				if !isIntType(rawType) && !isFloatType(rawType) {
+					return nil, fmt.Errorf("option \"thousands\" in field def %q at row %d requires an int or float type", cell, defineRow)
+				}
+				coerce.Thousands = true
+			case "yesno":
+				if strings.ToLower(rawType) != "bool" {
+					return nil, fmt.Errorf("option \"yesno\" in field def %q at row %d requires the bool type", cell, defineRow)
+				}
+				coerce.YesNo = true
+			default:
+				return nil, fmt.Errorf("unknown option %q in field def %q at row %d%s; supported options: %s",
+					opt, cell, defineRow, didYouMean(opt, fieldOptions), strings.Join(fieldOptions, ", "))
+			}
+		}
+
+		if exportFlag != "" {
+			switch exportFlag {
+			case "server":
+				if ff == FieldFlagClient {
+					continue
+				}
+			case "client":
+				if ff == FieldFlagServer {
+					continue
+				}
+			default:
+				return nil, fmt.Errorf("invalid --flag %q (expect server|client)", exportFlag)
+			}
+		}
+
+		goType, ok := mapGoType(rawType)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %q in field def %q at row %d%s; supported types: %s",
+				rawType, cell, defineRow, didYouMean(rawType, SupportedTypes), strings.Join(SupportedTypes, ", "))
+		}
+		if isWindowColumn(rawName) && strings.ToLower(rawType) != "datetime" {
+			return nil, fmt.Errorf("reserved column %q in field def %q at row %d must be datetime", rawName, cell, defineRow)
+		}
+		field := Field{
+			RawName:  rawName,
+			Name:     exportName(rawName),
+			RawType:  rawType,
+			GoType:   goType,
+			Col:      colIdx,
+			Flag:     ff,
+			Exported: true,
+			SortKey:  sortKey,
+			Key:      key,
+			Coerce:   coerce,
+			Ref:      ref,
+			Unit:     unit,
+		}
+		if key {
+			for _, prev := range fields {
+				if prev.Key {
+					return nil, fmt.Errorf("option \"key\" in field def %q at row %d: %s is already the key", cell, defineRow, prev.RawName)
+				}
+			}
+		}
+		if jsonString {
+			if !isIntType(rawType) {
+				return nil, fmt.Errorf("option \"str\" in field def %q at row %d requires an int type", cell, defineRow)
+			}
+			field.setJSONString()
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("no exported fields found")
+	}
+	return fields, nil
+}
+
+func isIntType(t string) bool {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return true
+	}
+	return false
+}
+
+func isFloatType(t string) bool {
+	switch strings.ToLower(t) {
+	case "float", "float32", "float64":
+		return true
+	}
+	return false
+}
+
+// setJSONString makes an integer column travel as a JSON string, so 64-bit
+// values survive JavaScript's float64 numbers.
+func (f *Field) setJSONString() {
+	f.JSONString = true
+	f.GoType = "int64"
+}
+
+// jsonItems returns sheet rows as they should appear in JSON, converting
+// JSONString columns to strings. Rows are copied only when needed.
+func jsonItems(sheet *Sheet) []map[string]any {
+	var conv []Field
+	for _, f := range sheet.Fields {
+		if f.JSONString {
+			conv = append(conv, f)
+		}
+	}
+	if len(conv) == 0 {
+		return sheet.Items
+	}
+	out := make([]map[string]any, len(sheet.Items))
+	for i, item := range sheet.Items {
+		cp := make(map[string]any, len(item))
+		for k, v := range item {
+			cp[k] = v
+		}
+		for _, f := range conv {
+			if n, ok := item[f.RawName].(int); ok {
+				cp[f.RawName] = strconv.Itoa(n)
+			}
+		}
+		out[i] = cp
+	}
+	return out
+}
+
+func exportName(name string) string {
+	if name == "" {
+		return name
+	}
+	// If it's already camelCase, keep inner casing and just capitalize first letter.
+	if !strings.ContainsAny(name, "_-") {
+		return strings.ToUpper(name[:1]) + name[1:]
+	}
+	// cid => Cid, data_id => DataId
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+	}
+	return strings.Join(parts, "")
+}
+
+func mapGoType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return "int", true
+	case "int[]":
+		return "[]int", true
+	case "int[][]":
+		return "[][]int", true
+	case "float", "float32", "float64":
+		return "float64", true
+	case "bool":
+		return "bool", true
+	case "string", "datetime":
+		return "string", true
+	default:
+		return "", false
+	}
+}
+
+func mapCSType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return "int", true
+	case "int[]":
+		return "List<int>", true
+	case "int[][]":
+		return "List<List<int>>", true
+	case "float", "float32", "float64":
+		return "double", true
+	case "bool":
+		return "bool", true
+	case "string", "datetime":
+		return "string", true
+	default:
+		return "", false
+	}
+}
+
+func mapTSType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64", "float", "float32", "float64":
+		return "number", true
+	case "int[]":
+		return "number[]", true
+	case "int[][]":
+		return "number[][]", true
+	case "bool":
+		return "boolean", true
+	case "string", "datetime":
+		return "string", true
+	default:
+		return "", false
+	}
+}
+
+func generateGo(pkg, rootName, itemName string, fields []Field) (string, error) {
+	var b strings.Builder
+	b.WriteString("package ")
+	b.WriteString(pkg)
+	b.WriteString("\n\n")
+
+	b.WriteString("type ")
+	b.WriteString(rootName)
+	b.WriteString(" struct {\n")
+	b.WriteString("\tItems []")
+	b.WriteString(itemName)
+	b.WriteString("\n")
+	b.WriteString("}\n\n\n")
+
+	b.WriteString("type ")
+	b.WriteString(itemName)
+	b.WriteString(" struct {\n")
+	for _, f := range fields {
+		b.WriteString("\t")
+		b.WriteString(f.Name)
+		b.WriteString(" ")
+		b.WriteString(f.GoType)
+		b.WriteString(" `json:\"")
+		b.WriteString(f.RawName)
+		b.WriteString("\"`")
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+
+	return b.String(), nil
+}
+
+func generateCS(rootName, itemName string, fields []Field) (string, error) {
+	var b strings.Builder
+	b.WriteString("using System.Collections.Generic;\n")
+	b.WriteString("using System.Text.Json.Serialization;\n\n")
+
+	b.WriteString("public class ")
+	b.WriteString(rootName)
+	b.WriteString("\n{\n")
+	b.WriteString("    public List<")
+	b.WriteString(itemName)
+	b.WriteString("> Items { get; set; }\n")
+	b.WriteString("}\n\n")
+
+	b.WriteString("public class ")
+	b.WriteString(itemName)
+	b.WriteString("\n{\n")
+	for _, f := range fields {
+		csType, ok := mapCSType(f.RawType)
+		if !ok {
+			return "", fmt.Errorf("unsupported type %q", f.RawType)
+		}
+		b.WriteString("    [JsonPropertyName(\"")
+		b.WriteString(f.RawName)
+		b.WriteString("\")]\n")
+		b.WriteString("    public ")
+		b.WriteString(csType)
+		b.WriteString(" ")
+		b.WriteString(f.Name)
+		b.WriteString(" { get; set; }\n\n")
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// GenerateGoBundle renders go.gen.go. With accessor it also has Load, Init and
+// Get (--go-accessor), with loader Load<Root> and indexed lookups (--loader go).
+func GenerateGoBundle(pkg, rootName string, sheets []*Sheet, accessor, loader bool) (string, error) {
+	var b strings.Builder
+	b.WriteString("package ")
+	b.WriteString(pkg)
+	b.WriteString("\n\n")
+	var imports []string
+	switch {
+	case accessor:
+		imports = goAccessorImports
+	case hasSparseSheets(sheets):
+		imports = []string{"encoding/json"}
+	}
+	if hasChunkedSheets(sheets) {
+		imports = mergeImports(imports, goChunkImports)
+	}
+	if loader {
+		imports = mergeImports(imports, goLoaderImports)
+	}
+	writeGoImports(&b, imports)
+
+	// Root config
+	b.WriteString("type ")
+	b.WriteString(rootName)
+	b.WriteString(" struct {\n")
+	for _, sheet := range sheets {
+		b.WriteString("\t")
+		b.WriteString(sheet.FieldName)
+		b.WriteString(" []")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(" `json:\"")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString("\"`\n")
+	}
+	if loader {
+		b.WriteString("\n")
+		b.WriteString(goLoaderIndexFields(sheets))
+	}
+	b.WriteString("}\n\n")
+
+	// Types
+	for _, sheet := range sheets {
+		b.WriteString("type ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(" struct {\n")
+		for _, f := range sheet.Fields {
+			writeDocComment(&b, "\t// ", f.Doc)
+			b.WriteString("\t")
+			b.WriteString(f.Name)
+			b.WriteString(" ")
+			b.WriteString(f.GoType)
+			b.WriteString(" `json:\"")
+			b.WriteString(f.RawName)
+			if f.JSONString {
+				b.WriteString(",string")
+			}
+			b.WriteString("\"`\n")
+		}
+		b.WriteString("}\n\n")
+		methods, err := renderGoMethods(rootName, sheet)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(methods)
+	}
+	b.WriteString(goSingletonAccessors(rootName, sheets))
+
+	if hasSparseSheets(sheets) {
+		b.WriteString(goSparseLoader(rootName, sheets))
+		b.WriteString("\n")
+	}
+	if hasChunkedSheets(sheets) {
+		b.WriteString(goChunkLoader())
+		b.WriteString("\n")
+	}
+	if loader {
+		b.WriteString(goLoader(rootName, sheets))
+		b.WriteString("\n")
+	}
+	if accessor {
+		b.WriteString(goAccessor(rootName, sheets, loader))
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+func GenerateCSBundle(rootName string, sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	b.WriteString("using System.Collections.Generic;\n")
+	if hasChunkedSheets(sheets) {
+		b.WriteString("using System.IO;\n")
+	}
+	if hasSparseSheets(sheets) || hasChunkedSheets(sheets) {
+		b.WriteString("using System.Text.Json;\n")
+		b.WriteString("using System.Text.Json.Nodes;\n")
+	}
+	b.WriteString("using System.Text.Json.Serialization;\n\n")
+
+	b.WriteString("public partial class ")
+	b.WriteString(rootName)
+	b.WriteString("\n{\n")
+	for _, sheet := range sheets {
+		b.WriteString("    [JsonPropertyName(\"")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString("\")]\n")
+		b.WriteString("    public List<")
+		b.WriteString(sheet.TypeName)
+		b.WriteString("> ")
+		b.WriteString(sheet.FieldName)
+		b.WriteString(" { get; set; }\n\n")
+	}
+	b.WriteString(csSingletonAccessors(sheets))
+	if hasSparseSheets(sheets) {
+		b.WriteString(csSparseLoader(rootName, sheets))
+	}
+	if hasChunkedSheets(sheets) {
+		b.WriteString(csChunkLoader(rootName))
+	}
+	b.WriteString("}\n\n")
+
+	for _, sheet := range sheets {
+		b.WriteString("public partial class ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString("\n{\n")
+		for _, f := range sheet.Fields {
+			csType, ok := mapCSType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("unsupported type %q", f.RawType)
+			}
+			writeCSDocComment(&b, "    ", f.Doc)
+			b.WriteString("    [JsonPropertyName(\"")
+			b.WriteString(f.RawName)
+			b.WriteString("\")]\n")
+			if f.JSONString {
+				csType = "long"
+				b.WriteString("    [JsonNumberHandling(JsonNumberHandling.AllowReadingFromString | JsonNumberHandling.WriteAsString)]\n")
+			}
+			b.WriteString("    public ")
+			b.WriteString(csType)
+			b.WriteString(" ")
+			b.WriteString(f.Name)
+			b.WriteString(" { get; set; }\n\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+func GenerateTSBundle(rootName string, sheets []*Sheet, guards bool) (string, error) {
+	var b strings.Builder
+	for _, sheet := range sheets {
+		b.WriteString("export interface ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(" {\n")
+		for _, f := range sheet.Fields {
+			tsType, ok := mapTSType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("unsupported type %q", f.RawType)
+			}
+			if f.JSONString {
+				tsType = "string"
+			}
+			writeJSDocComment(&b, "  ", f.Doc)
+			b.WriteString("  ")
+			b.WriteString(f.RawName)
+			b.WriteString(": ")
+			b.WriteString(tsType)
+			b.WriteString(";\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("export interface ")
+	b.WriteString(rootName)
+	b.WriteString(" {\n")
+	for _, sheet := range sheets {
+		b.WriteString("  ")
+		b.WriteString(sheet.JSONKey)
+		b.WriteString(": ")
+		b.WriteString(sheet.TypeName)
+		b.WriteString("[];\n")
+	}
+	b.WriteString("}\n")
+	b.WriteString(tsSingletonAccessors(rootName, sheets))
+
+	if hasSparseSheets(sheets) {
+		b.WriteString(tsSparseLoader(rootName, sheets))
+	}
+	if hasChunkedSheets(sheets) {
+		b.WriteString(tsChunkLoader(rootName))
+	}
+	if guards {
+		b.WriteString(tsTypeGuards(rootName, sheets))
+	}
+
+	return b.String(), nil
+}
+
+func generateTS(rootName, itemName string, fields []Field) (string, error) {
+	var b strings.Builder
+	b.WriteString("export interface ")
+	b.WriteString(itemName)
+	b.WriteString(" {\n")
+	for _, f := range fields {
+		tsType, ok := mapTSType(f.RawType)
+		if !ok {
+			return "", fmt.Errorf("unsupported type %q", f.RawType)
+		}
+		b.WriteString("  ")
+		b.WriteString(f.RawName)
+		b.WriteString(": ")
+		b.WriteString(tsType)
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("export interface ")
+	b.WriteString(rootName)
+	b.WriteString(" {\n")
+	b.WriteString("  Items: ")
+	b.WriteString(itemName)
+	b.WriteString("[];\n")
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// readHorizontalItems parses the data rows and also returns the 1-based sheet
+// row number of each item.
+// When bad is non-nil (lenient mode, --annotate), cells that fail to parse or,
+// in CellModeStrict, need coercion are passed to it and become the zero value
+// instead of failing. cached parses the cells through a cellCache, which
+// exports always do; `genxls bench` turns it off to compare.
+func readHorizontalItems(rows [][]string, dataStartRow int, fields []Field, mode CellMode, cached bool, bad func(*CellError)) ([]map[string]any, []int, error) {
+	if dataStartRow <= 0 {
+		dataStartRow = 1
+	}
+	data := sliceRows(nil)
+	if dataStartRow <= len(rows) {
+		data = rows[dataStartRow-1:]
+	}
+	return readItems(&data, dataStartRow, fields, mode, cached, bad)
+}
+
+// readItems is readHorizontalItems for the rows of it, the first of which is
+// sheet row first. Rows are parsed as they come, so a streamed sheet is never
+// held in memory; only from the first template marker on (see
+// expandTemplateRows) is the rest of the sheet read before parsing.
+func readItems(it rowIterator, first int, fields []Field, mode CellMode, cached bool, bad func(*CellError)) ([]map[string]any, []int, error) {
+	var items []map[string]any
+	var rowNums []int
+	var cache *cellCache
+	if cached {
+		cache = getCellCache(len(fields))
+		defer putCellCache(cache)
+	}
+	parse := func(src sourceRow) error {
+		rowNum := src.row
+		variants, err := expandDirectiveRow(src.cells, fields[0].Col, rowNum)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", rowNum, err)
+		}
+		for _, row := range variants {
+			obj := make(map[string]any, len(fields))
+			for i, field := range fields {
+				cell := ""
+				var cellErr *CellError
+				if field.Col >= 0 && field.Col < len(row) && !field.Scrubbed {
+					cell = row[field.Col]
+					if mode == CellModeStrict {
+						if note := coercionNote(field, cell); note != "" {
+							cellErr = &CellError{Row: rowNum, Col: field.Col + 1, Field: field.RawName, Err: errors.New(note + " (--strict)")}
+						}
+					}
+					cell = strings.TrimSpace(cell)
+				}
+				v, err := cache.parse(i, field, cell)
+				if err != nil && cellErr == nil {
+					cellErr = &CellError{Row: rowNum, Col: field.Col + 1, Field: field.RawName, Err: err}
+				}
+				if cellErr != nil {
+					if bad == nil {
+						return cellErr
+					}
+					bad(cellErr)
+					v, _ = parseCellValue(field.RawType, "")
+				}
+				obj[field.RawName] = v
+			}
+			items = append(items, obj)
+			rowNums = append(rowNums, rowNum)
+		}
+		return nil
+	}
+	for r := first; ; r++ {
+		row, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if isTemplateMarker(rowKey(row, fields[0].Col)) {
+			rest, err := readRows([][]string{row}, it)
+			if err != nil {
+				return nil, nil, err
+			}
+			sources, err := expandTemplateRows(rest, r-1, fields[0].Col)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, src := range sources {
+				if err := parse(src); err != nil {
+					return nil, nil, err
+				}
+			}
+			break
+		}
+		if isEmptyRow(row) {
+			continue
+		}
+		if err := parse(sourceRow{cells: row, row: r}); err != nil {
+			return nil, nil, err
+		}
+	}
+	if items == nil {
+		items, rowNums = []map[string]any{}, []int{}
+	}
+	return items, rowNums, nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCellValue(rawType string, s string) (any, error) {
+	if s == "" {
+		switch strings.ToLower(rawType) {
+		case "int", "int32", "int64":
+			return 0, nil
+		case "int[]":
+			return []int{}, nil
+		case "int[][]":
+			return [][]int{}, nil
+		case "float", "float32", "float64":
+			return float64(0), nil
+		case "bool":
+			return false, nil
+		case "string", "datetime":
+			return "", nil
+		default:
+			return nil, fmt.Errorf("unsupported type %q", rawType)
+		}
+	}
+
+	switch strings.ToLower(rawType) {
+	case "int", "int32", "int64":
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkIntRange(rawType, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "int[]":
+		var v []int
+		if err := parseBraceArrayJSON(s, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "int[][]":
+		var v [][]int
+		if err := parseBraceArrayJSON(s, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "float", "float32", "float64":
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%s is not a finite number", s)
+		}
+		return v, nil
+	case "bool":
+		ls := strings.ToLower(s)
+		if ls == "1" {
+			return true, nil
+		}
+		if ls == "0" {
+			return false, nil
+		}
+		v, err := strconv.ParseBool(ls)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case "string":
+		if !utf8.ValidString(s) {
+			return nil, errors.New("invalid UTF-8 (save the file as UTF-8)")
+		}
+		return s, nil
+	case "datetime":
+		t, err := parseDateTime(s)
+		if err != nil {
+			return nil, err
+		}
+		return formatDateTime(t), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q", rawType)
+	}
+}
+
+// checkIntRange rejects int32 values that don't fit 32 bits; ints are 64-bit.
+func checkIntRange(rawType string, v int) error {
+	if strings.EqualFold(rawType, "int32") && (v < math.MinInt32 || v > math.MaxInt32) {
+		return fmt.Errorf("%d is out of int32 range", v)
+	}
+	return nil
+}
+
+// maxBraceDepth is the nesting of int[][]; deeper cells are rejected before
+// decoding rather than walked.
+const maxBraceDepth = 2
+
+func parseBraceArrayJSON(s string, out any) error {
+	// Cells pasted from the web carry non-breaking and other Unicode spaces,
+	// which JSON doesn't take as whitespace (the cell cache does).
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	s = strings.Trim(s, "\"")
+	if s == "" || s == "{}" {
+		s = "[]"
+	}
+	// Convert Lua-like braces to JSON arrays.
+	s = strings.ReplaceAll(s, "{", "[")
+	s = strings.ReplaceAll(s, "}", "]")
+	if !strings.HasPrefix(strings.TrimSpace(s), "[") {
+		s = "[" + s + "]"
+	}
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			if depth++; depth > maxBraceDepth {
+				return fmt.Errorf("arrays nested more than %d deep", maxBraceDepth)
+			}
+		case ']':
+			depth--
+		}
+	}
+	return json.Unmarshal([]byte(s), out)
+}
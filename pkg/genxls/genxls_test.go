@@ -0,0 +1,43 @@
+package genxls
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseLangs(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{"go", "Pb", "ts"}},
+		{"all", []string{"go", "Pb", "ts"}},
+		{"ALL", []string{"go", "Pb", "ts"}},
+		{"all,gd", []string{"go", "Pb", "ts", "gd"}},
+		{"go, proto", []string{"go", "proto"}},
+		{"ue,php,erl,lua,dart,capnp", []string{"ue", "php", "erl", "lua", "dart", "capnp"}},
+	} {
+		langs, err := parseLangs(tt.in)
+		if err != nil {
+			t.Errorf("parseLangs(%q): %v", tt.in, err)
+			continue
+		}
+		var got []string
+		for _, l := range knownLangs {
+			if langs[l] {
+				got = append(got, l)
+			}
+		}
+		want := slices.Clone(tt.want)
+		slices.SortFunc(want, func(a, b string) int { return slices.Index(knownLangs, a) - slices.Index(knownLangs, b) })
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseLangs(%q) = %v, want %v", tt.in, got, want)
+		}
+	}
+	for _, in := range []string{"java", ",", "go,java"} {
+		if _, err := parseLangs(in); err == nil {
+			t.Errorf("parseLangs(%q): no error", in)
+		}
+	}
+}
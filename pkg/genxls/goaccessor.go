@@ -0,0 +1,136 @@
+package genxls
+
+import (
+	"strings"
+)
+
+// goAccessorImports are the imports goAccessor needs.
+var goAccessorImports = []string{"context", "crypto/sha256", "encoding/hex", "encoding/json", "fmt", "io", "os", "sync/atomic", "time"}
+
+// goAccessor renders Load, Init and Get for go.gen.go: one package-level config
+// behind an atomic pointer, so services load (and reload) it the same way
+// instead of each keeping its own global. Loading takes a context and stops
+// between reads and between sheets once it is canceled, for servers that load
+// during health-checked startup windows. Every Init is reported to the
+// Metrics set with SetMetrics. With indexes (--loader go) loaded configs are
+// indexed before Get returns them.
+func goAccessor(rootName string, sheets []*Sheet, indexes bool) string {
+	keys := make([]string, len(sheets))
+	for i, sheet := range sheets {
+		keys[i] = sheet.JSONKey
+	}
+	var b strings.Builder
+	b.WriteString("var (\n\tcurrent   atomic.Pointer[" + rootName + "]\n\tlastStats atomic.Pointer[LoadStats]\n\tmetrics   atomic.Pointer[Metrics]\n)\n\n")
+
+	b.WriteString("// LoadStats times one Load.\n")
+	b.WriteString("type LoadStats struct {\n")
+	b.WriteString("\tBytes  int           // payload size\n")
+	b.WriteString("\tRead   time.Duration // reading the file\n")
+	b.WriteString("\tDecode time.Duration // decoding the payload\n")
+	b.WriteString("\t// Version identifies the payload: the first 12 hex digits of its SHA-256.\n")
+	b.WriteString("\tVersion string\n")
+	b.WriteString("}\n\n")
+
+	b.WriteString("// Metrics receives every Init and InitContext, e.g. to export them to a\n")
+	b.WriteString("// monitoring system. See SetMetrics.\n")
+	b.WriteString("type Metrics interface {\n")
+	b.WriteString("\t// Loaded is called after each load attempt; err is nil when the config\n")
+	b.WriteString("\t// was replaced.\n")
+	b.WriteString("\tLoaded(stats LoadStats, err error)\n")
+	b.WriteString("}\n\n")
+	b.WriteString("// SetMetrics sets the Metrics loads are reported to; nil stops reporting.\n")
+	b.WriteString("func SetMetrics(m Metrics) {\n")
+	b.WriteString("\tif m == nil {\n\t\tmetrics.Store(nil)\n\t\treturn\n\t}\n")
+	b.WriteString("\tmetrics.Store(&m)\n}\n\n")
+
+	b.WriteString("// Load reads and decodes path (the JSON payload). It returns ctx.Err() as soon\n")
+	b.WriteString("// as ctx is canceled, checking between file reads and between sheets.\n")
+	b.WriteString("func Load(ctx context.Context, path string) (*" + rootName + ", LoadStats, error) {\n")
+	b.WriteString("\tvar stats LoadStats\n")
+	b.WriteString("\tstart := time.Now()\n")
+	b.WriteString("\tdata, err := readConfigFile(ctx, path)\n")
+	if hasChunkedSheets(sheets) {
+		b.WriteString("\tif err == nil {\n")
+		b.WriteString("\t\tdata, err = stitchChunks(path, data, func(path string) ([]byte, error) {\n")
+		b.WriteString("\t\t\treturn readConfigFile(ctx, path)\n")
+		b.WriteString("\t\t})\n")
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("\tstats.Bytes, stats.Read = len(data), time.Since(start)\n")
+	b.WriteString("\tif err != nil {\n\t\treturn nil, stats, err\n\t}\n")
+	b.WriteString("\tsum := sha256.Sum256(data)\n")
+	b.WriteString("\tstats.Version = hex.EncodeToString(sum[:6])\n")
+	b.WriteString("\tstart = time.Now()\n")
+	b.WriteString("\tc, err := decodeConfig(ctx, data)\n")
+	b.WriteString("\tstats.Decode = time.Since(start)\n")
+	b.WriteString("\tif err != nil {\n\t\treturn nil, stats, fmt.Errorf(\"%s: %w\", path, err)\n\t}\n")
+	if indexes {
+		b.WriteString("\tif err := c.BuildIndexes(); err != nil {\n\t\treturn nil, stats, fmt.Errorf(\"%s: %w\", path, err)\n\t}\n")
+	}
+	b.WriteString("\treturn c, stats, nil\n}\n\n")
+
+	b.WriteString("func readConfigFile(ctx context.Context, path string) ([]byte, error) {\n")
+	b.WriteString("\tf, err := os.Open(path)\n")
+	b.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	b.WriteString("\tdefer f.Close()\n")
+	b.WriteString("\tvar data []byte\n")
+	b.WriteString("\tchunk := make([]byte, 1<<20)\n")
+	b.WriteString("\tfor {\n")
+	b.WriteString("\t\tif err := ctx.Err(); err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	b.WriteString("\t\tn, err := f.Read(chunk)\n")
+	b.WriteString("\t\tdata = append(data, chunk[:n]...)\n")
+	b.WriteString("\t\tif err == io.EOF {\n\t\t\treturn data, nil\n\t\t}\n")
+	b.WriteString("\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	b.WriteString("\t}\n}\n\n")
+
+	b.WriteString("// decodeConfig decodes the payload one sheet at a time.\n")
+	b.WriteString("func decodeConfig(ctx context.Context, data []byte) (*" + rootName + ", error) {\n")
+	b.WriteString("\tvar raw map[string]json.RawMessage\n")
+	b.WriteString("\tif err := json.Unmarshal(data, &raw); err != nil {\n\t\treturn nil, err\n\t}\n")
+	b.WriteString("\tc := new(" + rootName + ")\n")
+	b.WriteString("\tfor _, key := range []string{" + quotedKeys(keys) + "} {\n")
+	b.WriteString("\t\tif err := ctx.Err(); err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	b.WriteString("\t\tv, ok := raw[key]\n")
+	b.WriteString("\t\tif !ok {\n\t\t\tcontinue\n\t\t}\n")
+	b.WriteString("\t\t// A one-sheet object, so sheets exported with --sparse are expanded too.\n")
+	b.WriteString("\t\tone := append(append([]byte(`{\"`+key+`\":`), v...), '}')\n")
+	b.WriteString("\t\tif err := json.Unmarshal(one, c); err != nil {\n\t\t\treturn nil, fmt.Errorf(\"%s: %w\", key, err)\n\t\t}\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn c, nil\n}\n\n")
+
+	b.WriteString("// Get returns the config loaded by the last successful Init, or nil before\n")
+	b.WriteString("// the first one. The result is shared and must not be modified.\n")
+	b.WriteString("func Get() *" + rootName + " {\n\treturn current.Load()\n}\n\n")
+	b.WriteString("// Init is InitContext without a deadline.\n")
+	b.WriteString("func Init(path string) error {\n\treturn InitContext(context.Background(), path)\n}\n\n")
+	b.WriteString("// InitContext loads path and makes it the config returned by Get. It is safe\n")
+	b.WriteString("// to call again to reload: callers holding the previous config keep it\n")
+	b.WriteString("// unchanged, and a failed or canceled reload keeps the current one.\n")
+	b.WriteString("func InitContext(ctx context.Context, path string) error {\n")
+	b.WriteString("\tc, stats, err := Load(ctx, path)\n")
+	b.WriteString("\tlastStats.Store(&stats)\n")
+	b.WriteString("\tif m := metrics.Load(); m != nil {\n\t\t(*m).Loaded(stats, err)\n\t}\n")
+	b.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+	b.WriteString("\tcurrent.Store(c)\n")
+	b.WriteString("\treturn nil\n}\n\n")
+	b.WriteString("// LastLoadStats times the last Init or InitContext, successful or not.\n")
+	b.WriteString("func LastLoadStats() LoadStats {\n")
+	b.WriteString("\tif s := lastStats.Load(); s != nil {\n\t\treturn *s\n\t}\n")
+	b.WriteString("\treturn LoadStats{}\n}\n")
+	return b.String()
+}
+
+// writeGoImports writes the import declaration of a generated Go file.
+func writeGoImports(b *strings.Builder, paths []string) {
+	switch len(paths) {
+	case 0:
+	case 1:
+		b.WriteString("import \"" + paths[0] + "\"\n\n")
+	default:
+		b.WriteString("import (\n")
+		for _, p := range paths {
+			b.WriteString("\t\"" + p + "\"\n")
+		}
+		b.WriteString(")\n\n")
+	}
+}
@@ -0,0 +1,46 @@
+package genxls
+
+import (
+	"strings"
+)
+
+// generateGoEmbed renders data.gen.go, which embeds dataFile (all.json) from
+// the same directory and decodes it into a package-level variable at init, so
+// servers can ship as a single binary. With accessor (--go-accessor) it is
+// also what Get returns until Init loads another file, and with loader
+// (--loader go) it is indexed.
+func generateGoEmbed(pkg, rootName, dataFile string, accessor, loader bool) string {
+	var b strings.Builder
+	b.WriteString("package ")
+	b.WriteString(pkg)
+	b.WriteString("\n\n")
+	b.WriteString("import (\n\t_ \"embed\"\n\t\"encoding/json\"\n)\n\n")
+	b.WriteString("//go:embed ")
+	b.WriteString(dataFile)
+	b.WriteString("\n")
+	b.WriteString("var allJSON []byte\n\n")
+	b.WriteString("// Data is the config decoded from the embedded ")
+	b.WriteString(dataFile)
+	b.WriteString(".\n")
+	b.WriteString("var Data ")
+	b.WriteString(rootName)
+	b.WriteString("\n\n")
+	b.WriteString("func init() {\n")
+	b.WriteString("\tif err := json.Unmarshal(allJSON, &Data); err != nil {\n")
+	b.WriteString("\t\tpanic(\"")
+	b.WriteString(pkg)
+	b.WriteString(": decode embedded ")
+	b.WriteString(dataFile)
+	b.WriteString(": \" + err.Error())\n")
+	b.WriteString("\t}\n")
+	if loader {
+		b.WriteString("\tif err := Data.BuildIndexes(); err != nil {\n")
+		b.WriteString("\t\tpanic(\"" + pkg + ": index embedded " + dataFile + ": \" + err.Error())\n")
+		b.WriteString("\t}\n")
+	}
+	if accessor {
+		b.WriteString("\tcurrent.Store(&Data)\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package genxls
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// goLoaderImports are the imports goLoader needs.
+var goLoaderImports = []string{"encoding/json", "fmt", "os"}
+
+// goLoaderKey returns the column a sheet is indexed by for --loader go: the
+// one marked ,key, else the first. Sheets keyed by an array have no index.
+func goLoaderKey(sheet *Sheet) *Field {
+	if len(sheet.Fields) == 0 {
+		return nil
+	}
+	key := &sheet.Fields[0]
+	for i := range sheet.Fields {
+		if sheet.Fields[i].Key {
+			key = &sheet.Fields[i]
+			break
+		}
+	}
+	if strings.HasPrefix(key.GoType, "[]") {
+		return nil
+	}
+	return key
+}
+
+// goIndexName is the unexported root field holding a sheet's index, e.g.
+// itemByCid.
+func goIndexName(sheet *Sheet, key *Field) string {
+	return lowerFirst(sheet.TypeName) + "By" + key.Name
+}
+
+// goLoaderIndexFields renders the root struct's index fields.
+func goLoaderIndexFields(sheets []*Sheet) string {
+	var b strings.Builder
+	for _, sheet := range sheets {
+		if key := goLoaderKey(sheet); key != nil {
+			fmt.Fprintf(&b, "\t%s map[%s]*%s\n", goIndexName(sheet, key), key.GoType, sheet.TypeName)
+		}
+	}
+	return b.String()
+}
+
+// goLoader renders Load<Root>, BuildIndexes and a Get<Type>By<Key> lookup per
+// sheet for go.gen.go (--loader go), so services stop writing the same
+// id-to-row maps by hand.
+func goLoader(rootName string, sheets []*Sheet) string {
+	var b strings.Builder
+	b.WriteString("// Load" + rootName + " reads path (the JSON payload) and indexes it for the\n")
+	b.WriteString("// Get...By lookups.\n")
+	b.WriteString("func Load" + rootName + "(path string) (*" + rootName + ", error) {\n")
+	b.WriteString("\tdata, err := os.ReadFile(path)\n")
+	b.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	if hasChunkedSheets(sheets) {
+		b.WriteString("\tif data, err = StitchChunks(path, data); err != nil {\n\t\treturn nil, err\n\t}\n")
+	}
+	b.WriteString("\tc := new(" + rootName + ")\n")
+	b.WriteString("\tif err := json.Unmarshal(data, c); err != nil {\n\t\treturn nil, fmt.Errorf(\"%s: %w\", path, err)\n\t}\n")
+	b.WriteString("\tif err := c.BuildIndexes(); err != nil {\n\t\treturn nil, fmt.Errorf(\"%s: %w\", path, err)\n\t}\n")
+	b.WriteString("\treturn c, nil\n}\n\n")
+
+	b.WriteString("// BuildIndexes (re)builds the maps behind the Get...By lookups. Load" + rootName + "\n")
+	b.WriteString("// calls it; call it after decoding or changing the rows yourself.\n")
+	b.WriteString("func (c *" + rootName + ") BuildIndexes() error {\n")
+	for _, sheet := range sheets {
+		key := goLoaderKey(sheet)
+		if key == nil {
+			continue
+		}
+		index := "c." + goIndexName(sheet, key)
+		rows := "c." + sheet.FieldName
+		fmt.Fprintf(&b, "\t%s = make(map[%s]*%s, len(%s))\n", index, key.GoType, sheet.TypeName, rows)
+		fmt.Fprintf(&b, "\tfor i := range %s {\n", rows)
+		fmt.Fprintf(&b, "\t\trow := &%s[i]\n", rows)
+		fmt.Fprintf(&b, "\t\tif _, dup := %s[row.%s]; dup {\n", index, key.Name)
+		fmt.Fprintf(&b, "\t\t\treturn fmt.Errorf(\"%s: duplicate %s %%v\", row.%s)\n\t\t}\n", sheet.JSONKey, key.RawName, key.Name)
+		fmt.Fprintf(&b, "\t\t%s[row.%s] = row\n\t}\n", index, key.Name)
+	}
+	b.WriteString("\treturn nil\n}\n")
+
+	for _, sheet := range sheets {
+		key := goLoaderKey(sheet)
+		if key == nil {
+			continue
+		}
+		param := lowerFirst(key.Name)
+		if param == "c" || token.IsKeyword(param) || !token.IsIdentifier(param) {
+			param = "key"
+		}
+		fmt.Fprintf(&b, "\n// Get%sBy%s returns the %s whose %s is %s, or nil.\n", sheet.TypeName, key.Name, sheet.TypeName, key.RawName, param)
+		fmt.Fprintf(&b, "func (c *%s) Get%sBy%s(%s %s) *%s {\n", rootName, sheet.TypeName, key.Name, param, key.GoType, sheet.TypeName)
+		fmt.Fprintf(&b, "\treturn c.%s[%s]\n}\n", goIndexName(sheet, key), param)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package genxls
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// goMethodData is what goMethods templates are rendered with.
+type goMethodData struct {
+	Type   string // sheet type, e.g. Item
+	Root   string // root type, e.g. AllConfig
+	Key    string // JSON key, e.g. items
+	Fields []goMethodField
+}
+
+type goMethodField struct {
+	Name   string // Go field name
+	Column string // define-row name
+	Type   string // Go type
+}
+
+// parseGoMethods parses a sheet's goMethods templates. {{field "col"}} expands
+// to the Go name of a column, so templates fail loudly when a column is renamed.
+func parseGoMethods(sheet *Sheet, srcs []string) ([]*template.Template, error) {
+	funcs := template.FuncMap{
+		"field": func(col string) (string, error) {
+			f := findField(sheet.Fields, col)
+			if f == nil {
+				var known []string
+				for _, f := range sheet.Fields {
+					known = append(known, f.RawName)
+				}
+				return "", fmt.Errorf("unknown column %q%s", col, didYouMean(col, known))
+			}
+			return f.Name, nil
+		},
+	}
+	var out []*template.Template
+	for i, src := range srcs {
+		t, err := template.New(fmt.Sprintf("goMethods[%d]", i)).Funcs(funcs).Option("missingkey=error").Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// renderGoMethods renders the sheet's goMethods, gofmt'd, for go.gen.go.
+func renderGoMethods(rootName string, sheet *Sheet) (string, error) {
+	data := goMethodData{Type: sheet.TypeName, Root: rootName, Key: sheet.JSONKey}
+	for _, f := range sheet.Fields {
+		data.Fields = append(data.Fields, goMethodField{Name: f.Name, Column: f.RawName, Type: f.GoType})
+	}
+	var b strings.Builder
+	for _, t := range sheet.GoMethods {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("%s: %w", sheet.Origin, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return "", fmt.Errorf("%s: %s renders invalid Go: %w\n%s", sheet.Origin, t.Name(), err, buf.String())
+		}
+		b.Write(bytes.TrimSpace(src))
+		b.WriteString("\n\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,74 @@
+package genxls
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+)
+
+// writeGoPackages generates every goPackages entry of the config: go.gen.go
+// with a root holding only the listed sheets, their JSON payload and, with
+// embed, data.gen.go; accessor adds Get and Init, loader the indexed
+// lookups (--loader go) and prometheus its prometheus.gen.go. The files go
+// through out's sink. It returns the written paths.
+func writeGoPackages(cfg *Config, out *OutputLayout, sheets []*Sheet, rootName, dataName string, embed, accessor, loader, prometheus bool) ([]string, error) {
+	var written []string
+	for i, gp := range cfg.GoPackages {
+		if !token.IsIdentifier(gp.Pkg) {
+			return nil, fmt.Errorf("config: goPackages[%d]: invalid pkg %q", i, gp.Pkg)
+		}
+		if gp.Out == "" {
+			return nil, fmt.Errorf("config: goPackages[%d] (%s): empty out", i, gp.Pkg)
+		}
+		var pkgSheets []*Sheet
+		for _, name := range gp.Sheets {
+			sheet := FindSheet(sheets, name)
+			if sheet == nil {
+				var known []string
+				for _, s := range sheets {
+					known = append(known, s.Name)
+				}
+				return nil, fmt.Errorf("config: goPackages[%d] (%s): no sheet %q%s", i, gp.Pkg, name, didYouMean(name, known))
+			}
+			pkgSheets = append(pkgSheets, sheet)
+		}
+		if len(pkgSheets) == 0 {
+			return nil, fmt.Errorf("config: goPackages[%d] (%s): no sheets", i, gp.Pkg)
+		}
+
+		outDir := gp.Out
+		if !filepath.IsAbs(outDir) {
+			outDir = filepath.Join(cfg.dir, outDir)
+		}
+		pkgOut := &OutputLayout{OutDir: outDir, DataName: dataName, RootName: rootName, Sink: out.Sink, Context: out.Context}
+		goCode, err := GenerateGoBundle(gp.Pkg, rootName, pkgSheets, accessor, loader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", gp.Pkg, err)
+		}
+		outFile := filepath.Join(outDir, "go.gen.go")
+		if err := pkgOut.write(outFile, []byte(goCode)); err != nil {
+			return nil, err
+		}
+		written = append(written, outFile)
+		files, err := WriteDataPayload(pkgOut, "json", pkgSheets)
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, files...)
+		if embed {
+			outFile := filepath.Join(outDir, "data.gen.go")
+			if err := pkgOut.write(outFile, []byte(generateGoEmbed(gp.Pkg, rootName, dataName+".json", accessor, loader))); err != nil {
+				return nil, err
+			}
+			written = append(written, outFile)
+		}
+		if prometheus {
+			outFile := filepath.Join(outDir, "prometheus.gen.go")
+			if err := pkgOut.write(outFile, []byte(generateGoPrometheus(gp.Pkg))); err != nil {
+				return nil, err
+			}
+			written = append(written, outFile)
+		}
+	}
+	return written, nil
+}
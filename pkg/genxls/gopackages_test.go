@@ -0,0 +1,38 @@
+package genxls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoPackagesUseSink(t *testing.T) {
+	dir := t.TempDir()
+	src := NewMemorySource("db").Add("Item", [][]string{{"id#int", "name#string"}, {"1", "Sword"}})
+	cfg := &Config{GoPackages: []GoPackageConfig{{Pkg: "items", Out: "items", Sheets: []string{"Item"}}}, dir: dir}
+	opts := DefaultOptions()
+	opts.OutDir, opts.Lang = filepath.Join(dir, "out"), "go"
+	opts.Sources = []SheetSource{src}
+
+	sink := NewMemorySink()
+	opts.Artifacts = sink
+	if err := Export(context.Background(), opts, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"go.gen.go", "all.json"} {
+		path := filepath.Join(dir, "items", name)
+		if _, ok := sink.File(path); !ok {
+			t.Errorf("%s not written to the sink; got %v", path, sink.Paths())
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("%s written to disk, not the sink", path)
+		}
+	}
+
+	opts.Artifacts, opts.Sink = nil, filepath.Join(dir, "out.zip")
+	if err := Export(context.Background(), opts, cfg); err == nil || !strings.Contains(err.Error(), "goPackages") {
+		t.Errorf("--sink with goPackages: got %v, want an error", err)
+	}
+}
@@ -0,0 +1,55 @@
+package genxls
+
+import (
+	"strings"
+)
+
+// generateGoPrometheus renders prometheus.gen.go, a Metrics implementation
+// for the --go-accessor loader. It is a separate file so packages that don't
+// use Prometheus never import it.
+func generateGoPrometheus(pkg string) string {
+	var b strings.Builder
+	b.WriteString("package " + pkg + "\n\n")
+	b.WriteString("import \"github.com/prometheus/client_golang/prometheus\"\n\n")
+	b.WriteString("// PrometheusMetrics exports config loads:\n")
+	b.WriteString("//\n")
+	b.WriteString("//\t<namespace>_config_version_info{version}   1 for the loaded payload version\n")
+	b.WriteString("//\t<namespace>_config_loads_total{result}      loads by result, ok or error\n")
+	b.WriteString("//\t<namespace>_config_load_duration_seconds    read and decode time\n")
+	b.WriteString("type PrometheusMetrics struct {\n")
+	b.WriteString("\tversion  *prometheus.GaugeVec\n")
+	b.WriteString("\tloads    *prometheus.CounterVec\n")
+	b.WriteString("\tduration prometheus.Histogram\n")
+	b.WriteString("}\n\n")
+	b.WriteString("// NewPrometheusMetrics registers the config metrics with reg. Pass the result\n")
+	b.WriteString("// to SetMetrics.\n")
+	b.WriteString("func NewPrometheusMetrics(reg prometheus.Registerer, namespace string) (*PrometheusMetrics, error) {\n")
+	b.WriteString("\tm := &PrometheusMetrics{\n")
+	b.WriteString("\t\tversion: prometheus.NewGaugeVec(prometheus.GaugeOpts{\n")
+	b.WriteString("\t\t\tNamespace: namespace, Name: \"config_version_info\", Help: \"Version of the loaded config payload.\",\n")
+	b.WriteString("\t\t}, []string{\"version\"}),\n")
+	b.WriteString("\t\tloads: prometheus.NewCounterVec(prometheus.CounterOpts{\n")
+	b.WriteString("\t\t\tNamespace: namespace, Name: \"config_loads_total\", Help: \"Config loads and reloads by result.\",\n")
+	b.WriteString("\t\t}, []string{\"result\"}),\n")
+	b.WriteString("\t\tduration: prometheus.NewHistogram(prometheus.HistogramOpts{\n")
+	b.WriteString("\t\t\tNamespace: namespace, Name: \"config_load_duration_seconds\", Help: \"Time to read and decode the config.\",\n")
+	b.WriteString("\t\t\tBuckets: prometheus.DefBuckets,\n")
+	b.WriteString("\t\t}),\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\tfor _, c := range []prometheus.Collector{m.version, m.loads, m.duration} {\n")
+	b.WriteString("\t\tif err := reg.Register(c); err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn m, nil\n}\n\n")
+	b.WriteString("// Loaded implements Metrics.\n")
+	b.WriteString("func (m *PrometheusMetrics) Loaded(stats LoadStats, err error) {\n")
+	b.WriteString("\tm.duration.Observe((stats.Read + stats.Decode).Seconds())\n")
+	b.WriteString("\tif err != nil {\n")
+	b.WriteString("\t\tm.loads.WithLabelValues(\"error\").Inc()\n")
+	b.WriteString("\t\treturn\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\tm.loads.WithLabelValues(\"ok\").Inc()\n")
+	b.WriteString("\tm.version.Reset()\n")
+	b.WriteString("\tm.version.WithLabelValues(stats.Version).Set(1)\n")
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package genxls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// protoServiceMessages are the messages protoService declares; a sheet can't
+// take their names.
+var protoServiceMessages = []string{"GetSheetRequest", "GetAllRequest", "WatchChangesRequest"}
+
+// protoService renders the --pb-grpc additions to GenerateProtoSchema: the
+// go_package option and a <root>Service answering with the root message,
+// holding only the requested sheet for GetSheet.
+func protoService(pkg, rootName string, sheets []*Sheet) (string, error) {
+	for _, sheet := range sheets {
+		for _, name := range protoServiceMessages {
+			if sheet.TypeName == name || sheet.TypeName == rootName+"Service" {
+				return "", fmt.Errorf("proto: %s: message name %q is taken by the gRPC service", sheet.Origin, sheet.TypeName)
+			}
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\noption go_package = \"./;%s\";\n\n", pkg)
+	b.WriteString("// GetSheetRequest names a sheet by its " + rootName + " field, e.g. \"" + sheetKeyExample(sheets) + "\".\n")
+	b.WriteString("message GetSheetRequest {\n  string sheet = 1;\n}\n\n")
+	b.WriteString("message GetAllRequest {}\n\n")
+	b.WriteString("message WatchChangesRequest {}\n\n")
+	b.WriteString("service " + rootName + "Service {\n")
+	b.WriteString("  // GetSheet returns " + rootName + " with only the requested sheet set.\n")
+	b.WriteString("  rpc GetSheet(GetSheetRequest) returns (" + rootName + ");\n")
+	b.WriteString("  rpc GetAll(GetAllRequest) returns (" + rootName + ");\n")
+	b.WriteString("  // WatchChanges sends the current rows, then the new rows on every update.\n")
+	b.WriteString("  rpc WatchChanges(WatchChangesRequest) returns (stream " + rootName + ");\n")
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+func sheetKeyExample(sheets []*Sheet) string {
+	if len(sheets) == 0 {
+		return "items"
+	}
+	return sheets[0].JSONKey
+}
+
+// protoGoName is the Go name protoc-gen-go gives a proto field: camel case,
+// with an underscore before a lower-case letter dropped.
+func protoGoName(name string) string {
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isASCIILower(name[i+1]):
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isASCIILower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool { return 'a' <= c && c <= 'z' }
+
+// generateGoGRPCServer renders grpc.gen.go, a Server implementing the
+// protoService service. It belongs in the package protoc-gen-go and
+// protoc-gen-go-grpc generate from <pkg>.proto.
+func generateGoGRPCServer(pkg, rootName string, sheets []*Sheet) string {
+	svc := rootName + "Service"
+	var b strings.Builder
+	b.WriteString("package " + pkg + "\n\n")
+	b.WriteString("import (\n")
+	b.WriteString("\t\"context\"\n\t\"fmt\"\n\t\"os\"\n\t\"sync\"\n\n")
+	b.WriteString("\t\"google.golang.org/grpc/codes\"\n")
+	b.WriteString("\t\"google.golang.org/grpc/status\"\n")
+	b.WriteString("\t\"google.golang.org/protobuf/proto\"\n")
+	b.WriteString(")\n\n")
+	b.WriteString("// Server serves " + rootName + " over gRPC; register it with\n")
+	b.WriteString("// Register" + svc + "Server. Update swaps the rows and pushes them to\n")
+	b.WriteString("// every WatchChanges stream.\n")
+	b.WriteString("type Server struct {\n")
+	b.WriteString("\tUnimplemented" + svc + "Server\n\n")
+	b.WriteString("\tmu       sync.RWMutex\n")
+	b.WriteString("\tdata     *" + rootName + "\n")
+	b.WriteString("\twatchers map[chan *" + rootName + "]struct{}\n")
+	b.WriteString("}\n\n")
+	b.WriteString("// NewServer returns a Server serving data.\n")
+	b.WriteString("func NewServer(data *" + rootName + ") *Server {\n")
+	b.WriteString("\tif data == nil {\n\t\tdata = new(" + rootName + ")\n\t}\n")
+	b.WriteString("\treturn &Server{data: data, watchers: make(map[chan *" + rootName + "]struct{})}\n")
+	b.WriteString("}\n\n")
+	b.WriteString("// LoadFile reads a payload written by --pb-data.\n")
+	b.WriteString("func LoadFile(path string) (*" + rootName + ", error) {\n")
+	b.WriteString("\traw, err := os.ReadFile(path)\n")
+	b.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	b.WriteString("\tdata := new(" + rootName + ")\n")
+	b.WriteString("\tif err := proto.Unmarshal(raw, data); err != nil {\n")
+	b.WriteString("\t\treturn nil, fmt.Errorf(\"%s: %w\", path, err)\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn data, nil\n")
+	b.WriteString("}\n\n")
+	b.WriteString("// Update replaces the served rows and sends them to every watcher. A watcher\n")
+	b.WriteString("// still sending an earlier update only gets the latest one.\n")
+	b.WriteString("func (s *Server) Update(data *" + rootName + ") {\n")
+	b.WriteString("\tif data == nil {\n\t\tdata = new(" + rootName + ")\n\t}\n")
+	b.WriteString("\ts.mu.Lock()\n")
+	b.WriteString("\tdefer s.mu.Unlock()\n")
+	b.WriteString("\ts.data = data\n")
+	b.WriteString("\tfor ch := range s.watchers {\n")
+	b.WriteString("\t\tselect {\n\t\tcase <-ch:\n\t\tdefault:\n\t\t}\n")
+	b.WriteString("\t\tch <- data\n")
+	b.WriteString("\t}\n")
+	b.WriteString("}\n\n")
+	b.WriteString("func (s *Server) current() *" + rootName + " {\n")
+	b.WriteString("\ts.mu.RLock()\n")
+	b.WriteString("\tdefer s.mu.RUnlock()\n")
+	b.WriteString("\treturn s.data\n")
+	b.WriteString("}\n\n")
+	b.WriteString("func (s *Server) GetAll(context.Context, *GetAllRequest) (*" + rootName + ", error) {\n")
+	b.WriteString("\treturn s.current(), nil\n")
+	b.WriteString("}\n\n")
+	b.WriteString("func (s *Server) GetSheet(_ context.Context, req *GetSheetRequest) (*" + rootName + ", error) {\n")
+	b.WriteString("\tdata := s.current()\n")
+	b.WriteString("\tswitch req.GetSheet() {\n")
+	for _, sheet := range sheets {
+		name := protoGoName(sheet.JSONKey)
+		fmt.Fprintf(&b, "\tcase %q:\n", sheet.JSONKey)
+		fmt.Fprintf(&b, "\t\treturn &%s{%s: data.Get%s()}, nil\n", rootName, name, name)
+	}
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn nil, status.Errorf(codes.NotFound, \"no sheet %q\", req.GetSheet())\n")
+	b.WriteString("}\n\n")
+	b.WriteString("func (s *Server) WatchChanges(_ *WatchChangesRequest, stream " + svc + "_WatchChangesServer) error {\n")
+	b.WriteString("\tch := make(chan *" + rootName + ", 1)\n")
+	b.WriteString("\ts.mu.Lock()\n")
+	b.WriteString("\tch <- s.data\n")
+	b.WriteString("\ts.watchers[ch] = struct{}{}\n")
+	b.WriteString("\ts.mu.Unlock()\n")
+	b.WriteString("\tdefer func() {\n")
+	b.WriteString("\t\ts.mu.Lock()\n")
+	b.WriteString("\t\tdelete(s.watchers, ch)\n")
+	b.WriteString("\t\ts.mu.Unlock()\n")
+	b.WriteString("\t}()\n")
+	b.WriteString("\tfor {\n")
+	b.WriteString("\t\tselect {\n")
+	b.WriteString("\t\tcase <-stream.Context().Done():\n")
+	b.WriteString("\t\t\treturn nil\n")
+	b.WriteString("\t\tcase data := <-ch:\n")
+	b.WriteString("\t\t\tif err := stream.Send(data); err != nil {\n")
+	b.WriteString("\t\t\t\treturn err\n")
+	b.WriteString("\t\t\t}\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,20 @@
+package genxls
+
+import "testing"
+
+// TestProtoGoName checks the names against what protoc-gen-go generates.
+func TestProtoGoName(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"items", "Items"},
+		{"itemList", "ItemList"},
+		{"item_list", "ItemList"},
+		{"item_2", "Item_2"},
+		{"_hidden", "XHidden"},
+		{"dtArr", "DtArr"},
+		{"v2items", "V2Items"},
+	} {
+		if got := protoGoName(tt.in); got != tt.want {
+			t.Errorf("protoGoName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
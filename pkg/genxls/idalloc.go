@@ -0,0 +1,155 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultIDsFile = "genxls-ids.json"
+
+// IDRanges records the primary-key blocks handed out per sheet (by JSON key),
+// so two designers never pick the same ids. It is committed next to the
+// workbooks; after a merge, overlapping blocks show who collided.
+type IDRanges map[string][]IDBlock
+
+// IDBlock is one allocation of primary keys From..To (inclusive).
+type IDBlock struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Owner string `json:"owner,omitempty"`
+	Date  string `json:"date,omitempty"` // YYYY-MM-DD
+}
+
+func (b IDBlock) String() string {
+	s := fmt.Sprintf("%d-%d", b.From, b.To)
+	if b.Owner != "" {
+		s += " (" + b.Owner + ")"
+	}
+	return s
+}
+
+// parseIDRange parses an inclusive range like "10000-19999".
+func parseIDRange(s string) (IDBlock, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	a, errA := strconv.Atoi(strings.TrimSpace(from))
+	b, errB := strconv.Atoi(strings.TrimSpace(to))
+	if !ok || errA != nil || errB != nil || a > b {
+		return IDBlock{}, fmt.Errorf("invalid ids range %q (expect e.g. 10000-19999)", s)
+	}
+	return IDBlock{From: a, To: b}, nil
+}
+
+// checkIDBounds reports primary keys outside their sheet's configured range,
+// typically rows pasted into the wrong sheet.
+func checkIDBounds(sheets []*Sheet) []Problem {
+	var problems []Problem
+	for _, sheet := range sheets {
+		r := sheet.IDRange
+		if r == nil {
+			continue
+		}
+		pk := sheet.Fields[0]
+		for i, item := range sheet.Items {
+			if v, ok := item[pk.RawName].(int); ok && (v < r.From || v > r.To) {
+				p := problemf(sheet, "%s: data row %d: %s %d is outside the sheet's ids range %d-%d", sheet.Origin, i+1, pk.RawName, v, r.From, r.To)
+				p.Row, p.Col = sheet.cellOf(i, pk)
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems
+}
+
+// LoadIDRanges returns nil when path doesn't exist, i.e. ids are not tracked.
+func LoadIDRanges(path string) (IDRanges, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ranges IDRanges
+	if err := json.Unmarshal(data, &ranges); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if ranges == nil {
+		ranges = IDRanges{}
+	}
+	return ranges, nil
+}
+
+func SaveIDRanges(path string, ranges IDRanges) error {
+	data, err := json.MarshalIndent(ranges, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// CheckIDRanges reports overlapping blocks and repeated primary keys in every
+// sheet with allocated blocks.
+func CheckIDRanges(ranges IDRanges, sheets []*Sheet) []Problem {
+	var problems []Problem
+	for _, sheet := range sheets {
+		blocks, ok := ranges[sheet.JSONKey]
+		if !ok {
+			continue
+		}
+		sorted := append([]IDBlock(nil), blocks...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].From < sorted[j].From })
+		for i := 1; i < len(sorted); i++ {
+			for _, prev := range sorted[:i] {
+				if prev.To >= sorted[i].From {
+					problems = append(problems, problemf(sheet, "%s: id blocks %s and %s overlap", sheet.Origin, prev, sorted[i]))
+				}
+			}
+		}
+
+		pk := sheet.Fields[0]
+		seen := make(map[string]int, len(sheet.Items))
+		for i, item := range sheet.Items {
+			key := fmt.Sprint(item[pk.RawName])
+			if first, ok := seen[key]; ok {
+				p := problemf(sheet, "%s: data row %d: %s %s is already used by data row %d", sheet.Origin, i+1, pk.RawName, key, first+1)
+				p.Row, p.Col = sheet.cellOf(i, pk)
+				problems = append(problems, p)
+				continue
+			}
+			seen[key] = i
+		}
+	}
+	return problems
+}
+
+// NextIDBlock returns the n ids after every id used in the sheet or allocated
+// to it, within the sheet's configured range.
+func NextIDBlock(sheet *Sheet, blocks []IDBlock, n int) (IDBlock, error) {
+	pk := sheet.Fields[0]
+	if !isIntType(pk.RawType) {
+		return IDBlock{}, fmt.Errorf("%s: primary key %s is %s, not an integer", sheet.Origin, pk.RawName, pk.RawType)
+	}
+	last := 0
+	for _, item := range sheet.Items {
+		if v, ok := item[pk.RawName].(int); ok {
+			last = max(last, v)
+		}
+	}
+	for _, b := range blocks {
+		last = max(last, b.To)
+	}
+	block := IDBlock{From: last + 1, To: last + n}
+	if r := sheet.IDRange; r != nil {
+		block.From = max(block.From, r.From)
+		block.To = block.From + n - 1
+		if block.To > r.To {
+			return IDBlock{}, fmt.Errorf("%s: no %d free ids left in the ids range %d-%d", sheet.Origin, n, r.From, r.To)
+		}
+	}
+	return block, nil
+}
@@ -0,0 +1,144 @@
+package genxls
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// JoinConfig embeds columns of the row another sheet's primary key column
+// references, so client payloads come denormalized while the workbooks stay
+// normalized.
+type JoinConfig struct {
+	Column string   `json:"column"` // the referencing column, e.g. itemId
+	Sheet  string   `json:"sheet"`  // the referenced sheet: sheet name, type name or JSON key
+	Fields []string `json:"fields"` // referenced columns to embed
+	// Prefix names the embedded columns prefix+Name, e.g. itemName. It
+	// defaults to the referenced sheet name starting in lower case.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// addJoinedFields appends the embedded columns of every join to sheet. An
+// empty reference (0 or "") embeds zero values; a reference to a row the
+// other sheet doesn't have is an error.
+func addJoinedFields(sheet *Sheet, sheets []*Sheet, joins []JoinConfig) error {
+	for _, j := range joins {
+		col := findField(sheet.Fields, j.Column)
+		if col == nil {
+			var known []string
+			for _, f := range sheet.Fields {
+				known = append(known, f.RawName)
+			}
+			return fmt.Errorf("join on unknown column %q%s", j.Column, didYouMean(j.Column, known))
+		}
+		ref := FindSheet(sheets, j.Sheet)
+		if ref == nil {
+			return fmt.Errorf("join %s: sheet %q is not loaded", col.RawName, j.Sheet)
+		}
+		if len(j.Fields) == 0 {
+			return fmt.Errorf("join %s: no fields", col.RawName)
+		}
+		pk := ref.Fields[0]
+		if col.RawType != pk.RawType && !(isIntType(col.RawType) && isIntType(pk.RawType)) {
+			return fmt.Errorf("join %s: %s column can't reference %s's %s primary key %s", col.RawName, col.RawType, ref.TypeName, pk.RawType, pk.RawName)
+		}
+		rows := make(map[string]map[string]any, len(ref.Items))
+		for _, item := range ref.Items {
+			rows[fmt.Sprint(item[pk.RawName])] = item
+		}
+
+		prefix := j.Prefix
+		if prefix == "" {
+			prefix = lowerFirst(ref.Name)
+		}
+		var srcs, embedded []Field
+		for _, name := range j.Fields {
+			src := findField(ref.Fields, name)
+			if src == nil {
+				var known []string
+				for _, f := range ref.Fields {
+					known = append(known, f.RawName)
+				}
+				return fmt.Errorf("join %s: %s has no column %q%s", col.RawName, ref.TypeName, name, didYouMean(name, known))
+			}
+			f := *src
+			f.RawName = prefix + exportName(src.RawName)
+			f.Name = exportName(f.RawName)
+			f.Col = -1 // not in the workbook
+			f.SortKey = false
+			f.Key = false
+			f.Ref = nil
+			if findField(sheet.Fields, f.RawName) != nil {
+				return fmt.Errorf("join %s: the sheet already has a column %q (set a prefix)", col.RawName, f.RawName)
+			}
+			srcs, embedded = append(srcs, *src), append(embedded, f)
+		}
+
+		for i, item := range sheet.Items {
+			v := item[col.RawName]
+			target, ok := rows[fmt.Sprint(v)]
+			if !ok && v != nil && !reflect.ValueOf(v).IsZero() {
+				return fmt.Errorf("join %s: data row %d references %s %v, which %s doesn't have", col.RawName, i+1, pk.RawName, v, ref.TypeName)
+			}
+			for k, f := range embedded {
+				if ok {
+					item[f.RawName] = target[srcs[k].RawName]
+					continue
+				}
+				zero, err := parseCellValue(f.RawType, "")
+				if err != nil {
+					return err
+				}
+				item[f.RawName] = zero
+			}
+		}
+		sheet.Fields = append(sheet.Fields, embedded...)
+	}
+	return nil
+}
+
+// joinOrder returns the config entries of the loaded sheets with joins, those
+// of every sheet after those of the sheets it joins, so joined columns can be
+// joined again. A sheet joining itself is fine; longer cycles are an error.
+func joinOrder(cfg *Config, sheets []*Sheet, names []string) ([]string, error) {
+	entries := make(map[*Sheet][]string)
+	for _, name := range names {
+		if sheet := FindSheet(sheets, name); sheet != nil && len(cfg.Sheets[name].Joins) > 0 {
+			entries[sheet] = append(entries[sheet], name)
+		}
+	}
+	const visiting, visited = 1, 2
+	state := make(map[*Sheet]int)
+	var order []string
+	var visit func(sheet *Sheet, path []string) error
+	visit = func(sheet *Sheet, path []string) error {
+		path = append(path, sheet.Name)
+		switch state[sheet] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("config: join cycle %s", strings.Join(path, " -> "))
+		}
+		state[sheet] = visiting
+		for _, name := range entries[sheet] {
+			for _, j := range cfg.Sheets[name].Joins {
+				if ref := FindSheet(sheets, j.Sheet); ref != nil && ref != sheet {
+					if err := visit(ref, path); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		state[sheet] = visited
+		order = append(order, entries[sheet]...)
+		return nil
+	}
+	for _, name := range names {
+		if sheet := FindSheet(sheets, name); sheet != nil {
+			if err := visit(sheet, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return order, nil
+}
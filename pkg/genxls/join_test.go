@@ -0,0 +1,71 @@
+package genxls
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestJoinOrder(t *testing.T) {
+	load := func(t *testing.T) []*Sheet {
+		t.Helper()
+		src := NewMemorySource("test").
+			Add("Alpha", [][]string{{"id#int", "betaId#int"}, {"1", "10"}}).
+			Add("Beta", [][]string{{"id#int", "gammaId#int"}, {"10", "100"}}).
+			Add("Gamma", [][]string{{"id#int", "name#string", "alphaId#int"}, {"100", "gold", "1"}})
+		sheets, err := LoadSources(context.Background(), []SheetSource{src}, Options{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sheets
+	}
+
+	// Alpha embeds the column Beta's join embeds, and sorts before Beta.
+	cfg := &Config{Sheets: map[string]SheetConfig{
+		"Alpha": {Joins: []JoinConfig{{Column: "betaId", Sheet: "Beta", Fields: []string{"gammaName"}}}},
+		"Beta":  {Joins: []JoinConfig{{Column: "gammaId", Sheet: "Gamma", Fields: []string{"name"}}}},
+	}}
+	sheets := load(t)
+	if err := ApplyConfig(cfg, sheets, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := sheets[0].Items[0]["betaGammaName"]; got != "gold" {
+		t.Errorf("Alpha.betaGammaName = %v, want gold", got)
+	}
+
+	cfg.Sheets["Gamma"] = SheetConfig{Joins: []JoinConfig{{Column: "alphaId", Sheet: "Alpha", Fields: []string{"id"}}}}
+	err := ApplyConfig(cfg, load(t), false)
+	if err == nil || !strings.Contains(err.Error(), "join cycle Alpha -> Beta -> Gamma -> Alpha") {
+		t.Errorf("got %v, want a join cycle error", err)
+	}
+}
+
+// TestDependencyCycles checks both kinds of dependencies between sheets fail
+// on a cycle, naming its path: ref columns typed from each other (resolveRefs)
+// and joins embedding each other's columns (joinOrder).
+func TestDependencyCycles(t *testing.T) {
+	src := NewMemorySource("test").
+		Add("Alpha", [][]string{{"id#int", "next#ref:Beta.prev"}, {"1", "1"}}).
+		Add("Beta", [][]string{{"id#int", "prev#ref:Gamma.back"}, {"1", "1"}}).
+		Add("Gamma", [][]string{{"id#int", "back#ref:Alpha.next"}, {"1", "1"}})
+	_, err := LoadSources(context.Background(), []SheetSource{src}, Options{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "reference cycle Alpha.next -> Beta.prev -> Gamma.back -> Alpha.next") {
+		t.Errorf("refs: got %v, want a reference cycle error", err)
+	}
+
+	src = NewMemorySource("test").
+		Add("Alpha", [][]string{{"id#int", "betaId#int"}, {"1", "1"}}).
+		Add("Beta", [][]string{{"id#int", "alphaId#int"}, {"1", "1"}})
+	sheets, err := LoadSources(context.Background(), []SheetSource{src}, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{Sheets: map[string]SheetConfig{
+		"Alpha": {Joins: []JoinConfig{{Column: "betaId", Sheet: "Beta", Fields: []string{"id"}}}},
+		"Beta":  {Joins: []JoinConfig{{Column: "alphaId", Sheet: "Alpha", Fields: []string{"id"}}}},
+	}}
+	err = ApplyConfig(cfg, sheets, false)
+	if err == nil || !strings.Contains(err.Error(), "join cycle Alpha -> Beta -> Alpha") {
+		t.Errorf("joins: got %v, want a join cycle error", err)
+	}
+}
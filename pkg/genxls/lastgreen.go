@@ -0,0 +1,159 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// RunSummary is what --last-green keeps of a successful run: per sheet the
+// row count, columns and row hashes. Unlike the run state in --out it lives
+// wherever CI caches it between builds.
+type RunSummary struct {
+	Time   string                   `json:"time"`
+	Sheets map[string]*SheetSummary `json:"sheets"` // keyed by JSON key
+}
+
+type SheetSummary struct {
+	Rows      int               `json:"rows"`
+	Columns   []string          `json:"columns"`
+	RowHashes map[string]string `json:"rowHashes"` // primary key -> row hash
+}
+
+// lastGreenSample is how many keys a summary line names before "...".
+const lastGreenSample = 5
+
+// loadRunSummary returns nil when path doesn't exist, i.e. before the first
+// green run.
+func loadRunSummary(path string) (*RunSummary, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s RunSummary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+func buildRunSummary(cur *RunState, when time.Time) *RunSummary {
+	s := &RunSummary{Time: when.Format(time.RFC3339), Sheets: make(map[string]*SheetSummary, len(cur.Sheets))}
+	for key, st := range cur.Sheets {
+		s.Sheets[key] = &SheetSummary{Rows: len(st.Rows), Columns: st.Columns, RowHashes: st.Rows}
+	}
+	return s
+}
+
+func saveRunSummary(path string, s *RunSummary) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// printLastGreenDiff writes what changed since the last green run, a line per
+// changed sheet, for CI logs.
+func printLastGreenDiff(out io.Writer, prev *RunSummary, cur *RunState, sheets []*Sheet) error {
+	if prev == nil {
+		_, err := fmt.Fprintln(out, "no previous green run to compare with (--last-green)")
+		return err
+	}
+	when := prev.Time
+	if t, err := time.Parse(time.RFC3339, prev.Time); err == nil {
+		when = t.Local().Format("2006-01-02 15:04")
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "changes since the last green run (%s):\n", when)
+	unchanged := 0
+	for _, sheet := range sheets {
+		rows := cur.Sheets[sheet.JSONKey].Rows
+		old, ok := prev.Sheets[sheet.JSONKey]
+		if !ok {
+			fmt.Fprintf(w, "  %s\tnew sheet, %d rows\n", sheet.JSONKey, len(rows))
+			continue
+		}
+		var parts []string
+		if cols := columnChanges(old.Columns, cur.Sheets[sheet.JSONKey].Columns); cols != "" {
+			parts = append(parts, cols)
+		}
+		keys, _ := rowKeys(sheet) // already validated by buildRunState
+		added, changed, removed := diffRowHashes(keys, old.RowHashes, rows)
+		for _, d := range []struct {
+			what string
+			keys []string
+		}{{"added", added}, {"changed", changed}, {"removed", removed}} {
+			if len(d.keys) > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s (%s)", len(d.keys), d.what, sampleKeys(d.keys)))
+			}
+		}
+		if len(parts) == 0 {
+			unchanged++
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%d -> %d rows\t%s\n", sheet.JSONKey, old.Rows, len(rows), strings.Join(parts, ", "))
+	}
+	var gone []string
+	for key := range prev.Sheets {
+		if _, ok := cur.Sheets[key]; !ok {
+			gone = append(gone, key)
+		}
+	}
+	sort.Strings(gone)
+	for _, key := range gone {
+		fmt.Fprintf(w, "  %s\tsheet removed (had %d rows)\n", key, prev.Sheets[key].Rows)
+	}
+	if unchanged > 0 {
+		fmt.Fprintf(w, "  %d sheets unchanged\n", unchanged)
+	}
+	return w.Flush()
+}
+
+// columnChanges describes columns added and removed, e.g. "columns +reward#int
+// -old#string", or "" when there are none.
+func columnChanges(old, cur []string) string {
+	had := make(map[string]bool, len(old))
+	for _, c := range old {
+		had[c] = true
+	}
+	has := make(map[string]bool, len(cur))
+	var parts []string
+	for _, c := range cur {
+		has[c] = true
+		if !had[c] {
+			parts = append(parts, "+"+c)
+		}
+	}
+	for _, c := range old {
+		if !has[c] {
+			parts = append(parts, "-"+c)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "columns " + strings.Join(parts, " ")
+}
+
+func sampleKeys(keys []string) string {
+	if len(keys) <= lastGreenSample {
+		return strings.Join(keys, ", ")
+	}
+	return strings.Join(keys[:lastGreenSample], ", ") + ", ..."
+}
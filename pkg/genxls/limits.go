@@ -0,0 +1,94 @@
+package genxls
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits are export guardrails; zero means unlimited.
+type Limits struct {
+	MaxRows         int
+	MaxStringLen    int // in characters
+	MaxPayloadBytes int64
+}
+
+// checkLimits returns one problem per exceeded limit.
+func checkLimits(l Limits, sheets []*Sheet) ([]Problem, error) {
+	var problems []Problem
+	for _, sheet := range sheets {
+		if l.MaxRows > 0 && len(sheet.Items) > l.MaxRows {
+			problems = append(problems, problemf(sheet, "%s: %d rows exceeds --max-rows %d", sheet.Origin, len(sheet.Items), l.MaxRows))
+		}
+		if l.MaxStringLen <= 0 {
+			continue
+		}
+		for i, item := range sheet.Items {
+			for _, f := range sheet.Fields {
+				s, ok := item[f.RawName].(string)
+				if !ok {
+					continue
+				}
+				if n := utf8.RuneCountInString(s); n > l.MaxStringLen {
+					p := problemf(sheet, "%s: data row %d (%s) string length %d exceeds --max-string-len %d", sheet.Origin, i+1, f.RawName, n, l.MaxStringLen)
+					p.Row, p.Col = sheet.cellOf(i, f)
+					problems = append(problems, p)
+				}
+			}
+		}
+	}
+	if l.MaxPayloadBytes > 0 {
+		data, err := json.Marshal(buildJSONPayload(sheets))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > l.MaxPayloadBytes {
+			problems = append(problems, problemf(nil, "payload size %s exceeds --max-payload %s", FormatByteSize(int64(len(data))), FormatByteSize(l.MaxPayloadBytes)))
+		}
+		chunks, err := chunkPayloadProblems(sheets, l.MaxPayloadBytes)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, chunks...)
+	}
+	return problems, nil
+}
+
+// ParseByteSize parses sizes like 1048576, 512KB, 300MB or 1GB (binary units).
+func ParseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+func FormatByteSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
@@ -0,0 +1,204 @@
+package genxls
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const DefaultLockFile = "genxls.lock"
+
+// Lock pins every sheet's schema. Sections are keyed by --flag ("all" when
+// unset) because server and client exports have different columns.
+type Lock map[string]map[string]LockedSheet
+
+type LockedSheet struct {
+	Hash    string       `json:"hash,omitempty"`
+	Columns []string     `json:"columns,omitempty"`
+	Proto   *LockedProto `json:"proto,omitempty"`
+	// Removed marks a sheet that is gone, kept so its proto field number is
+	// not given to another sheet.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// LockedProto pins the proto field numbers of a sheet (--lang proto): its
+// field in the root message and its columns', by column name. Numbers stay
+// with a column when columns move and are not reused once it's removed.
+type LockedProto struct {
+	Field   int            `json:"field"`
+	Columns map[string]int `json:"columns"`
+}
+
+// loadLock returns nil when path doesn't exist, i.e. schemas are not locked.
+func loadLock(path string) (Lock, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if lock == nil {
+		lock = Lock{}
+	}
+	return lock, nil
+}
+
+func saveLock(path string, lock Lock) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func lockSection(exportFlag string) string {
+	if exportFlag == "" {
+		return "all"
+	}
+	return exportFlag
+}
+
+// lockedSheet describes the parts of a sheet's schema consumers depend on:
+// column names, types and the JSON string option.
+func lockedSheet(sheet *Sheet) LockedSheet {
+	cols := make([]string, len(sheet.Fields))
+	for i, f := range sheet.Fields {
+		cols[i] = f.RawName + "#" + strings.ToLower(f.RawType)
+		if f.JSONString {
+			cols[i] += ",str"
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join(cols, "\n")))
+	return LockedSheet{Hash: "sha256:" + hex.EncodeToString(sum[:]), Columns: cols}
+}
+
+// checkLock reports sheets whose schema differs from the lock, or whose proto
+// field numbers it doesn't pin yet when proto is generated. Locked sheets
+// missing from the run count as removed only when every sheet was loaded.
+func checkLock(lock Lock, section string, sheets []*Sheet, partial, proto bool) []Problem {
+	locked, ok := lock[section]
+	if !ok {
+		return []Problem{problemf(nil, "no schemas locked for %s exports yet", section)}
+	}
+	var problems []Problem
+	seen := make(map[string]bool, len(sheets))
+	for _, sheet := range sheets {
+		seen[sheet.JSONKey] = true
+		cur := lockedSheet(sheet)
+		old, ok := locked[sheet.JSONKey]
+		switch {
+		case !ok || old.Removed:
+			problems = append(problems, problemf(sheet, "%s: new sheet %s is not in the lock", sheet.Origin, sheet.JSONKey))
+		case old.Hash != cur.Hash:
+			problems = append(problems, problemf(sheet, "%s: schema changed\n      locked: %s\n      now:    %s",
+				sheet.Origin, strings.Join(old.Columns, ", "), strings.Join(cur.Columns, ", ")))
+		case proto && old.Proto == nil:
+			problems = append(problems, problemf(sheet, "%s: proto field numbers of %s are not in the lock", sheet.Origin, sheet.JSONKey))
+		}
+	}
+	if !partial {
+		var gone []string
+		for key, old := range locked {
+			if !seen[key] && !old.Removed {
+				gone = append(gone, key)
+			}
+		}
+		sort.Strings(gone)
+		for _, key := range gone {
+			problems = append(problems, problemf(nil, "locked sheet %s was removed", key))
+		}
+	}
+	return problems
+}
+
+// updateLock records the current schemas. Entries of sheets that were not
+// loaded are kept for partial runs and dropped otherwise, except for their
+// proto field numbers. With proto, sheets and columns without a number get
+// the next free one, so a lock's first numbers are the define-row positions.
+func updateLock(lock Lock, section string, sheets []*Sheet, partial, proto bool) Lock {
+	if lock == nil {
+		lock = Lock{}
+	}
+	old := lock[section]
+	locked := make(map[string]LockedSheet, len(sheets))
+	next := 1
+	for key, e := range old {
+		switch {
+		case partial:
+			locked[key] = e
+		case e.Proto != nil:
+			locked[key] = LockedSheet{Proto: e.Proto, Removed: true}
+		}
+		if e.Proto != nil {
+			next = max(next, e.Proto.Field+1)
+		}
+	}
+	for _, sheet := range sheets {
+		e := lockedSheet(sheet)
+		e.Proto = old[sheet.JSONKey].Proto
+		if proto {
+			if e.Proto == nil {
+				e.Proto = &LockedProto{Field: next}
+				next++
+			}
+			e.Proto = pinProtoColumns(e.Proto, sheet)
+		}
+		locked[sheet.JSONKey] = e
+	}
+	lock[section] = locked
+	return lock
+}
+
+// pinProtoColumns returns p with a number for every column of sheet.
+func pinProtoColumns(p *LockedProto, sheet *Sheet) *LockedProto {
+	cols := make(map[string]int, len(p.Columns)+len(sheet.Fields))
+	next := 1
+	for name, n := range p.Columns {
+		cols[name] = n
+		next = max(next, n+1)
+	}
+	for _, f := range sheet.Fields {
+		if _, ok := cols[f.RawName]; !ok {
+			cols[f.RawName] = next
+			next++
+		}
+	}
+	return &LockedProto{Field: p.Field, Columns: cols}
+}
+
+// applyProtoNumbers gives sheets and their columns the proto field numbers
+// the lock section pins; the others keep their positions.
+func applyProtoNumbers(locked map[string]LockedSheet, sheets []*Sheet) {
+	for _, sheet := range sheets {
+		p := locked[sheet.JSONKey].Proto
+		if p == nil {
+			continue
+		}
+		sheet.ProtoField = p.Field
+		for i := range sheet.Fields {
+			sheet.Fields[i].ProtoField = p.Columns[sheet.Fields[i].RawName]
+		}
+	}
+}
+
+// ApplyLockedProtoNumbers numbers the proto fields of sheets as the lock file
+// at path pins them for exportFlag's exports, like --lang proto does. Without
+// the file they keep their positions.
+func ApplyLockedProtoNumbers(path, exportFlag string, sheets []*Sheet) error {
+	lock, err := loadLock(path)
+	if err != nil {
+		return err
+	}
+	applyProtoNumbers(lock[lockSection(exportFlag)], sheets)
+	return nil
+}
@@ -0,0 +1,63 @@
+package genxls
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Parsing and generation report through Options.Logger: warnings at
+// slog.LevelWarn, progress (what -v prints) at slog.LevelInfo. Messages are
+// complete sentences, so embedders can route them to any handler; attributes
+// only add what a handler may want to index, like an artifact's path.
+
+// cliHandler renders records as the command line always printed them:
+// "warning: " before warnings, nothing before the rest, and no attributes.
+type cliHandler struct {
+	w     io.Writer
+	level slog.Level
+	mu    *sync.Mutex
+}
+
+// NewCLILogger logs to w, progress included when verbose.
+func NewCLILogger(w io.Writer, verbose bool) *slog.Logger {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelInfo
+	}
+	return slog.New(&cliHandler{w: w, level: level, mu: new(sync.Mutex)})
+}
+
+func (h *cliHandler) Enabled(_ context.Context, level slog.Level) bool { return level >= h.level }
+
+func (h *cliHandler) Handle(_ context.Context, r slog.Record) error {
+	line := r.Message + "\n"
+	if r.Level >= slog.LevelWarn {
+		line = "warning: " + line
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line)
+	return err
+}
+
+func (h *cliHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *cliHandler) WithGroup(string) slog.Handler      { return h }
+
+// logger returns Options.Logger, or a logger printing warnings to stderr.
+func (o Options) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return NewCLILogger(os.Stderr, false)
+}
+
+// report returns Options.Report, or stderr.
+func (o Options) report() io.Writer {
+	if o.Report != nil {
+		return o.Report
+	}
+	return os.Stderr
+}
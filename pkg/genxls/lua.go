@@ -0,0 +1,163 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func mapLuaType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return "integer", true
+	case "int[]":
+		return "integer[]", true
+	case "int[][]":
+		return "integer[][]", true
+	case "float", "float32", "float64":
+		return "number", true
+	case "bool":
+		return "boolean", true
+	case "string", "datetime":
+		return "string", true
+	default:
+		return "", false
+	}
+}
+
+// GenerateLuaBundle renders the payload as a Lua chunk returning a table keyed
+// by sheet JSON key, each a list of row tables, suitable for
+// `local cfg = dofile "lua.gen.lua"`. The types are declared in LuaLS
+// annotations, which editors and linters pick up.
+func GenerateLuaBundle(rootName string, sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	for _, sheet := range sheets {
+		fmt.Fprintf(&b, "\n---@class %s\n", sheet.TypeName)
+		for _, f := range sheet.Fields {
+			t, ok := mapLuaType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("%s.%s: unsupported type %q", sheet.TypeName, f.RawName, f.RawType)
+			}
+			fmt.Fprintf(&b, "---@field %s %s", luaKey(f.RawName), t)
+			if f.Doc != "" {
+				b.WriteString(" " + strings.Join(strings.Fields(f.Doc), " "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&b, "\n---@class %s\n", rootName)
+	for _, sheet := range sheets {
+		fmt.Fprintf(&b, "---@field %s %s[]\n", luaKey(sheet.JSONKey), sheet.TypeName)
+	}
+
+	fmt.Fprintf(&b, "\n---@type %s\nlocal config = {\n", rootName)
+	for _, sheet := range sheets {
+		fmt.Fprintf(&b, "  %s = {\n", luaKey(sheet.JSONKey))
+		for _, item := range sheet.Items {
+			b.WriteString("    { ")
+			for i, f := range sheet.Fields {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				v, err := luaValue(item[f.RawName])
+				if err != nil {
+					return "", fmt.Errorf("%s.%s: %w", sheet.TypeName, f.RawName, err)
+				}
+				b.WriteString(luaKey(f.RawName))
+				b.WriteString(" = ")
+				b.WriteString(v)
+			}
+			b.WriteString(" },\n")
+		}
+		b.WriteString("  },\n")
+	}
+	b.WriteString("}\n\nreturn config\n")
+	return strings.TrimPrefix(b.String(), "\n"), nil
+}
+
+func luaValue(v any) (string, error) {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x), nil
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return "0/0", nil
+		case math.IsInf(x, 1):
+			return "math.huge", nil
+		case math.IsInf(x, -1):
+			return "-math.huge", nil
+		}
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case string:
+		return luaString(x), nil
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return "{" + strings.Join(parts, ", ") + "}", nil
+	case [][]int:
+		parts := make([]string, len(x))
+		for i, inner := range x {
+			parts[i], _ = luaValue(inner)
+		}
+		return "{" + strings.Join(parts, ", ") + "}", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// luaString quotes s as a double-quoted Lua literal. Control bytes use
+// three-digit decimal escapes, so a following digit can't extend them.
+func luaString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\%03d`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+var luaKeywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true, "false": true, "for": true,
+	"function": true, "goto": true, "if": true, "in": true, "local": true, "nil": true, "not": true, "or": true,
+	"repeat": true, "return": true, "then": true, "true": true, "until": true, "while": true,
+}
+
+var luaNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// luaKey writes a table key (and a ---@field name) as name = ..., or
+// ["name"] = ... for names Lua doesn't take bare: keywords and JSON keys from
+// the config that aren't identifiers.
+func luaKey(name string) string {
+	if luaKeywords[name] || !luaNameRe.MatchString(name) {
+		return "[" + luaString(name) + "]"
+	}
+	return name
+}
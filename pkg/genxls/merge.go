@@ -0,0 +1,64 @@
+package genxls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// columnsDiff describes how the columns of two sheets differ, or returns ""
+// when they have the same names and types in the same order.
+func columnsDiff(a, b *Sheet) string {
+	var diffs []string
+	for i := 0; i < max(len(a.Fields), len(b.Fields)); i++ {
+		switch {
+		case i >= len(a.Fields):
+			diffs = append(diffs, fmt.Sprintf("only %s has %s#%s", b.Origin, b.Fields[i].RawName, b.Fields[i].RawType))
+		case i >= len(b.Fields):
+			diffs = append(diffs, fmt.Sprintf("only %s has %s#%s", a.Origin, a.Fields[i].RawName, a.Fields[i].RawType))
+		case a.Fields[i].RawName != b.Fields[i].RawName || !strings.EqualFold(a.Fields[i].RawType, b.Fields[i].RawType):
+			diffs = append(diffs, fmt.Sprintf("column %d is %s#%s in %s but %s#%s in %s", i+1,
+				a.Fields[i].RawName, a.Fields[i].RawType, a.Origin, b.Fields[i].RawName, b.Fields[i].RawType, b.Origin))
+		}
+	}
+	return strings.Join(diffs, "; ")
+}
+
+// duplicateSheetError reports two sheets exported under the same JSON key,
+// whether their columns match, and what to do about it.
+func duplicateSheetError(prev, sheet *Sheet) error {
+	e := &DuplicateKeyError{Key: sheet.JSONKey, Sheet: sheet.Origin, PrevSheet: prev.Origin}
+	if diff := columnsDiff(prev, sheet); diff != "" {
+		e.Hint = "\n  columns differ: " + diff + "\n  rename one of the sheets"
+	} else {
+		e.Hint = "\n  columns match\n  rename one of the sheets, or rerun with --merge-sheets to export their rows as one sheet"
+	}
+	return e
+}
+
+// mergeSheet appends the rows of sheet, a sheet of another workbook with the
+// same key and columns, to prev. Their primary keys must not overlap.
+func mergeSheet(prev, sheet *Sheet) error {
+	if diff := columnsDiff(prev, sheet); diff != "" {
+		return fmt.Errorf("--merge-sheets: %s and %s: columns differ: %s", prev.Origin, sheet.Origin, diff)
+	}
+	pk := prev.Fields[0].RawName
+	seen := make(map[string]int, len(prev.Items))
+	for i, item := range prev.Items {
+		seen[fmt.Sprint(item[pk])] = i
+	}
+	for i, item := range sheet.Items {
+		key := fmt.Sprint(item[pk])
+		if j, ok := seen[key]; ok {
+			return &DuplicateKeyError{Key: key, Field: pk, Sheet: sheet.Origin, Row: sheet.Rows[i], PrevSheet: prev.Origin, PrevRow: prev.Rows[j]}
+		}
+	}
+	prev.Origin += ", " + sheet.Origin
+	prev.Items = append(prev.Items, sheet.Items...)
+	prev.Rows = append(prev.Rows, sheet.Rows...)
+	prev.BadCells = append(prev.BadCells, sheet.BadCells...)
+	prev.Bundles = mergeBundles(prev.Bundles, sheet.Bundles)
+	if sheet.Modified > prev.Modified {
+		prev.ModifiedBy, prev.Modified = sheet.ModifiedBy, sheet.Modified
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// writeMongoBundle writes one <jsonKey>.mongo.json file per sheet for
+// mongoimport: a JSON document per line with _id set to the primary key, the
+// first exported column, followed by every column. It returns the written
+// paths in sheet order.
+func writeMongoBundle(out *OutputLayout, sheets []*Sheet) ([]string, error) {
+	var written []string
+	for _, sheet := range sheets {
+		pk := sheet.Fields[0]
+		var b bytes.Buffer
+		for i, item := range sheet.Items {
+			id, err := json.Marshal(item[pk.RawName])
+			if err != nil {
+				return nil, fmt.Errorf("%s row %d: %w", sheet.TypeName, i+1, err)
+			}
+			doc, err := EncodeJSONObject(sheet.Fields, item)
+			if err != nil {
+				return nil, fmt.Errorf("%s row %d: %w", sheet.TypeName, i+1, err)
+			}
+			b.WriteString(`{"_id":`)
+			b.Write(id)
+			if len(doc) > 2 {
+				b.WriteString(",")
+			}
+			b.Write(doc[1:])
+			b.WriteString("\n")
+		}
+		outFile, err := out.WriteFile("mongo", sheet.JSONKey+".mongo.json", sheet, b.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, outFile)
+	}
+	return written, nil
+}
+
+// mongoImport loads the files writeMongoBundle wrote for sheets into the
+// database at uri (--mongo-uri) by running mongoimport, one collection per
+// sheet named after its JSON key. Each collection is dropped first so rows
+// removed from the sheet don't linger.
+func mongoImport(uri string, sheets []*Sheet, files []string) error {
+	if _, err := exec.LookPath("mongoimport"); err != nil {
+		return fmt.Errorf("--mongo-uri: %w (install the MongoDB database tools)", err)
+	}
+	for i, sheet := range sheets {
+		cmd := exec.Command("mongoimport", "--uri", uri, "--collection", sheet.JSONKey, "--file", files[i], "--drop", "--quiet")
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("mongoimport %s: %w", sheet.JSONKey, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package genxls
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// sheetBaseName is the exported name a sheet's type, root field and JSON key
+// derive from. With --namespace-by-file it is prefixed with the workbook name,
+// so battle.xlsx's Item and shop.xlsx's Item become BattleItem and ShopItem;
+// sheets already named after their workbook (Battle, BattleRule) and TSV
+// files, which are named by their file, are left as they are.
+func sheetBaseName(file, sheetName string, opts Options) string {
+	base := exportName(sheetName)
+	if !opts.NamespaceFile || base == "" {
+		return base
+	}
+	ns := exportName(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+	if strings.HasPrefix(strings.ToLower(base), strings.ToLower(ns)) {
+		return base
+	}
+	return ns + base
+}
@@ -0,0 +1,198 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sheetNameMatches reports whether name refers to the sheet called sheetName
+// in file, accepting the same names as FindSheet, before the sheet is parsed.
+func sheetNameMatches(file, sheetName, name string, opts Options) bool {
+	base := sheetBaseName(file, sheetName, opts)
+	fieldName := pluralizeTypeName(base)
+	for _, n := range []string{sheetName, opts.TypePrefix + base + opts.TypeSuffix, fieldName, lowerFirst(fieldName)} {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runOnly refreshes the outputs of a single sheet in place. Bundled source files
+// cover every sheet, so they are left alone and the sheet's columns must match
+// the last full run; outputs that bundle other sheets' data are not updated.
+func runOnly(opts Options, cfg *Config, out *OutputLayout, langs map[string]bool, dataFormat string, sheets []*Sheet, prev *RunState) error {
+	switch len(sheets) {
+	case 0:
+		return fmt.Errorf("--only: no sheet %q", opts.Only)
+	case 1:
+	default:
+		return fmt.Errorf("--only %q matched %d sheets", opts.Only, len(sheets))
+	}
+	sheet := sheets[0]
+	old, ok := prev.Sheets[sheet.JSONKey]
+	if !ok || !slices.Equal(old.Columns, sheetColumns(sheet)) {
+		return fmt.Errorf("%s: columns differ from the last full run; run a full export to regenerate the sources", sheet.Origin)
+	}
+
+	var written []string
+	if opts.JSON {
+		switch dataFormat {
+		case "json":
+			outFile, err := out.Path("json", out.DataName+".json", nil)
+			if err != nil {
+				return err
+			}
+			if err := patchJSONPayload(out, outFile, sheet); err != nil {
+				return err
+			}
+			written = append(written, outFile)
+			var rootNames []string
+			for name := range cfg.Roots {
+				rootNames = append(rootNames, name)
+			}
+			slices.Sort(rootNames)
+			for _, name := range rootNames {
+				if !slices.ContainsFunc(cfg.Roots[name], func(n string) bool { return FindSheet(sheets, n) != nil }) {
+					continue
+				}
+				outFile, err := out.rootLayout(Root{DataName: strings.ToLower(name), TypeName: bundleRootName(name)}).Path("json", strings.ToLower(name)+".json", nil)
+				if err != nil {
+					return err
+				}
+				if err := patchJSONPayload(out, outFile, sheet); err != nil {
+					return err
+				}
+				written = append(written, outFile)
+			}
+		case "jsonl", "tsv", "csv":
+			files, err := WriteDataPayload(out, dataFormat, sheets)
+			if err != nil {
+				return err
+			}
+			written = append(written, files...)
+		default:
+			warnOnlySkipped(opts, out.DataName+"."+dataFormat)
+		}
+	}
+	if langs["go"] {
+		for _, gp := range cfg.GoPackages {
+			if !slices.ContainsFunc(gp.Sheets, func(n string) bool { return FindSheet(sheets, n) != nil }) {
+				continue
+			}
+			outDir := gp.Out
+			if !filepath.IsAbs(outDir) {
+				outDir = filepath.Join(cfg.dir, outDir)
+			}
+			outFile := filepath.Join(outDir, out.DataName+".json")
+			if err := patchJSONPayload(out, outFile, sheet); err != nil {
+				return err
+			}
+			written = append(written, outFile)
+		}
+	}
+	if langs["ue"] {
+		csvData, err := generateUECSV(sheet.Fields, sheet.Items)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sheet.TypeName, err)
+		}
+		outFile, err := out.WriteFile("ue", sheet.TypeName+".csv", sheet, csvData)
+		if err != nil {
+			return err
+		}
+		written = append(written, outFile)
+	}
+	if opts.Parquet {
+		files, err := writeParquetBundle(out, sheets)
+		if err != nil {
+			return err
+		}
+		written = append(written, files...)
+	}
+	if opts.Avro {
+		files, err := writeAvroBundle(out, sheets)
+		if err != nil {
+			return err
+		}
+		written = append(written, files...)
+	}
+	if langs["php"] {
+		warnOnlySkipped(opts, "php.gen.php")
+	}
+	if langs["erl"] {
+		warnOnlySkipped(opts, "erl.gen.config")
+	}
+	if langs["lua"] {
+		warnOnlySkipped(opts, "lua.gen.lua")
+	}
+	if langs["capnp"] {
+		warnOnlySkipped(opts, out.DataName+".capnp.bin")
+	}
+	if opts.Redis {
+		warnOnlySkipped(opts, "redis.gen.resp")
+	}
+	if opts.Mongo {
+		files, err := writeMongoBundle(out, sheets)
+		if err != nil {
+			return err
+		}
+		if opts.MongoURI != "" {
+			if err := mongoImport(opts.MongoURI, sheets, files); err != nil {
+				return err
+			}
+		}
+		written = append(written, files...)
+	}
+	if opts.Provenance {
+		warnOnlySkipped(opts, "provenance.json")
+	}
+	for _, f := range written {
+		opts.logger().Info("updated "+f, "path", f)
+	}
+
+	cur, err := buildRunState(sheets)
+	if err != nil {
+		return err
+	}
+	prev.Sheets[sheet.JSONKey] = cur.Sheets[sheet.JSONKey]
+	return saveRunState(statePath(opts.OutDir), prev)
+}
+
+func warnOnlySkipped(opts Options, file string) {
+	opts.logger().Warn(file+" holds every sheet and is not updated by --only; run a full export", "path", file)
+}
+
+// patchJSONPayload replaces the sheet's entry in an existing aggregated JSON
+// payload, writing it back through out. Keys are re-sorted exactly as a full
+// run writes them.
+func patchJSONPayload(out *OutputLayout, path string, sheet *Sheet) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s does not exist; run a full export first", path)
+	}
+	if err != nil {
+		return err
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	entry, err := json.Marshal(buildJSONPayload([]*Sheet{sheet})[sheet.JSONKey])
+	if err != nil {
+		return err
+	}
+	payload[sheet.JSONKey] = entry
+	if sheet.OldJSONKey != "" {
+		payload[sheet.OldJSONKey] = entry
+	}
+	data, err = json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return err
+	}
+	return out.write(path, data)
+}
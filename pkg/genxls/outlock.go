@@ -0,0 +1,117 @@
+package genxls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// outLockName is created in --out for the length of a run, so two runs (two
+// CI jobs, or a developer and a watcher) never interleave their artifacts.
+const outLockName = ".genxls-run.lock"
+
+// outLockHolder is the content of the lock file, for the error the next run
+// reports and for telling a crashed run's lock from a live one.
+type outLockHolder struct {
+	PID     int    `json:"pid"`
+	Host    string `json:"host"`
+	Started string `json:"started"`
+}
+
+// lockOutDir takes the lock of dir, waiting up to wait for another run to
+// release it (0: fail at once). The lock is an OS advisory lock on the lock
+// file, so the OS drops it with the process holding it: a crashed run's file
+// is simply locked again. The returned func releases the lock.
+func lockOutDir(ctx context.Context, dir string, wait time.Duration, log *slog.Logger) (func(), error) {
+	path := filepath.Join(dir, outLockName)
+	host, _ := os.Hostname()
+	me := outLockHolder{PID: os.Getpid(), Host: host, Started: time.Now().Format(time.RFC3339)}
+	data, err := json.Marshal(me)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	waiting := false
+	for {
+		f, err := tryLockPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			err := f.Truncate(0)
+			if err == nil {
+				_, err = f.WriteAt(append(data, '\n'), 0)
+			}
+			if err != nil {
+				_ = unlockFile(f)
+				_ = f.Close()
+				return nil, err
+			}
+			return func() {
+				// Removed while still locked, so a run that opened it meanwhile
+				// finds its file replaced and opens the path again. Windows
+				// refuses to remove an open file, leaving it for the next run.
+				_ = os.Remove(path)
+				_ = unlockFile(f)
+				_ = f.Close()
+			}, nil
+		}
+
+		var holder outLockHolder
+		if b, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(b, &holder) // an unreadable holder is waited for like any other
+		}
+		by := "another run"
+		if holder.PID > 0 {
+			by = fmt.Sprintf("pid %d on %s since %s", holder.PID, holder.Host, holder.Started)
+		}
+		if !time.Now().Before(deadline) {
+			hint := "pass --wait-lock to wait for it"
+			if wait > 0 {
+				hint = "gave up after --wait-lock " + wait.String()
+			}
+			return nil, fmt.Errorf("%s is locked by %s (%s); %s", dir, by, path, hint)
+		}
+		if !waiting {
+			log.Info(fmt.Sprintf("waiting for %s, locked by %s", dir, by), "path", path)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// tryLockPath opens path and locks it without blocking, returning nil while
+// another run holds the lock. A file removed or replaced before the lock was
+// taken is opened again, so the lock is always on the file at path.
+func tryLockPath(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := lockFile(f)
+		if err != nil || !ok {
+			_ = f.Close()
+			return nil, err
+		}
+		locked, err := f.Stat()
+		if err != nil {
+			_ = unlockFile(f)
+			_ = f.Close()
+			return nil, err
+		}
+		if cur, err := os.Stat(path); err == nil && os.SameFile(locked, cur) {
+			return f, nil
+		}
+		_ = unlockFile(f)
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,61 @@
+package genxls
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockOutDir(t *testing.T) {
+	dir := t.TempDir()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	// A crashed run's file, naming a pid that no longer holds the lock.
+	if err := os.WriteFile(filepath.Join(dir, outLockName), []byte(`{"pid":1,"host":"elsewhere"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := lockOutDir(ctx, dir, 0, log)
+	if err != nil {
+		t.Fatalf("left-over lock file: %v", err)
+	}
+	if _, err := lockOutDir(ctx, dir, 0, log); err == nil || !strings.Contains(err.Error(), "is locked by pid") {
+		t.Errorf("second lock: got %v, want locked by this pid", err)
+	}
+	unlock()
+
+	// Runs racing for the lock hold it one at a time.
+	var mu sync.Mutex
+	holders, most := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := lockOutDir(ctx, dir, 10*time.Second, log)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			most = max(most, holders)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+	if most != 1 {
+		t.Errorf("%d runs held the lock at once", most)
+	}
+}
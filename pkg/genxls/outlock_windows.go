@@ -0,0 +1,29 @@
+package genxls
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRange is the byte LockFileEx locks, far past the holder written at the
+// start of the file: Windows locks are mandatory, and waiting runs read the
+// holder for their message.
+func lockRange() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: 1 << 30}
+}
+
+// lockFile takes an exclusive lock on f without blocking; ok is false while
+// another handle holds it, in this process or another.
+func lockFile(f *os.File) (ok bool, err error) {
+	err = windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, lockRange())
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, lockRange())
+}
@@ -0,0 +1,129 @@
+package genxls
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultOutTemplate keeps every artifact directly in --out under its usual name.
+const defaultOutTemplate = "{outDir}/{file}"
+
+// outputTargets are the keys of the config "outputs" map: the --lang targets
+// plus every data export.
+var outputTargets = append(append([]string(nil), knownLangs...),
+	"json", "yaml", "toml", "xml", "jsonl", "tsv", "csv", "parquet", "avro", "redis", "mongo", "provenance", "docs")
+
+var outPlaceholderRe = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+// OutputLayout decides where generated artifacts are written. Templates are
+// picked per target (go, Pb, ts, ..., json, xml, jsonl, tsv, csv, parquet,
+// avro, redis, mongo, provenance, docs) from the config "outputs" map, falling
+// back to Template.
+type OutputLayout struct {
+	OutDir   string
+	Template string            // --out-template; "" means defaultOutTemplate
+	Targets  map[string]string // target -> template, from the config file
+	Bundle   string            // --bundle, "" when every sheet is exported
+	DataName string            // all, or the bundle's payload name
+	RootName string            // AllConfig, or the bundle's root type
+	Sink     ArtifactSink      // --sink; nil writes the files
+	Context  context.Context   // stops the run between artifacts once done; nil: never
+
+	written map[string]string // path -> artifact, to catch templates that collide
+}
+
+// Path renders the template of target for one artifact. file is the artifact's default name in --out; sheet is nil for
+// artifacts that cover every sheet.
+func (l *OutputLayout) Path(target, file string, sheet *Sheet) (string, error) {
+	tmpl := l.Targets[target]
+	if tmpl == "" {
+		tmpl = l.Template
+	}
+	if tmpl == "" {
+		tmpl = defaultOutTemplate
+	}
+	ext := strings.TrimPrefix(filepath.Ext(file), ".")
+	vars := map[string]string{
+		"outDir": l.OutDir,
+		"lang":   target,
+		"file":   file,
+		"name":   strings.TrimSuffix(file, "."+ext),
+		"ext":    ext,
+		"sheet":  l.DataName,
+		"type":   l.RootName,
+		"bundle": l.DataName,
+		"owner":  "unowned",
+	}
+	if sheet != nil {
+		vars["sheet"] = sheet.JSONKey
+		vars["type"] = sheet.TypeName
+		if l.Bundle == "" && len(sheet.Bundles) > 0 {
+			vars["bundle"] = sheet.Bundles[0]
+		}
+		if sheet.Owner != "" {
+			vars["owner"] = sheet.Owner
+		}
+	}
+	var unknown []string
+	path := outPlaceholderRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		v, ok := vars[m[1:len(m)-1]]
+		if !ok {
+			unknown = append(unknown, m)
+		}
+		return v
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("output template %q: unknown placeholder %s (expect {outDir} {lang} {file} {name} {ext} {sheet} {type} {bundle} {owner})", tmpl, unknown[0])
+	}
+	path = filepath.Clean(path)
+
+	artifact := target + " " + file
+	if l.written == nil {
+		l.written = make(map[string]string)
+	}
+	if prev, ok := l.written[path]; ok && prev != artifact {
+		return "", fmt.Errorf("output template %q: %s and %s both resolve to %s", tmpl, prev, artifact, path)
+	}
+	l.written[path] = artifact
+	return path, nil
+}
+
+// WriteFile writes one artifact to its templated path and returns that path.
+func (l *OutputLayout) WriteFile(target, file string, sheet *Sheet, data []byte) (string, error) {
+	path, err := l.Path(target, file, sheet)
+	if err != nil {
+		return "", err
+	}
+	if err := l.write(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// write stores an artifact at a path returned by Path.
+func (l *OutputLayout) write(path string, data []byte) error {
+	ctx := l.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.Sink == nil {
+		return fsSink{}.WriteArtifact(ctx, path, data)
+	}
+	return l.Sink.WriteArtifact(ctx, path, data)
+}
+
+// embedPath returns dataFile relative to the directory of goFile, which
+// //go:embed requires to hold it.
+func embedPath(goFile, dataFile string) (string, error) {
+	rel, err := filepath.Rel(filepath.Dir(goFile), dataFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("--go-embed: %s must be in the directory of %s or below it", dataFile, goFile)
+	}
+	return filepath.ToSlash(rel), nil
+}
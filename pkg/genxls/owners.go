@@ -0,0 +1,125 @@
+package genxls
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OwnerRule assigns an owner to the workbooks matching Pattern. As in
+// CODEOWNERS, the last matching rule wins.
+type OwnerRule struct {
+	Pattern string `json:"pattern"` // glob against the workbook path or its base name
+	Owner   string `json:"owner"`
+}
+
+// ownerOf resolves who owns a sheet: its "sheets" entry wins over workbook
+// rules. c may be nil.
+func (c *Config) ownerOf(file, sheetName string, opts Options) string {
+	if c == nil {
+		return ""
+	}
+	names := make([]string, 0, len(c.Sheets))
+	for name := range c.Sheets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if o := c.Sheets[name].Owner; o != "" && sheetNameMatches(file, sheetName, name, opts) {
+			return o
+		}
+	}
+	owner := ""
+	for _, r := range c.Owners {
+		if pathMatches(r.Pattern, file) {
+			owner = r.Owner
+		}
+	}
+	return owner
+}
+
+// Problem is a validation finding, attributed to a sheet when there is one.
+type Problem struct {
+	Sheet *Sheet
+	Msg   string
+	// Row and Col locate the problem's source cell (1-based), for --annotate;
+	// zero when it isn't about one cell.
+	Row, Col int
+}
+
+func problemf(sheet *Sheet, format string, args ...any) Problem {
+	return Problem{Sheet: sheet, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p Problem) owner() string {
+	if p.Sheet == nil {
+		return ""
+	}
+	return p.Sheet.Owner
+}
+
+func (p Problem) modifiedBy() string {
+	if p.Sheet == nil {
+		return ""
+	}
+	return p.Sheet.ModifiedBy
+}
+
+// String is the message with the sheet owner and last editor, for one-line
+// warnings.
+func (p Problem) String() string {
+	return p.Msg + annotation(p.owner(), p.modifiedBy())
+}
+
+// annotation returns " [owner: @x, last modified by y]" with the parts that
+// are known, or "".
+func annotation(owner, modifiedBy string) string {
+	var parts []string
+	if owner != "" {
+		parts = append(parts, "owner: "+owner)
+	}
+	if modifiedBy != "" {
+		parts = append(parts, "last modified by "+modifiedBy)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+// FormatProblems lists problems for an error report, grouped by owner in order
+// of first appearance with unowned problems last. Without any owners it is a
+// plain indented list.
+func FormatProblems(problems []Problem) string {
+	var owners []string
+	byOwner := make(map[string][]string)
+	for _, p := range problems {
+		o := p.owner()
+		if _, ok := byOwner[o]; !ok && o != "" {
+			owners = append(owners, o)
+		}
+		byOwner[o] = append(byOwner[o], p.Msg+annotation("", p.modifiedBy()))
+	}
+	if len(owners) == 0 {
+		return "  " + strings.Join(byOwner[""], "\n  ")
+	}
+	var b strings.Builder
+	for _, o := range append(owners, "") {
+		msgs := byOwner[o]
+		if len(msgs) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if o == "" {
+			b.WriteString("  (no owner):")
+		} else {
+			b.WriteString("  " + o + ":")
+		}
+		for _, m := range msgs {
+			b.WriteString("\n    " + m)
+		}
+	}
+	return b.String()
+}
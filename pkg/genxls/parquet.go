@@ -0,0 +1,121 @@
+package genxls
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func mapParquetNode(t string) (parquet.Node, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32", "int64":
+		return parquet.Leaf(parquet.Int64Type), true
+	case "int[]":
+		return parquet.List(parquet.Leaf(parquet.Int64Type)), true
+	case "int[][]":
+		return parquet.List(parquet.List(parquet.Leaf(parquet.Int64Type))), true
+	case "float", "float32", "float64":
+		return parquet.Leaf(parquet.DoubleType), true
+	case "bool":
+		return parquet.Leaf(parquet.BooleanType), true
+	case "string", "datetime":
+		return parquet.String(), true
+	default:
+		return nil, false
+	}
+}
+
+// parquetGroup is a parquet.Group that keeps its columns in define-row order;
+// parquet.Group itself sorts them by name.
+type parquetGroup struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func (g parquetGroup) Fields() []parquet.Field { return g.fields }
+
+func (g parquetGroup) String() string {
+	var b strings.Builder
+	b.WriteString("message {")
+	for _, f := range g.fields {
+		fmt.Fprintf(&b, "\n\t%s: %s", f.Name(), f.Type())
+	}
+	b.WriteString("\n}")
+	return b.String()
+}
+
+// parquetField is a column of a parquetGroup, read from map rows.
+type parquetField struct {
+	parquet.Node
+	name string
+}
+
+func (f parquetField) Name() string { return f.name }
+
+func (f parquetField) Value(base reflect.Value) reflect.Value {
+	if base.Kind() == reflect.Interface {
+		if base.IsNil() {
+			return reflect.ValueOf(nil)
+		}
+		if base = base.Elem(); base.Kind() == reflect.Pointer && base.IsNil() {
+			return reflect.ValueOf(nil)
+		}
+	}
+	return base.MapIndex(reflect.ValueOf(f.name))
+}
+
+func parquetSchema(typeName string, fields []Field) (*parquet.Schema, error) {
+	group := parquetGroup{Group: make(parquet.Group, len(fields))}
+	for _, f := range fields {
+		node, ok := mapParquetNode(f.RawType)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %q", f.RawType)
+		}
+		group.Group[f.RawName] = node
+		group.fields = append(group.fields, parquetField{Node: node, name: f.RawName})
+	}
+	return parquet.NewSchema(typeName, group), nil
+}
+
+// writeParquetBundle writes one <jsonKey>.parquet file per sheet and returns
+// the written paths in sheet order.
+func writeParquetBundle(out *OutputLayout, sheets []*Sheet) ([]string, error) {
+	var written []string
+	for _, sheet := range sheets {
+		schema, err := parquetSchema(sheet.TypeName, sheet.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sheet.TypeName, err)
+		}
+
+		outFile, err := out.Path("parquet", sheet.JSONKey+".parquet", sheet)
+		if err != nil {
+			return nil, err
+		}
+		data, err := encodeParquet(schema, sheet.Items)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", outFile, err)
+		}
+		if err := out.write(outFile, data); err != nil {
+			return nil, err
+		}
+		written = append(written, outFile)
+	}
+	return written, nil
+}
+
+func encodeParquet(schema *parquet.Schema, items []map[string]any) ([]byte, error) {
+	var b bytes.Buffer
+	w := parquet.NewWriter(&b, schema)
+	for _, item := range items {
+		if err := w.Write(item); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,64 @@
+package genxls
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetColumnOrder(t *testing.T) {
+	fields := []Field{
+		{RawName: "id", RawType: "int"},
+		{RawName: "name", RawType: "string"},
+		{RawName: "cost", RawType: "float"},
+		{RawName: "active", RawType: "bool"},
+		{RawName: "tags", RawType: "int[]"},
+		{RawName: "grid", RawType: "int[][]"},
+		{RawName: "at", RawType: "datetime"},
+	}
+	items := []map[string]any{
+		{"id": 1, "name": "Sword", "cost": 1.5, "active": true, "tags": []int{1, 2}, "grid": [][]int{{1}, {2, 3}}, "at": "2024-01-02T03:04:05"},
+		{"id": 2, "name": "", "cost": 0.0, "active": false, "tags": []int{}, "grid": [][]int{}, "at": ""},
+	}
+	schema, err := parquetSchema("Item", fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeParquet(schema, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range f.Schema().Fields() {
+		got = append(got, c.Name())
+	}
+	if want := []string{"id", "name", "cost", "active", "tags", "grid", "at"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns %v, want define-row order %v", got, want)
+	}
+
+	rows := make([]parquet.Row, len(items))
+	r := parquet.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	if n, err := r.ReadRows(rows); n != len(items) {
+		t.Fatalf("read %d rows: %v", n, err)
+	}
+	var id, name parquet.Value
+	rows[0].Range(func(col int, values []parquet.Value) bool {
+		switch col {
+		case 0:
+			id = values[0]
+		case 1:
+			name = values[0]
+		}
+		return true
+	})
+	if id.Int64() != 1 || name.String() != "Sword" {
+		t.Errorf("first row id %v name %v, want 1 Sword", id, name)
+	}
+}
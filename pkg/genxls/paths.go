@@ -0,0 +1,138 @@
+package genxls
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// cleanInputPath undoes what shells and file managers add to a pasted --in:
+// surrounding ASCII blanks and the quotes of Explorer's "Copy as path". Other
+// Unicode spaces are kept: a full-width space (U+3000) at the end of a name is
+// part of the file name on Chinese- and Japanese-locale systems.
+func cleanInputPath(in string) string {
+	in = strings.Trim(in, " \t\r\n")
+	if len(in) >= 2 && in[0] == '"' && in[len(in)-1] == '"' {
+		in = strings.Trim(in[1:len(in)-1], " \t\r\n")
+	}
+	return in
+}
+
+// maxPathLen is Windows' MAX_PATH less room for a file name, the length from
+// which the os package switches to \\?\ paths.
+const maxPathLen = 248
+
+// longPath makes a long relative path absolute on Windows: the os package
+// only adds the \\?\ prefix past MAX_PATH to absolute paths. Paths typed with
+// the prefix (\\?\C:\..., \\?\UNC\server\share\...) are kept as they are.
+func longPath(p string) string {
+	if runtime.GOOS != "windows" || len(p) < maxPathLen || filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return p
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// isRelativeInput reports whether p may be looked up under ./xls: drive
+// letters, UNC shares (\\server\share) and device paths (\\?\) name a place of
+// their own, and a missing share must not quietly be replaced by a local file
+// of the same name.
+func isRelativeInput(p string) bool {
+	if filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return false
+	}
+	return !strings.HasPrefix(p, `\\`) && !strings.HasPrefix(p, "//")
+}
+
+// pathMatches reports whether a config pattern (owners, passwords) matches
+// file, by its slash-separated path or its base name. Both are compared in
+// NFC: macOS lists decomposed names (NFD), while patterns are typed composed.
+func pathMatches(pattern, file string) bool {
+	pattern = norm.NFC.String(pattern)
+	file = norm.NFC.String(file)
+	if ok, _ := filepath.Match(pattern, filepath.ToSlash(file)); ok {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, filepath.Base(file))
+	return ok
+}
+
+// readInputManifest resolves --in @path: one workbook, directory, glob or
+// connector folder per line, relative paths taken from the manifest's
+// directory. Blank lines and lines starting with # are skipped. Inputs listed
+// twice (say, by a glob and by name) are read once, where first listed.
+func readInputManifest(path string, cfg *Config) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	var out []string
+	seen := make(map[string]bool)
+	add := func(paths ...string) {
+		for _, p := range paths {
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		entry := cleanInputPath(sc.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if scheme, folder, ok := remoteInput(entry); ok {
+			paths, err := syncRemoteFolder(scheme, folder, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			add(paths...)
+			continue
+		}
+		if isRelativeInput(entry) {
+			entry = filepath.Join(dir, entry)
+		}
+		entry = longPath(filepath.Clean(entry))
+		matches := []string{entry}
+		if strings.ContainsAny(entry, "*?[") {
+			if matches, err = filepath.Glob(entry); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s:%d: %s matches no files", path, line, entry)
+			}
+		}
+		for _, m := range matches {
+			st, err := os.Stat(m)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: input file not found: %s", path, line, m)
+			}
+			if st.IsDir() && !strings.EqualFold(filepath.Ext(m), ".numbers") {
+				paths, err := listExcelFiles(m)
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+				}
+				add(paths...)
+				continue
+			}
+			add(m)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s: no inputs listed", path)
+	}
+	return out, nil
+}
@@ -0,0 +1,164 @@
+package genxls
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// onOS reports whether a case for os ("", "windows" or "unix") applies here.
+func onOS(os string) bool {
+	switch os {
+	case "windows":
+		return runtime.GOOS == "windows"
+	case "unix":
+		return runtime.GOOS != "windows"
+	}
+	return true
+}
+
+func TestCleanInputPath(t *testing.T) {
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{"plain", "xls/Item.xlsx", "xls/Item.xlsx"},
+		{"ascii blanks", " \tItem.xlsx\r\n", "Item.xlsx"},
+		{"explorer quotes", `"C:\Data\Item.xlsx"`, `C:\Data\Item.xlsx`},
+		{"blanks around quotes", `  "C:\Data\Item.xlsx" `, `C:\Data\Item.xlsx`},
+		{"blanks inside quotes", `" C:\Data\Item.xlsx "`, `C:\Data\Item.xlsx`},
+		{"unc", `"\\server\share\Item.xlsx"`, `\\server\share\Item.xlsx`},
+		{"long-path prefix", `\\?\C:\Data\Item.xlsx`, `\\?\C:\Data\Item.xlsx`},
+		{"trailing full-width space", "道具\u3000.xlsx\u3000", "道具\u3000.xlsx\u3000"},
+		{"leading full-width space in quotes", "\"\u3000道具.xlsx\"", "\u3000道具.xlsx"},
+		{"lone quote", `"Item.xlsx`, `"Item.xlsx`},
+		{"only blanks", " \t ", ""},
+	} {
+		if got := cleanInputPath(tc.in); got != tc.want {
+			t.Errorf("%s: cleanInputPath(%q) = %q, want %q", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	long := strings.Repeat("dir/", maxPathLen/4) + "Item.xlsx"
+	abs, err := filepath.Abs(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name, os, in, want string
+	}{
+		{"short relative", "", "xls/Item.xlsx", "xls/Item.xlsx"},
+		{"long relative", "windows", long, abs},
+		{"long relative", "unix", long, long},
+		{"long absolute", "", abs, abs},
+		{"long-path prefix", "", `\\?\C:\` + strings.Repeat(`dir\`, maxPathLen/4) + "Item.xlsx", `\\?\C:\` + strings.Repeat(`dir\`, maxPathLen/4) + "Item.xlsx"},
+		{"long unc with long-path prefix", "", `\\?\UNC\server\share\` + strings.Repeat(`dir\`, maxPathLen/4), `\\?\UNC\server\share\` + strings.Repeat(`dir\`, maxPathLen/4)},
+	} {
+		if !onOS(tc.os) {
+			continue
+		}
+		if got := longPath(tc.in); got != tc.want {
+			t.Errorf("%s: longPath(%.40q...) = %.40q..., want %.40q...", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestIsRelativeInput(t *testing.T) {
+	for _, tc := range []struct {
+		name, os, in string
+		want         bool
+	}{
+		{"file name", "", "Item.xlsx", true},
+		{"relative path", "", "sub/Item.xlsx", true},
+		{"full-width name", "", "道具\u3000.xlsx", true},
+		{"unc", "", `\\server\share\Item.xlsx`, false},
+		{"unc with slashes", "", "//server/share/Item.xlsx", false},
+		{"long-path prefix", "", `\\?\C:\Data\Item.xlsx`, false},
+		{"long unc", "", `\\?\UNC\server\share\Item.xlsx`, false},
+		{"unix absolute", "unix", "/data/Item.xlsx", false},
+		{"drive letter", "windows", `C:\Data\Item.xlsx`, false},
+		{"drive-relative", "windows", `C:Item.xlsx`, false},
+	} {
+		if !onOS(tc.os) {
+			continue
+		}
+		if got := isRelativeInput(tc.in); got != tc.want {
+			t.Errorf("%s: isRelativeInput(%q) = %v, want %v", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	const composed, decomposed = "caf\u00e9", "cafe\u0301"
+	for _, tc := range []struct {
+		name, os, pattern, file string
+		want                    bool
+	}{
+		{"base name", "", "Item.xlsx", "xls/sub/Item.xlsx", true},
+		{"path glob", "", "xls/*/Item.xlsx", "xls/sub/Item.xlsx", true},
+		{"glob misses", "", "xls/*.xlsx", "xls/sub/Item.xlsx", false},
+		{"nfd file, nfc pattern", "", composed + "*.xlsx", "xls/" + decomposed + ".xlsx", true},
+		{"nfc file, nfd pattern", "", decomposed + ".xlsx", composed + ".xlsx", true},
+		{"full-width space kept", "", "道具\u3000.xlsx", "xls/道具\u3000.xlsx", true},
+		{"full-width space not ascii", "", "道具 .xlsx", "xls/道具\u3000.xlsx", false},
+		{"backslash path", "windows", "xls/*/Item.xlsx", `xls\sub\Item.xlsx`, true},
+	} {
+		if !onOS(tc.os) {
+			continue
+		}
+		if got := pathMatches(tc.pattern, tc.file); got != tc.want {
+			t.Errorf("%s: pathMatches(%q, %q) = %v, want %v", tc.name, tc.pattern, tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestResolveInputPathsFallback(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "xls"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "xls", "Item.xlsx"), []byte("id#int\n1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	for _, tc := range []struct {
+		name, in, want, err string
+	}{
+		{"file name falls back to ./xls", "Item.xlsx", filepath.Join(dir, "xls", "Item.xlsx"), ""},
+		{"quoted file name", ` "Item.xlsx" `, filepath.Join(dir, "xls", "Item.xlsx"), ""},
+		{"absolute path doesn't", filepath.Join(dir, "missing", "Item.xlsx"), "", "input file not found"},
+		{"unc path doesn't", `\\server\share\Item.xlsx`, "", "input file not found"},
+	} {
+		got, err := ResolveInputPaths(tc.in, nil)
+		switch {
+		case tc.err != "":
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: ResolveInputPaths(%q) = %v, %v; want error %q", tc.name, tc.in, got, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%s: ResolveInputPaths(%q): %v", tc.name, tc.in, err)
+		case len(got) != 1 || !sameFile(got[0], tc.want):
+			t.Errorf("%s: ResolveInputPaths(%q) = %v, want [%s]", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func sameFile(a, b string) bool {
+	sa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	sb, err := os.Stat(b)
+	return err == nil && os.SameFile(sa, sb)
+}
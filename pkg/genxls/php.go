@@ -0,0 +1,85 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GeneratePHPBundle renders the payload as a PHP file returning a nested
+// array, suitable for `$cfg = require 'php.gen.php';`.
+func GeneratePHPBundle(sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	b.WriteString("<?php\n\nreturn [\n")
+	for _, sheet := range sheets {
+		b.WriteString("    ")
+		b.WriteString(phpString(sheet.JSONKey))
+		b.WriteString(" => [\n")
+		for _, item := range sheet.Items {
+			b.WriteString("        [")
+			for i, f := range sheet.Fields {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				v, err := phpValue(item[f.RawName])
+				if err != nil {
+					return "", fmt.Errorf("%s.%s: %w", sheet.TypeName, f.RawName, err)
+				}
+				b.WriteString(phpString(f.RawName))
+				b.WriteString(" => ")
+				b.WriteString(v)
+			}
+			b.WriteString("],\n")
+		}
+		b.WriteString("    ],\n")
+	}
+	b.WriteString("];\n")
+	return b.String(), nil
+}
+
+func phpValue(v any) (string, error) {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x), nil
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return "NAN", nil
+		case math.IsInf(x, 1):
+			return "INF", nil
+		case math.IsInf(x, -1):
+			return "-INF", nil
+		}
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case string:
+		return phpString(x), nil
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case [][]int:
+		parts := make([]string, len(x))
+		for i, inner := range x {
+			parts[i], _ = phpValue(inner)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// phpString quotes s as a single-quoted PHP literal, where only \ and ' need escaping.
+func phpString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
@@ -0,0 +1,129 @@
+package genxls
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PivotConfig turns a long attribute table (one row per key, attribute and
+// value) into one wide row per key with a typed column per attribute.
+type PivotConfig struct {
+	Key       string `json:"key"`       // column the rows are grouped by, the new primary key
+	Attribute string `json:"attribute"` // column naming the attribute of a row
+	Value     string `json:"value"`     // column holding its value
+	// Columns are the attributes as define-row cells, e.g. ["hp#int",
+	// "speed#float"], in output order. An attribute a key lacks gets its zero
+	// value.
+	Columns []string `json:"columns"`
+}
+
+// pivotSheet replaces the rows of sheet with one row per key, in order of first
+// appearance. The key comes first, then the columns besides the attribute and
+// value ones, which must agree within a key, then the pivoted columns.
+func pivotSheet(sheet *Sheet, p *PivotConfig) error {
+	var known []string
+	for _, f := range sheet.Fields {
+		known = append(known, f.RawName)
+	}
+	var cols [3]*Field
+	for i, name := range []string{p.Key, p.Attribute, p.Value} {
+		if cols[i] = findField(sheet.Fields, name); cols[i] == nil {
+			return fmt.Errorf("pivot: unknown column %q%s", name, didYouMean(name, known))
+		}
+	}
+	key, attr, value := *cols[0], *cols[1], *cols[2]
+	if key.RawName == attr.RawName || key.RawName == value.RawName || attr.RawName == value.RawName {
+		return fmt.Errorf("pivot: key, attribute and value must be different columns")
+	}
+	if !isScalarType(value.RawType) {
+		return fmt.Errorf("pivot: value column %q is %s, not a scalar", value.RawName, value.RawType)
+	}
+
+	var pivoted []Field
+	var names []string
+	for _, c := range p.Columns {
+		m := fieldRe.FindStringSubmatch(c)
+		if m == nil {
+			return fmt.Errorf("pivot: invalid column %q (expect e.g. hp#int)", c)
+		}
+		goType, ok := mapGoType(m[2])
+		if !ok {
+			return fmt.Errorf("pivot: column %q: unsupported type %q%s", m[1], m[2], didYouMean(m[2], SupportedTypes))
+		}
+		if findField(sheet.Fields, m[1]) != nil || findField(pivoted, m[1]) != nil {
+			return fmt.Errorf("pivot: duplicate column %q", m[1])
+		}
+		pivoted = append(pivoted, Field{
+			RawName:  m[1],
+			Name:     exportName(m[1]),
+			RawType:  m[2],
+			GoType:   goType,
+			Col:      -1, // not in the workbook
+			Flag:     FieldFlagAll,
+			Exported: true,
+		})
+		names = append(names, m[1])
+	}
+	if len(pivoted) == 0 {
+		return fmt.Errorf("pivot: no columns")
+	}
+
+	fields := []Field{key}
+	for _, f := range sheet.Fields {
+		if f.RawName != key.RawName && f.RawName != attr.RawName && f.RawName != value.RawName {
+			fields = append(fields, f)
+		}
+	}
+
+	var items []map[string]any
+	var rows []int
+	firstRow := make(map[string]int) // key -> data row, 0-based
+	byKey := make(map[string]map[string]any)
+	for i, item := range sheet.Items {
+		k := fmt.Sprint(item[key.RawName])
+		wide, ok := byKey[k]
+		if !ok {
+			wide = make(map[string]any, len(fields)+len(pivoted))
+			for _, f := range fields {
+				wide[f.RawName] = item[f.RawName]
+			}
+			byKey[k], firstRow[k] = wide, i
+			items, rows = append(items, wide), append(rows, sheet.Rows[i])
+		} else {
+			for _, f := range fields[1:] {
+				if !reflect.DeepEqual(wide[f.RawName], item[f.RawName]) {
+					return fmt.Errorf("pivot: data row %d: %s differs from data row %d of %s %s", i+1, f.RawName, firstRow[k]+1, key.RawName, k)
+				}
+			}
+		}
+
+		name := fmt.Sprint(item[attr.RawName])
+		f := findField(pivoted, name)
+		if f == nil {
+			return fmt.Errorf("pivot: data row %d: unknown attribute %q%s", i+1, name, didYouMean(name, names))
+		}
+		if _, dup := wide[f.RawName]; dup {
+			return fmt.Errorf("pivot: data row %d: %s %s has %s twice", i+1, key.RawName, k, f.RawName)
+		}
+		text, ok := item[value.RawName].(string)
+		if !ok {
+			text = fmt.Sprint(item[value.RawName])
+		}
+		v, err := parseCellValue(f.RawType, text)
+		if err != nil {
+			return fmt.Errorf("pivot: data row %d: %s %q: %w", i+1, f.RawName, text, err)
+		}
+		wide[f.RawName] = v
+	}
+	for _, wide := range items {
+		for _, f := range pivoted {
+			if _, ok := wide[f.RawName]; !ok {
+				wide[f.RawName], _ = parseCellValue(f.RawType, "")
+			}
+		}
+	}
+
+	sheet.Fields = append(fields, pivoted...)
+	sheet.Items, sheet.Rows = items, rows
+	return nil
+}
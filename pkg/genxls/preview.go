@@ -0,0 +1,17 @@
+package genxls
+
+import (
+	"strings"
+)
+
+// FindSheet matches name against sheet, type and JSON key names, ignoring case.
+func FindSheet(sheets []*Sheet, name string) *Sheet {
+	for _, sheet := range sheets {
+		for _, n := range []string{sheet.Name, sheet.TypeName, sheet.FieldName, sheet.JSONKey} {
+			if strings.EqualFold(n, name) {
+				return sheet
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package genxls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Protobuf wire types.
+const (
+	pbVarint  = 0
+	pbFixed64 = 1
+	pbBytes   = 2
+	pbFixed32 = 5
+)
+
+// encodeProtoPayload serializes the rows as the root message of
+// GenerateProtoSchema (--pb-data), with the same field numbers. As proto3
+// does, zero scalars and empty lists are left out; int[] columns are packed.
+func encodeProtoPayload(sheets []*Sheet) ([]byte, error) {
+	var out, row []byte
+	for si, sheet := range sheets {
+		for i, item := range sheet.Items {
+			row = row[:0]
+			for j, f := range sheet.Fields {
+				var err error
+				if row, err = appendProtoField(row, protoNumber(f.ProtoField, j), f, item[f.RawName]); err != nil {
+					return nil, fmt.Errorf("%s row %d (%s): %w", sheet.TypeName, i+1, f.RawName, err)
+				}
+			}
+			out = appendProtoBytes(out, protoNumber(sheet.ProtoField, si), row)
+		}
+	}
+	return out, nil
+}
+
+func appendProtoField(b []byte, num int, f Field, v any) ([]byte, error) {
+	switch x := v.(type) {
+	case int:
+		if x != 0 {
+			b = appendProtoTag(b, num, pbVarint)
+			b = binary.AppendUvarint(b, uint64(int64(x))) // negative int32 and int64 alike take ten bytes
+		}
+	case float64:
+		if x == 0 && !math.Signbit(x) {
+			break
+		}
+		if strings.ToLower(f.RawType) == "float32" {
+			b = appendProtoTag(b, num, pbFixed32)
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(x)))
+		} else {
+			b = appendProtoTag(b, num, pbFixed64)
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+		}
+	case bool:
+		if x {
+			b = appendProtoTag(b, num, pbVarint)
+			b = append(b, 1)
+		}
+	case string:
+		if x != "" {
+			b = appendProtoBytes(b, num, []byte(x))
+		}
+	case []int:
+		if len(x) > 0 {
+			b = appendProtoBytes(b, num, appendPackedInts(nil, x))
+		}
+	case [][]int:
+		// Every IntList is kept, empty ones too, so inner lists keep their positions.
+		for _, inner := range x {
+			var list []byte
+			if len(inner) > 0 {
+				list = appendProtoBytes(nil, 1, appendPackedInts(nil, inner))
+			}
+			b = appendProtoBytes(b, num, list)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+	return b, nil
+}
+
+func appendProtoTag(b []byte, num, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(wireType))
+}
+
+func appendProtoBytes(b []byte, num int, data []byte) []byte {
+	b = appendProtoTag(b, num, pbBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendPackedInts(b []byte, xs []int) []byte {
+	for _, x := range xs {
+		b = binary.AppendUvarint(b, uint64(int64(x)))
+	}
+	return b
+}
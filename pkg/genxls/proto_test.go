@@ -0,0 +1,175 @@
+package genxls
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func protoTestSheet(typeName, jsonKey string, columns ...string) *Sheet {
+	sheet := &Sheet{Origin: typeName + ".xlsx[" + typeName + "]", Name: typeName, TypeName: typeName, JSONKey: jsonKey}
+	for _, c := range columns {
+		name, typ, _ := strings.Cut(c, "#")
+		sheet.Fields = append(sheet.Fields, Field{RawName: name, RawType: typ})
+	}
+	return sheet
+}
+
+func TestProtoNumbersPinnedByLock(t *testing.T) {
+	// The first lock pins the positions.
+	lock := updateLock(nil, "all", []*Sheet{
+		protoTestSheet("Item", "items", "id#int", "name#string"),
+		protoTestSheet("Quest", "quests", "id#int"),
+	}, false, true)
+
+	// Armor comes first now, quests is gone, item columns moved and one was
+	// added in front.
+	sheets := []*Sheet{
+		protoTestSheet("Armor", "armors", "id#int"),
+		protoTestSheet("Item", "items", "cost#float", "name#string", "id#int"),
+	}
+	if problems := checkLock(lock, "all", sheets, false, true); len(problems) != 3 {
+		t.Errorf("got problems %v, want a new, a changed and a removed sheet", problems)
+	}
+	lock = updateLock(lock, "all", sheets, false, true)
+	if problems := checkLock(lock, "all", sheets, false, true); len(problems) > 0 {
+		t.Errorf("updated lock: got problems %v", problems)
+	}
+	if e := lock["all"]["quests"]; !e.Removed || e.Proto == nil || e.Proto.Field != 2 {
+		t.Errorf("removed sheet: got %+v, want its proto number kept", e)
+	}
+
+	applyProtoNumbers(lock["all"], sheets)
+	schema, err := GenerateProtoSchema("cfg", "AllConfig", sheets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  repeated Armor armors = 3;\n", // 2 was quests
+		"  repeated Item items = 1;\n",
+		"  double cost = 3;\n",
+		"  string name = 2;\n",
+		"  int64 id = 1;\n",
+	} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("schema lacks %q:\n%s", want, schema)
+		}
+	}
+
+	// The payload uses the same numbers: items (1) holding id (1) = 7.
+	sheets[1].Items = []map[string]any{{"cost": 0.0, "name": "", "id": 7}}
+	data, err := encodeProtoPayload(sheets[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "\x0a\x02\x08\x07"; got != want {
+		t.Errorf("payload: got % x, want % x", got, want)
+	}
+}
+
+func TestProtoLockNeedsNumbers(t *testing.T) {
+	sheets := []*Sheet{protoTestSheet("Item", "items", "id#int")}
+	lock := updateLock(nil, "all", sheets, false, false)
+	if problems := checkLock(lock, "all", sheets, false, false); len(problems) > 0 {
+		t.Errorf("without proto: got problems %v", problems)
+	}
+	problems := checkLock(lock, "all", sheets, false, true)
+	if len(problems) != 1 || !strings.Contains(problems[0].String(), "proto field numbers") {
+		t.Errorf("with proto: got problems %v, want the numbers missing", problems)
+	}
+}
+
+func TestProtoSchemaInvalidSheetKey(t *testing.T) {
+	_, err := GenerateProtoSchema("cfg", "AllConfig", []*Sheet{protoTestSheet("Item", "boss-items", "id#int")})
+	if err == nil || !strings.Contains(err.Error(), `sheet key "boss-items"`) {
+		t.Errorf("got %v, want an invalid sheet key error", err)
+	}
+}
+
+func TestProtoPayloadGolden(t *testing.T) {
+	data, err := encodeProtoPayload([]*Sheet{wireTestSheet()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// `buf convert` decodes these bytes to the same rows.
+	golden := "\n\xa0\x01\b\xfe\xff\xff\xff\xff\xff\xff\xff\xff\x01\x12\x82\x01" + strings.Repeat("x", 130) +
+		"\"\x00\"\f\n\n\xfd\xff\xff\xff\xff\xff\xff\xff\xff\x01\n\x0f\b\x03\x1a\v\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01\a"
+	if string(data) != golden {
+		t.Errorf("got %q\nwant %q", data, golden)
+	}
+
+	// Decode it again as AllConfig { repeated Item items = 1; } with
+	// Item { int64 id = 1; string name = 2; repeated int64 tags = 3;
+	// repeated IntList costs = 4; }.
+	var got []map[string]any
+	for _, item := range protoTestFields(t, data)[1] {
+		row := map[string]any{"id": 0, "name": "", "tags": []int{}, "costs": [][]int{}}
+		fields := protoTestFields(t, item.([]byte))
+		for _, v := range fields[1] {
+			row["id"] = int(int64(v.(uint64)))
+		}
+		for _, v := range fields[2] {
+			row["name"] = string(v.([]byte))
+		}
+		for _, v := range fields[3] {
+			row["tags"] = protoTestPacked(t, v.([]byte))
+		}
+		for _, v := range fields[4] {
+			inner := []int{}
+			for _, values := range protoTestFields(t, v.([]byte))[1] {
+				inner = protoTestPacked(t, values.([]byte))
+			}
+			row["costs"] = append(row["costs"].([][]int), inner)
+		}
+		got = append(got, row)
+	}
+	if want := wireTestSheet().Items; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %v\nwant %v", got, want)
+	}
+}
+
+// protoTestFields splits a message into its fields by number: uint64 for
+// varints, []byte for length-delimited ones.
+func protoTestFields(t *testing.T, b []byte) map[int][]any {
+	t.Helper()
+	fields := make(map[int][]any)
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad tag at % x", b)
+		}
+		b = b[n:]
+		num := int(key >> 3)
+		switch key & 7 {
+		case pbVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("field %d: bad varint", num)
+			}
+			fields[num], b = append(fields[num], v), b[n:]
+		case pbBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				t.Fatalf("field %d: bad length", num)
+			}
+			fields[num], b = append(fields[num], b[n:n+int(size)]), b[n+int(size):]
+		default:
+			t.Fatalf("field %d: unexpected wire type %d", num, key&7)
+		}
+	}
+	return fields
+}
+
+func protoTestPacked(t *testing.T, b []byte) []int {
+	t.Helper()
+	out := []int{}
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad packed varint at % x", b)
+		}
+		out, b = append(out, int(int64(v))), b[n:]
+	}
+	return out
+}
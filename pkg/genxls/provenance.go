@@ -0,0 +1,26 @@
+package genxls
+
+import (
+	"encoding/json"
+)
+
+// SheetProvenance maps the rows of one payload array back to their source:
+// Rows[i] is the sheet row number of item i.
+type SheetProvenance struct {
+	File  string `json:"file"`
+	Sheet string `json:"sheet"`
+	Rows  []int  `json:"rows"`
+}
+
+// writeProvenance writes provenance.json, keyed like the payload.
+func writeProvenance(out *OutputLayout, sheets []*Sheet) (string, error) {
+	prov := make(map[string]SheetProvenance, len(sheets))
+	for _, sheet := range sheets {
+		prov[sheet.JSONKey] = SheetProvenance{File: sheet.File, Sheet: sheet.Name, Rows: sheet.Rows}
+	}
+	data, err := json.MarshalIndent(prov, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return out.WriteFile("provenance", "provenance.json", nil, data)
+}
@@ -0,0 +1,64 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// GenerateRedisBundle renders a Redis protocol (RESP) stream for
+// `redis-cli --pipe`. Every row becomes a hash at <jsonKey>:<primaryKey>, where
+// the primary key is the first exported column. Each hash is deleted before it
+// is written so columns removed from the sheet don't linger.
+func GenerateRedisBundle(sheets []*Sheet) ([]byte, error) {
+	var b bytes.Buffer
+	for _, sheet := range sheets {
+		fields := sheet.Fields
+		pkField := fields[0]
+		for i, item := range sheet.Items {
+			pk, err := redisValue(item[pkField.RawName])
+			if err != nil {
+				return nil, fmt.Errorf("%s row %d: %w", sheet.TypeName, i+1, err)
+			}
+			key := sheet.JSONKey + ":" + pk
+			writeRESPCommand(&b, "DEL", key)
+
+			args := []string{"HSET", key}
+			for _, f := range fields {
+				v, err := redisValue(item[f.RawName])
+				if err != nil {
+					return nil, fmt.Errorf("%s row %d (%s): %w", sheet.TypeName, i+1, f.RawName, err)
+				}
+				args = append(args, f.RawName, v)
+			}
+			writeRESPCommand(&b, args...)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// redisValue stores strings verbatim and everything else as JSON.
+func redisValue(v any) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func writeRESPCommand(b *bytes.Buffer, args ...string) {
+	b.WriteString("*")
+	b.WriteString(strconv.Itoa(len(args)))
+	b.WriteString("\r\n")
+	for _, a := range args {
+		b.WriteString("$")
+		b.WriteString(strconv.Itoa(len(a)))
+		b.WriteString("\r\n")
+		b.WriteString(a)
+		b.WriteString("\r\n")
+	}
+}
@@ -0,0 +1,115 @@
+package genxls
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRedisBundleGolden(t *testing.T) {
+	data, err := GenerateRedisBundle([]*Sheet{wireTestSheet()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := "*2\r\n$3\r\nDEL\r\n$8\r\nitems:-2\r\n" +
+		"*10\r\n$4\r\nHSET\r\n$8\r\nitems:-2\r\n$2\r\nid\r\n$2\r\n-2\r\n$4\r\nname\r\n$130\r\n" + strings.Repeat("x", 130) + "\r\n" +
+		"$4\r\ntags\r\n$2\r\n[]\r\n$5\r\ncosts\r\n$9\r\n[[],[-3]]\r\n" +
+		"*2\r\n$3\r\nDEL\r\n$7\r\nitems:3\r\n" +
+		"*10\r\n$4\r\nHSET\r\n$7\r\nitems:3\r\n$2\r\nid\r\n$1\r\n3\r\n$4\r\nname\r\n$0\r\n\r\n" +
+		"$4\r\ntags\r\n$6\r\n[-1,7]\r\n$5\r\ncosts\r\n$2\r\n[]\r\n"
+	if string(data) != golden {
+		t.Errorf("got %q\nwant %q", data, golden)
+	}
+
+	// Replay the commands into hashes and read the rows back from them.
+	hashes := make(map[string]map[string]string)
+	var keys []string
+	r := bufio.NewReader(strings.NewReader(string(data)))
+	for {
+		cmd, err := readRESPTestCommand(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case cmd[0] == "DEL" && len(cmd) == 2:
+			delete(hashes, cmd[1])
+		case cmd[0] == "HSET" && len(cmd)%2 == 0:
+			if hashes[cmd[1]] == nil {
+				hashes[cmd[1]] = make(map[string]string)
+				keys = append(keys, cmd[1])
+			}
+			for i := 2; i < len(cmd); i += 2 {
+				hashes[cmd[1]][cmd[i]] = cmd[i+1]
+			}
+		default:
+			t.Fatalf("unexpected command %q", cmd)
+		}
+	}
+	var got []map[string]any
+	for _, key := range keys {
+		h := hashes[key]
+		id, err := strconv.Atoi(h["id"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "items:"+h["id"] {
+			t.Errorf("hash %s holds id %s", key, h["id"])
+		}
+		row := map[string]any{"id": id, "name": h["name"]}
+		var tags []int
+		var costs [][]int
+		if err := json.Unmarshal([]byte(h["tags"]), &tags); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(h["costs"]), &costs); err != nil {
+			t.Fatal(err)
+		}
+		row["tags"], row["costs"] = tags, costs
+		got = append(got, row)
+	}
+	if want := wireTestSheet().Items; !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %v\nwant %v", got, want)
+	}
+}
+
+// readRESPTestCommand reads one command, an array of bulk strings, as
+// `redis-cli --pipe` sends it.
+func readRESPTestCommand(r *bufio.Reader) ([]string, error) {
+	line := func(prefix byte) (int, error) {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		if len(s) < 3 || s[0] != prefix || !strings.HasSuffix(s, "\r\n") {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return strconv.Atoi(s[1 : len(s)-2])
+	}
+	n, err := line('*')
+	if err != nil {
+		return nil, err
+	}
+	cmd := make([]string, n)
+	for i := range cmd {
+		size, err := line('$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if string(buf[size:]) != "\r\n" {
+			return nil, io.ErrUnexpectedEOF
+		}
+		cmd[i] = string(buf[:size])
+	}
+	return cmd, nil
+}
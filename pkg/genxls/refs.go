@@ -0,0 +1,213 @@
+package genxls
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// FieldRef is the target of a ref column (rewardId#ref:Item.cid): every
+// non-empty value must be a value of that column. The column takes its type.
+type FieldRef struct {
+	Sheet  string // matched like findSheet: sheet, type, field or JSON key name
+	Column string // "" for the target's first column
+	Row    int    // define row the ref was written in, for errors
+}
+
+func (r *FieldRef) String() string {
+	if r.Column == "" {
+		return "ref:" + r.Sheet
+	}
+	return "ref:" + r.Sheet + "." + r.Column
+}
+
+// parseFieldRef parses the type of a ref column, "ref:Sheet.column" or
+// "ref:Sheet"; ok is false for other types.
+func parseFieldRef(rawType string, defineRow int) (ref *FieldRef, ok bool, err error) {
+	if len(rawType) < 4 || !strings.EqualFold(rawType[:4], "ref:") {
+		return nil, false, nil
+	}
+	sheet, column, _ := strings.Cut(rawType[4:], ".")
+	if sheet == "" || strings.Contains(column, ".") {
+		return nil, true, fmt.Errorf("invalid reference %q (expect ref:Sheet.column)", rawType)
+	}
+	return &FieldRef{Sheet: sheet, Column: column, Row: defineRow}, true, nil
+}
+
+// refTarget returns the column ref points at in target, or nil.
+func refTarget(target *Sheet, ref *FieldRef) *Field {
+	if ref.Column == "" {
+		if len(target.Fields) == 0 {
+			return nil
+		}
+		return &target.Fields[0]
+	}
+	return findField(target.Fields, ref.Column)
+}
+
+// resolveRefs gives every ref column the type of its target column and parses
+// its cells, read as text until now, as that type. skipped are the sheets
+// --only left out: their columns still type the refs into them.
+func resolveRefs(sheets, skipped []*Sheet, opts Options, log *slog.Logger) error {
+	all := slices.Concat(sheets, skipped)
+	const resolving, resolved = 1, 2
+	state := make(map[*Field]int)
+	var resolve func(sheet *Sheet, f *Field, path []string) error
+	resolve = func(sheet *Sheet, f *Field, path []string) error {
+		path = append(path, sheet.TypeName+"."+f.RawName)
+		schemaErr := func(err error) error {
+			return &SchemaError{Origin: sheet.Origin, Sheet: sheet.Name, Row: f.Ref.Row, Err: fmt.Errorf("column %s (%s): %w", f.RawName, f.Ref, err)}
+		}
+		switch state[f] {
+		case resolved:
+			return nil
+		case resolving:
+			return schemaErr(fmt.Errorf("reference cycle %s", strings.Join(path, " -> ")))
+		}
+		state[f] = resolving
+		target := FindSheet(all, f.Ref.Sheet)
+		if target == nil {
+			return schemaErr(fmt.Errorf("no sheet %q%s", f.Ref.Sheet, didYouMean(f.Ref.Sheet, sheetNames(all))))
+		}
+		tf := refTarget(target, f.Ref)
+		if tf == nil {
+			return schemaErr(fmt.Errorf("%s has no column %q%s", target.Origin, f.Ref.Column, didYouMean(f.Ref.Column, fieldNames(target.Fields))))
+		}
+		if tf.Ref != nil {
+			if err := resolve(target, tf, path); err != nil {
+				return err
+			}
+		}
+		if !isIntType(tf.RawType) && strings.ToLower(tf.RawType) != "string" {
+			return schemaErr(fmt.Errorf("%s.%s is %s; references need an int or string column", target.TypeName, tf.RawName, tf.RawType))
+		}
+		f.RawType, f.GoType, f.JSONString, f.Coerce = tf.RawType, tf.GoType, tf.JSONString, tf.Coerce
+		state[f] = resolved
+		return nil
+	}
+	for _, sheet := range all {
+		for i := range sheet.Fields {
+			if f := &sheet.Fields[i]; f.Ref != nil {
+				if err := resolve(sheet, f, nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, sheet := range sheets {
+		for _, f := range sheet.Fields {
+			if f.Ref == nil {
+				continue
+			}
+			for i, item := range sheet.Items {
+				s, _ := item[f.RawName].(string)
+				v, err := parseFieldValue(f, s)
+				if err != nil {
+					e := &CellError{Sheet: sheet.Name, Field: f.RawName, Err: err}
+					e.Row, e.Col = sheet.cellOf(i, f)
+					if !opts.Lenient && opts.Annotate == "" {
+						return fmt.Errorf("%s: %w%s", sheet.Origin, e, annotation(sheet.Owner, sheet.ModifiedBy))
+					}
+					if opts.Lenient {
+						log.Warn(fmt.Sprintf("%s: %v, using zero value%s", sheet.Origin, e, annotation(sheet.Owner, sheet.ModifiedBy)), "sheet", sheet.Name, "row", e.Row, "col", e.Col)
+					}
+					sheet.BadCells = append(sheet.BadCells, e)
+					v, _ = parseCellValue(f.RawType, "")
+				}
+				item[f.RawName] = v
+			}
+		}
+	}
+	return nil
+}
+
+// checkRefs reports ref cells whose value is not in the target column. Empty
+// cells (0 for int columns) refer to nothing and pass. Targets not loaded
+// (--only) are not checked.
+func checkRefs(sheets []*Sheet) []Problem {
+	var problems []Problem
+	values := make(map[*Field]map[any]bool)
+	for _, sheet := range sheets {
+		for _, f := range sheet.Fields {
+			if f.Ref == nil {
+				continue
+			}
+			target := FindSheet(sheets, f.Ref.Sheet)
+			if target == nil {
+				continue
+			}
+			tf := refTarget(target, f.Ref)
+			if tf == nil {
+				continue
+			}
+			set, ok := values[tf]
+			if !ok {
+				set = make(map[any]bool, len(target.Items))
+				for _, item := range target.Items {
+					set[item[tf.RawName]] = true
+				}
+				values[tf] = set
+			}
+			for i, item := range sheet.Items {
+				v := item[f.RawName]
+				if v == 0 || v == "" || set[v] {
+					continue
+				}
+				row, col := sheet.cellOf(i, f)
+				p := problemf(sheet, "%s: row %d col %d (%s): %v not found in %s.%s", sheet.Origin, row, col, f.RawName, v, target.TypeName, tf.RawName)
+				p.Row, p.Col = row, col
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems
+}
+
+func sheetNames(sheets []*Sheet) []string {
+	names := make([]string, len(sheets))
+	for i, sheet := range sheets {
+		names[i] = sheet.Name
+	}
+	return names
+}
+
+func fieldNames(fields []Field) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.RawName
+	}
+	return names
+}
+
+// refTargetStub reads just enough of a sheet --only leaves out, its names and
+// columns, for refs into it to be typed. Sheets that can't be read that way
+// are left out as before (nil).
+func refTargetStub(file, origin, sheetName string, rows [][]string, opts Options, cfg *Config) *Sheet {
+	spec, err := detectHeaderSpec(rows)
+	if err != nil {
+		return nil
+	}
+	if spec.Orientation == OrientationVertical {
+		rows, _ = verticalGrid(rows, spec.DefineRow, nil)
+	}
+	fields, err := ParseFieldsFromDefineRow(rows, spec.DefineRow, opts.fieldFlag())
+	if err != nil {
+		return nil
+	}
+	if opts.Int64AsString {
+		for i := range fields {
+			if strings.ToLower(fields[i].RawType) == "int64" {
+				fields[i].setJSONString()
+			}
+		}
+	}
+	base := sheetBaseName(file, sheetName, opts)
+	fieldName := pluralizeTypeName(base)
+	jsonKey := lowerFirst(fieldName)
+	if customKey, _ := cfg.jsonKeysOf(file, sheetName, opts); customKey != "" {
+		jsonKey = customKey
+	}
+	return &Sheet{Origin: origin, Name: sheetName, TypeName: opts.TypePrefix + base + opts.TypeSuffix, FieldName: fieldName, JSONKey: jsonKey, File: file, Fields: fields}
+}
@@ -0,0 +1,180 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SchemaSet is the registry representation of one run's parsed sheets.
+type SchemaSet struct {
+	Sheets []SheetSchema `json:"sheets"`
+}
+
+type SheetSchema struct {
+	Name   string        `json:"name"`
+	Key    string        `json:"key"`
+	Fields []FieldSchema `json:"fields"`
+}
+
+type FieldSchema struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+const schemaSetFileName = "genxls.schema.json"
+
+func buildSchemaSet(sheets []*Sheet) SchemaSet {
+	var set SchemaSet
+	for _, sheet := range sheets {
+		ss := SheetSchema{Name: sheet.TypeName, Key: sheet.JSONKey}
+		for _, f := range sheet.Fields {
+			ss.Fields = append(ss.Fields, FieldSchema{Name: f.RawName, Type: strings.ToLower(f.RawType)})
+		}
+		set.Sheets = append(set.Sheets, ss)
+	}
+	return set
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// publishSchemaSet stores set in a registry. A URL receives an HTTP PUT, a
+// directory gets genxls.schema.json, anything else is treated as the file path.
+func publishSchemaSet(target string, set SchemaSet) (string, error) {
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if isURL(target) {
+		req, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode/100 != 2 {
+			return "", fmt.Errorf("publish schema to %s: %s", target, resp.Status)
+		}
+		return target, nil
+	}
+	outFile := target
+	if st, err := os.Stat(target); err == nil && st.IsDir() {
+		outFile = filepath.Join(target, schemaSetFileName)
+	}
+	if err := os.WriteFile(outFile, data, 0o644); err != nil {
+		return "", err
+	}
+	return outFile, nil
+}
+
+// loadPinnedSchemas reads the schema sets consumers have pinned. source may be
+// a URL, a single schema file or a registry directory of *.json files, one per
+// consumer. The returned map is keyed by origin.
+func loadPinnedSchemas(source string) (map[string]SchemaSet, error) {
+	out := make(map[string]SchemaSet)
+	if isURL(source) {
+		resp, err := http.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("fetch schema from %s: %s", source, resp.Status)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		var set SchemaSet
+		if err := json.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		out[source] = set
+		return out, nil
+	}
+
+	st, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{source}
+	if st.IsDir() {
+		files, err = filepath.Glob(filepath.Join(source, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no schema files in %s", source)
+		}
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var set SchemaSet
+		if err := json.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		out[f] = set
+	}
+	return out, nil
+}
+
+// verifySchemaCompat reports every pinned sheet or field that current no
+// longer provides with the same type. Added sheets and fields are compatible.
+func verifySchemaCompat(current SchemaSet, pinned map[string]SchemaSet) error {
+	sheets := make(map[string]SheetSchema, len(current.Sheets))
+	for _, s := range current.Sheets {
+		sheets[s.Name] = s
+	}
+	origins := make([]string, 0, len(pinned))
+	for origin := range pinned {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+
+	var problems []string
+	for _, origin := range origins {
+		for _, ps := range pinned[origin].Sheets {
+			cs, ok := sheets[ps.Name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: sheet %s was removed", origin, ps.Name))
+				continue
+			}
+			if cs.Key != ps.Key {
+				problems = append(problems, fmt.Sprintf("%s: sheet %s key changed %q -> %q", origin, ps.Name, ps.Key, cs.Key))
+			}
+			fields := make(map[string]string, len(cs.Fields))
+			for _, f := range cs.Fields {
+				fields[f.Name] = f.Type
+			}
+			for _, pf := range ps.Fields {
+				t, ok := fields[pf.Name]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: %s.%s was removed", origin, ps.Name, pf.Name))
+				} else if t != pf.Type {
+					problems = append(problems, fmt.Sprintf("%s: %s.%s type changed %s -> %s", origin, ps.Name, pf.Name, pf.Type, t))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("schema incompatible with pinned consumers:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --in <connector>:<folder> reads the spreadsheets of a folder in an online
+// office suite, e.g. drive:1AbC or feishu:fldcnXyz. The folder is synced into
+// a local cache, downloading only files whose revision changed, and the cached
+// workbooks are the input from there on.
+
+// remoteConnector lists and downloads the spreadsheets of a remote folder.
+type remoteConnector interface {
+	list(folder string) ([]remoteFile, error)
+	download(f remoteFile) ([]byte, error)
+}
+
+// remoteFile is a spreadsheet in a remote folder. Name is the cached file's
+// name, with .xlsx for native documents exported as xlsx; Kind is whatever
+// the connector needs to download it.
+type remoteFile struct {
+	ID       string
+	Name     string
+	Revision string
+	Kind     string
+}
+
+// remoteConnectors are the --in prefixes, each with the constructor of its
+// connector. cfg may be nil.
+var remoteConnectors = map[string]func(cfg *Config) (remoteConnector, error){
+	"drive":   newDriveConnector,
+	"feishu":  func(cfg *Config) (remoteConnector, error) { return newFeishuConnector(cfg, "feishu", feishuAPI) },
+	"lark":    func(cfg *Config) (remoteConnector, error) { return newFeishuConnector(cfg, "lark", larkAPI) },
+	"tencent": newTencentDocsConnector,
+}
+
+func remoteConnectorNames() []string {
+	names := make([]string, 0, len(remoteConnectors))
+	for name := range remoteConnectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// remoteInput splits a --in value naming a remote folder. Drive letters never
+// match, as they are a single character.
+func remoteInput(in string) (scheme, folder string, ok bool) {
+	scheme, folder, ok = strings.Cut(in, ":")
+	if !ok || remoteConnectors[scheme] == nil {
+		return "", "", false
+	}
+	return scheme, folder, true
+}
+
+// connectorSetting returns a connector's setting: the environment variable
+// env, or else key of the connector's "connectors" entry in the config.
+func connectorSetting(cfg *Config, scheme, env, key string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	if cfg == nil {
+		return ""
+	}
+	return cfg.Connectors[scheme][key]
+}
+
+// Requests are spread out and retried so a big folder stays well within the
+// API quotas instead of failing halfway like the old sync jobs.
+const (
+	remoteWorkers = 4
+	remoteRetries = 5
+)
+
+// remoteClient sends rate-limited, retried requests.
+type remoteClient struct {
+	every time.Duration // minimum interval between requests
+	auth  func(req *http.Request)
+	// rateLimited recognizes throttling besides 429; may be nil.
+	rateLimited func(status int, body []byte) bool
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func (c *remoteClient) wait() {
+	c.mu.Lock()
+	now := time.Now()
+	if c.next.Before(now) {
+		c.next = now
+	}
+	d := c.next.Sub(now)
+	c.next = c.next.Add(c.every)
+	c.mu.Unlock()
+	time.Sleep(d)
+}
+
+// do sends a request with an optional JSON body and returns the body of its
+// 200 response.
+func (c *remoteClient) do(method, u string, body any) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		c.wait()
+		req, err := http.NewRequest(method, u, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		}
+		if c.auth != nil {
+			c.auth(req)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return data, nil
+		}
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 ||
+			(c.rateLimited != nil && c.rateLimited(resp.StatusCode, data))
+		if !retry || attempt == remoteRetries {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, u, resp.Status, strings.TrimSpace(string(data)))
+		}
+		time.Sleep(time.Duration(1<<attempt) * time.Second)
+	}
+}
+
+func (c *remoteClient) get(u string) ([]byte, error) {
+	return c.do(http.MethodGet, u, nil)
+}
+
+// pollExport waits for an export job: check reports whether it is done.
+func pollExport(what string, check func() (bool, error)) error {
+	deadline := time.Now().Add(5 * time.Minute)
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s: export timed out", what)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// remoteManifestFile records what a cache holds, per remote file id.
+const remoteManifestFile = ".genxls-remote.json"
+
+type remoteCacheEntry struct {
+	Name     string `json:"name"`
+	Revision string `json:"revision"`
+}
+
+// remoteCacheDir is where the workbooks of a folder are cached:
+// $GENXLS_CACHE/<connector>/<folder>, by default genxls in the user cache
+// directory.
+func remoteCacheDir(scheme, folder string) (string, error) {
+	base := os.Getenv("GENXLS_CACHE")
+	if base == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(dir, "genxls")
+	}
+	return filepath.Join(base, scheme, folder), nil
+}
+
+// syncRemoteFolder brings the cache of a remote folder up to date and returns
+// the cached workbooks.
+func syncRemoteFolder(scheme, folder string, cfg *Config) ([]string, error) {
+	if folder == "" || strings.ContainsAny(folder, `/\`) || folder == "." || folder == ".." {
+		return nil, fmt.Errorf("%s: invalid folder %q", scheme, folder)
+	}
+	c, err := remoteConnectors[scheme](cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", scheme, err)
+	}
+	dir, err := remoteCacheDir(scheme, folder)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	manifestPath := filepath.Join(dir, remoteManifestFile)
+	manifest := make(map[string]remoteCacheEntry)
+	if data, err := os.ReadFile(manifestPath); err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("%s: %w", manifestPath, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	listed, err := c.list(folder)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", scheme, err)
+	}
+	var files []remoteFile
+	names := make(map[string]string) // lower-cased local name -> file id
+	for _, f := range listed {
+		f.Name = strings.NewReplacer("/", "_", `\`, "_").Replace(f.Name)
+		key := strings.ToLower(f.Name)
+		if other, ok := names[key]; ok {
+			return nil, fmt.Errorf("%s: folder %s has two workbooks named %q (%s and %s)", scheme, folder, f.Name, other, f.ID)
+		}
+		names[key] = f.ID
+		files = append(files, f)
+	}
+
+	var stale []remoteFile
+	for _, f := range files {
+		e, ok := manifest[f.ID]
+		if ok && e.Name == f.Name && e.Revision == f.Revision {
+			if _, err := os.Stat(filepath.Join(dir, e.Name)); err == nil {
+				continue
+			}
+		}
+		stale = append(stale, f)
+	}
+	errs := make([]error, len(stale))
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < min(remoteWorkers, len(stale)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f := stale[i]
+				data, err := c.download(f)
+				if err == nil {
+					err = os.WriteFile(filepath.Join(dir, f.Name), data, 0o644)
+				}
+				if err != nil {
+					errs[i] = fmt.Errorf("%s: %s: %w", scheme, f.Name, err)
+				}
+			}
+		}()
+	}
+	for i := range stale {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// Drop what was deleted or renamed remotely since the last sync.
+	current := make(map[string]remoteCacheEntry, len(files))
+	for _, f := range files {
+		current[f.ID] = remoteCacheEntry{Name: f.Name, Revision: f.Revision}
+	}
+	for id, e := range manifest {
+		if cur, ok := current[id]; ok && cur.Name == e.Name {
+			continue
+		}
+		if _, taken := names[strings.ToLower(e.Name)]; !taken {
+			_ = os.Remove(filepath.Join(dir, e.Name))
+		}
+	}
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "%s: folder %s: %d workbooks, %d downloaded\n", scheme, folder, len(files), len(stale))
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, filepath.Join(dir, f.Name))
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s: no spreadsheets in folder %s", scheme, folder)
+	}
+	return paths, nil
+}
+
+// exportedName is the cached name of a native document exported as .xlsx.
+func exportedName(title string) string {
+	if strings.EqualFold(filepath.Ext(title), ".xlsx") {
+		return title
+	}
+	return title + ".xlsx"
+}
+
+// isWorkbookName reports whether an uploaded file is read as a workbook.
+func isWorkbookName(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".xlsx" || ext == ".xlsm"
+}
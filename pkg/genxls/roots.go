@@ -0,0 +1,115 @@
+package genxls
+
+import (
+	"fmt"
+	"go/token"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Root is an extra root type holding a subset of the sheets, written next to
+// AllConfig with its own payload (config "roots"), so a service can parse only
+// the slice of config it uses.
+type Root struct {
+	DataName string // payload name, e.g. battle for battle.json
+	TypeName string // root type, e.g. BattleConfig
+	Sheets   []*Sheet
+}
+
+// configRoots resolves the config's roots against sheets, sorted by name.
+func configRoots(cfg *Config, sheets []*Sheet, rootName, dataName string) ([]Root, error) {
+	names := make([]string, 0, len(cfg.Roots))
+	for name := range cfg.Roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	roots := make([]Root, 0, len(names))
+	for _, name := range names {
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("config: roots: invalid name %q", name)
+		}
+		r := Root{DataName: strings.ToLower(name), TypeName: bundleRootName(name)}
+		if r.DataName == dataName || r.TypeName == rootName {
+			return nil, fmt.Errorf("config: roots: %q clashes with the %s root", name, rootName)
+		}
+		for _, prev := range roots {
+			if prev.DataName == r.DataName {
+				return nil, fmt.Errorf("config: roots: %q is given twice", name)
+			}
+		}
+		for _, s := range cfg.Roots[name] {
+			sheet := FindSheet(sheets, s)
+			if sheet == nil {
+				var known []string
+				for _, s := range sheets {
+					known = append(known, s.Name)
+				}
+				return nil, fmt.Errorf("config: roots: %s: no sheet %q%s", name, s, didYouMean(s, known))
+			}
+			if sheet.TypeName == r.TypeName {
+				return nil, fmt.Errorf("config: roots: %s: root type %s clashes with the sheet's type", name, r.TypeName)
+			}
+			if slices.Contains(r.Sheets, sheet) {
+				return nil, fmt.Errorf("config: roots: %s: sheet %q is listed twice", name, s)
+			}
+			r.Sheets = append(r.Sheets, sheet)
+		}
+		if len(r.Sheets) == 0 {
+			return nil, fmt.Errorf("config: roots: %s: no sheets", name)
+		}
+		roots = append(roots, r)
+	}
+	return roots, nil
+}
+
+// rootLayout lays out the artifacts of root like those of a bundle.
+func (l *OutputLayout) rootLayout(r Root) *OutputLayout {
+	if l.written == nil {
+		l.written = make(map[string]string)
+	}
+	root := *l
+	root.Bundle, root.DataName, root.RootName = r.DataName, r.DataName, r.TypeName
+	return &root
+}
+
+// goRootTypes renders the root struct of every root, for go.gen.go.
+func goRootTypes(roots []Root) string {
+	var b strings.Builder
+	for _, r := range roots {
+		b.WriteString("\ntype " + r.TypeName + " struct {\n")
+		for _, sheet := range r.Sheets {
+			b.WriteString("\t" + sheet.FieldName + " []" + sheet.TypeName + " `json:\"" + sheet.JSONKey + "\"`\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func csRootTypes(roots []Root) string {
+	var b strings.Builder
+	for _, r := range roots {
+		b.WriteString("\npublic partial class " + r.TypeName + "\n{\n")
+		for i, sheet := range r.Sheets {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("    [JsonPropertyName(\"" + sheet.JSONKey + "\")]\n")
+			b.WriteString("    public List<" + sheet.TypeName + "> " + sheet.FieldName + " { get; set; }\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func tsRootTypes(roots []Root) string {
+	var b strings.Builder
+	for _, r := range roots {
+		b.WriteString("\nexport interface " + r.TypeName + " {\n")
+		for _, sheet := range r.Sheets {
+			b.WriteString("  " + sheet.JSONKey + ": " + sheet.TypeName + "[];\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,273 @@
+package genxls
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Editor integrations (the Excel add-in, the VS Code extension) keep one
+// `genxls serve` process running and talk JSON-RPC 2.0 to it, one message per
+// line on stdin and stdout, instead of spawning genxls on every keystroke.
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes; rpcCodeFailed is for workbooks genxls rejects.
+const (
+	rpcCodeParse          = -32700
+	rpcCodeInvalidRequest = -32600
+	rpcCodeNoMethod       = -32601
+	rpcCodeInvalidParams  = -32602
+	rpcCodeFailed         = -32000
+)
+
+// rpcSheetParams selects the sheets of a parse or validate request. Only
+// path is required; the rest mean what the flags of a normal run do.
+type rpcSheetParams struct {
+	Path            string `json:"path"`
+	Config          string `json:"config"`
+	Flag            string `json:"flag"`
+	Sheet           string `json:"sheet"`
+	NamespaceByFile bool   `json:"namespaceByFile"`
+}
+
+// rpcProblem is one validation finding, located when it is about one cell.
+type rpcProblem struct {
+	Origin  string `json:"origin,omitempty"`
+	Sheet   string `json:"sheet,omitempty"`
+	Row     int    `json:"row,omitempty"`
+	Col     int    `json:"col,omitempty"`
+	Message string `json:"message"`
+}
+
+type rpcCompleteParams struct {
+	Text string `json:"text"`
+}
+
+// rpcCompletion suggests how to go on with the define-row cell being typed:
+// each item replaces the text of the cell from Start (0-based, in bytes).
+type rpcCompletion struct {
+	Start int      `json:"start"`
+	Items []string `json:"items"`
+}
+
+func ServeRPC(r io.Reader, w io.Writer) error {
+	in := bufio.NewScanner(r)
+	in.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+	for in.Scan() {
+		line := strings.TrimSpace(in.Text())
+		if line == "" {
+			continue
+		}
+		var req rpcRequest
+		err := json.Unmarshal([]byte(line), &req)
+		if err != nil {
+			resp := rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcCodeParse, err.Error()}}
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			resp.Error = &rpcError{rpcCodeInvalidRequest, `expect "jsonrpc": "2.0" and a method`}
+		} else {
+			var result any
+			if result, resp.Error = handleRPC(req.Method, req.Params); resp.Error == nil {
+				if resp.Result, err = json.Marshal(result); err != nil {
+					return err
+				}
+			}
+		}
+		// Notifications (no id) get no response unless they fail.
+		if resp.ID == nil {
+			resp.ID = json.RawMessage("null")
+		}
+		if req.ID != nil || resp.Error != nil {
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+		}
+		if req.Method == "shutdown" {
+			return nil
+		}
+	}
+	return in.Err()
+}
+
+func handleRPC(method string, params json.RawMessage) (any, *rpcError) {
+	decode := func(v any) *rpcError {
+		if len(params) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(params, v); err != nil {
+			return &rpcError{rpcCodeInvalidParams, err.Error()}
+		}
+		return nil
+	}
+
+	switch method {
+	case "parse":
+		var p rpcSheetParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		sheets, err := rpcLoadSheets(p, false)
+		if err != nil {
+			return nil, &rpcError{rpcCodeFailed, err.Error()}
+		}
+		return BuildSchemaModel("AllConfig", sheets), nil
+	case "validate":
+		var p rpcSheetParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return rpcValidate(p), nil
+	case "complete":
+		var p rpcCompleteParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return completeDefineCell(p.Text), nil
+	case "shutdown":
+		return nil, nil
+	}
+	return nil, &rpcError{rpcCodeNoMethod, fmt.Sprintf("unknown method %q%s", method, didYouMean(method, []string{"parse", "validate", "complete", "shutdown"}))}
+}
+
+// rpcLoadSheets loads the sheets a request selects, with the config applied.
+// lenient keeps invalid cells as zero values, recorded in BadCells.
+func rpcLoadSheets(p rpcSheetParams, lenient bool) ([]*Sheet, error) {
+	if p.Path == "" {
+		return nil, errors.New("path is required")
+	}
+	cfg, err := LoadConfig(p.Config)
+	if err != nil {
+		return nil, err
+	}
+	inPaths, err := ResolveInputPaths(p.Path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	sheets, err := LoadSheets(context.Background(), inPaths, Options{Flag: p.Flag, Lenient: lenient, NamespaceFile: p.NamespaceByFile}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ApplyConfig(cfg, sheets, false); err != nil {
+		return nil, err
+	}
+	if p.Sheet != "" {
+		sheet := FindSheet(sheets, p.Sheet)
+		if sheet == nil {
+			return nil, fmt.Errorf("sheet %q not found", p.Sheet)
+		}
+		sheets = []*Sheet{sheet}
+	}
+	return sheets, nil
+}
+
+// rpcValidate reports every invalid cell and the checks of a normal export
+// that need nothing but the sheets. An error that stops the sheets from
+// loading at all, such as a bad define row, is the only problem then.
+func rpcValidate(p rpcSheetParams) []rpcProblem {
+	sheets, err := rpcLoadSheets(p, true)
+	if err != nil {
+		return loadProblems(err)
+	}
+	return sheetProblems(sheets)
+}
+
+// loadProblems is a problem per sheet or cell that failed to load.
+func loadProblems(err error) []rpcProblem {
+	var failed *LoadErrors
+	if !errors.As(err, &failed) {
+		return []rpcProblem{loadProblem(err)}
+	}
+	problems := make([]rpcProblem, len(failed.Errs))
+	for i, err := range failed.Errs {
+		problems[i] = loadProblem(err)
+	}
+	return problems
+}
+
+// loadProblem is the problem of sheets failing to load, placed at the sheet,
+// row and cell the error names.
+func loadProblem(err error) rpcProblem {
+	p := rpcProblem{Message: err.Error()}
+	var schemaErr *SchemaError
+	var cellErr *CellError
+	var dupErr *DuplicateKeyError
+	switch {
+	case errors.As(err, &cellErr):
+		p.Sheet, p.Row, p.Col = cellErr.Sheet, cellErr.Row, cellErr.Col
+	case errors.As(err, &schemaErr):
+		p.Origin, p.Sheet, p.Row = schemaErr.Origin, schemaErr.Sheet, schemaErr.Row
+	case errors.As(err, &dupErr):
+		p.Origin, p.Row = dupErr.Sheet, dupErr.Row
+	}
+	return p
+}
+
+// sheetProblems lists the invalid cells of sheets loaded leniently and what
+// checkWindows, checkRefs and checkIDBounds find.
+func sheetProblems(sheets []*Sheet) []rpcProblem {
+	result := []rpcProblem{}
+	for _, sheet := range sheets {
+		for _, e := range sheet.BadCells {
+			result = append(result, rpcProblem{Origin: sheet.Origin, Sheet: sheet.Name, Row: e.Row, Col: e.Col, Message: e.Error()})
+		}
+	}
+	problems := slices.Concat(checkWindows(sheets), checkRefs(sheets), checkIDBounds(sheets))
+	for _, p := range problems {
+		result = append(result, rpcProblem{Origin: p.Sheet.Origin, Sheet: p.Sheet.Name, Row: p.Row, Col: p.Col, Message: p.Msg})
+	}
+	return result
+}
+
+// completeDefineCell suggests how to go on with a define-row cell: the types
+// after "#", the options after a ",". Column names are free, so there is
+// nothing to suggest before the "#".
+func completeDefineCell(text string) rpcCompletion {
+	hash := strings.LastIndex(text, "#")
+	if hash < 0 {
+		return rpcCompletion{Start: len(text), Items: []string{}}
+	}
+	vocab, start := SupportedTypes, hash+1
+	if comma := strings.LastIndex(text, ","); comma > hash {
+		vocab, start = fieldOptions, comma+1
+	}
+	prefix := strings.ToLower(strings.TrimSpace(text[start:]))
+	for start < len(text) && text[start] == ' ' {
+		start++
+	}
+	items := []string{}
+	for _, v := range vocab {
+		if strings.HasPrefix(v, prefix) {
+			items = append(items, v)
+		}
+	}
+	return rpcCompletion{Start: start, Items: items}
+}
@@ -0,0 +1,164 @@
+package genxls
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SchemaModel is the parsed schema `genxls schema --format json` dumps, for
+// tools that want genxls's reading of the sheets rather than the workbooks.
+type SchemaModel struct {
+	Root   string             `json:"root"`
+	Sheets []SchemaModelSheet `json:"sheets"`
+}
+
+type SchemaModelSheet struct {
+	Name     string              `json:"name"`
+	Type     string              `json:"type"`
+	Field    string              `json:"field"`
+	Key      string              `json:"key"`
+	Origin   string              `json:"origin"`
+	Owner    string              `json:"owner,omitempty"`
+	Rows     int                 `json:"rows"`
+	Columns  []SchemaModelColumn `json:"columns"`
+	SortedBy []string            `json:"sortedBy,omitempty"`
+	IDRange  *IDBlock            `json:"idRange,omitempty"`
+	OldKey   string              `json:"oldKey,omitempty"`
+}
+
+type SchemaModelColumn struct {
+	Name    string `json:"name"`
+	Field   string `json:"field"`
+	Type    string `json:"type"`
+	GoType  string `json:"goType"`
+	Export  string `json:"export"`
+	Column  int    `json:"column,omitempty"` // 1-based; 0 for derived, joined and pivoted columns
+	Primary bool   `json:"primary,omitempty"`
+	String  bool   `json:"string,omitempty"`
+	Doc     string `json:"doc,omitempty"`
+}
+
+func BuildSchemaModel(rootName string, sheets []*Sheet) SchemaModel {
+	model := SchemaModel{Root: rootName, Sheets: []SchemaModelSheet{}}
+	for _, sheet := range sheets {
+		ms := SchemaModelSheet{
+			Name:    sheet.Name,
+			Type:    sheet.TypeName,
+			Field:   sheet.FieldName,
+			Key:     sheet.JSONKey,
+			Origin:  sheet.Origin,
+			Owner:   sheet.Owner,
+			Rows:    len(sheet.Items),
+			IDRange: sheet.IDRange,
+			OldKey:  sheet.OldJSONKey,
+		}
+		for i, f := range sheet.Fields {
+			ms.Columns = append(ms.Columns, SchemaModelColumn{
+				Name:    f.RawName,
+				Field:   f.Name,
+				Type:    strings.ToLower(f.RawType),
+				GoType:  f.GoType,
+				Export:  fieldFlagDoc(f.Flag),
+				Column:  f.Col + 1,
+				Primary: i == 0,
+				String:  f.JSONString,
+				Doc:     f.Doc,
+			})
+			if f.SortKey {
+				ms.SortedBy = append(ms.SortedBy, f.RawName)
+			}
+		}
+		model.Sheets = append(model.Sheets, ms)
+	}
+	return model
+}
+
+var protoNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func protoType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int64":
+		return "int64", true
+	case "int32":
+		return "int32", true
+	case "int[]":
+		return "repeated int64", true
+	case "int[][]":
+		return "repeated IntList", true
+	case "float", "float64":
+		return "double", true
+	case "float32":
+		return "float", true
+	case "bool":
+		return "bool", true
+	case "string", "datetime":
+		return "string", true
+	default:
+		return "", false
+	}
+}
+
+// GenerateProtoSchema renders a proto3 file with a message per sheet and the
+// root message holding their rows, numbered in column order or as the schema
+// lock pins them. Fields keep the column names, which proto3 JSON parsing
+// accepts as is.
+func GenerateProtoSchema(pkg, rootName string, sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n\n")
+	b.WriteString("package " + pkg + ";\n\n")
+	b.WriteString("message " + rootName + " {\n")
+	taken := make(map[int]string)
+	for i, sheet := range sheets {
+		if !protoNameRe.MatchString(sheet.JSONKey) {
+			return "", fmt.Errorf("proto: %s: sheet key %q is not a valid field name", sheet.Origin, sheet.JSONKey)
+		}
+		num := protoNumber(sheet.ProtoField, i)
+		if prev, ok := taken[num]; ok {
+			return "", fmt.Errorf("proto: %s: field number %d of %s is also %s's", rootName, num, sheet.JSONKey, prev)
+		}
+		taken[num] = sheet.JSONKey
+		fmt.Fprintf(&b, "  repeated %s %s = %d;\n", sheet.TypeName, sheet.JSONKey, num)
+	}
+	b.WriteString("}\n")
+
+	needIntList := false
+	for _, sheet := range sheets {
+		if !protoNameRe.MatchString(sheet.TypeName) {
+			return "", fmt.Errorf("proto: %s: invalid message name %q", sheet.Origin, sheet.TypeName)
+		}
+		b.WriteString("\nmessage " + sheet.TypeName + " {\n")
+		clear(taken)
+		for i, f := range sheet.Fields {
+			if !protoNameRe.MatchString(f.RawName) {
+				return "", fmt.Errorf("proto: %s: column %q is not a valid field name", sheet.Origin, f.RawName)
+			}
+			t, ok := protoType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("proto: %s: unsupported type %q", sheet.Origin, f.RawType)
+			}
+			num := protoNumber(f.ProtoField, i)
+			if prev, ok := taken[num]; ok {
+				return "", fmt.Errorf("proto: %s: field number %d of column %s is also %s's", sheet.Origin, num, f.RawName, prev)
+			}
+			taken[num] = f.RawName
+			needIntList = needIntList || strings.HasSuffix(t, "IntList")
+			writeDocComment(&b, "  // ", f.Doc)
+			fmt.Fprintf(&b, "  %s %s = %d;\n", t, f.RawName, num)
+		}
+		b.WriteString("}\n")
+	}
+	if needIntList {
+		b.WriteString("\n// IntList is one row of an int[][] column.\nmessage IntList {\n  repeated int64 values = 1;\n}\n")
+	}
+	return b.String(), nil
+}
+
+// protoNumber is the field number of the i-th sheet or column: the one the
+// schema lock pins, or its position.
+func protoNumber(pinned, i int) int {
+	if pinned > 0 {
+		return pinned
+	}
+	return i + 1
+}
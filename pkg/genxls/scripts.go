@@ -0,0 +1,308 @@
+package genxls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// A transform script (config "script") massages a sheet's rows where Excel
+// can't. It is Starlark, a Python dialect, run by an embedded interpreter:
+//
+//	add_columns = {"dps": "float"}
+//
+//	def transform(row):
+//	    if row["hidden"] or row["level"] < 1:
+//	        return False                  # drop the row
+//	    row["name"] = "%s (%d)" % (row["name"], row["level"])
+//	    row["dps"] = row["damage"] / row["interval"]
+//
+// transform is called once per row with a dict of the row's cells, keyed by
+// column name, and edits it in place; returning False drops the row, None or
+// True keeps it. add_columns appends columns, name to type, which start out
+// as zero values. The predeclared sheet, columns and types are the sheet name,
+// its column names in define-row order and their types. Scripts can't load
+// other files or reach the filesystem.
+
+// runSheetScript runs the transform script at path on sheet.
+func runSheetScript(sheet *Sheet, path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(path)
+	thread := &starlark.Thread{
+		Name:  name,
+		Print: func(_ *starlark.Thread, msg string) { fmt.Fprintf(os.Stderr, "%s: %s\n", name, msg) },
+	}
+	columns := make([]starlark.Value, len(sheet.Fields))
+	types := starlark.NewDict(len(sheet.Fields))
+	for i, f := range sheet.Fields {
+		columns[i] = starlark.String(f.RawName)
+		_ = types.SetKey(starlark.String(f.RawName), starlark.String(f.RawType))
+	}
+	predeclared := starlark.StringDict{
+		"sheet":   starlark.String(sheet.Name),
+		"columns": starlark.NewList(columns),
+		"types":   types,
+	}
+	predeclared.Freeze()
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, src, predeclared)
+	if err != nil {
+		return scriptError(err, "")
+	}
+
+	if v, ok := globals["add_columns"]; ok {
+		if err := addScriptColumns(sheet, v); err != nil {
+			return fmt.Errorf("%s: add_columns: %w", name, err)
+		}
+	}
+	fn, ok := globals["transform"].(starlark.Callable)
+	if !ok {
+		return fmt.Errorf("%s: no transform(row) function", name)
+	}
+	items, rows := sheet.Items[:0], sheet.Rows[:0]
+	for i, item := range sheet.Items {
+		row, err := scriptRow(sheet.Fields, item)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %w", name, sheet.Rows[i], err)
+		}
+		res, err := starlark.Call(thread, fn, starlark.Tuple{row}, nil)
+		if err != nil {
+			return scriptError(err, fmt.Sprintf("row %d: ", sheet.Rows[i]))
+		}
+		switch res {
+		case starlark.None, starlark.True:
+		case starlark.False:
+			continue
+		default:
+			return fmt.Errorf("%s: row %d: transform returned %s, want True, False or None", name, sheet.Rows[i], res.Type())
+		}
+		if err := applyScriptRow(sheet.Fields, item, row); err != nil {
+			return fmt.Errorf("%s: row %d: %w", name, sheet.Rows[i], err)
+		}
+		items, rows = append(items, item), append(rows, sheet.Rows[i])
+	}
+	sheet.Items, sheet.Rows = items, rows
+	return nil
+}
+
+// scriptError locates an error raised in the script at its innermost line,
+// followed by where, e.g. the row transform was called for.
+func scriptError(err error, where string) error {
+	var ee *starlark.EvalError
+	if errors.As(err, &ee) && len(ee.CallStack) > 0 {
+		return fmt.Errorf("%s: %s%s", ee.CallStack.At(0).Pos, where, ee.Msg)
+	}
+	if where != "" {
+		return fmt.Errorf("%s%w", where, err)
+	}
+	return err
+}
+
+// addScriptColumns appends the columns of add_columns, named and typed like
+// a define-row cell, with zero values.
+func addScriptColumns(sheet *Sheet, v starlark.Value) error {
+	cols, ok := v.(*starlark.Dict)
+	if !ok {
+		return fmt.Errorf("got %s, want a dict of column name to type", v.Type())
+	}
+	for _, kv := range cols.Items() {
+		name, ok1 := starlark.AsString(kv[0])
+		typ, ok2 := starlark.AsString(kv[1])
+		if !ok1 || !ok2 {
+			return fmt.Errorf("%s: %s, want a column name and a type", kv[0], kv[1])
+		}
+		if m := fieldRe.FindStringSubmatch(name + "#" + typ); m == nil || m[1] != name {
+			return fmt.Errorf("column %q: invalid name or type %q", name, typ)
+		}
+		if findField(sheet.Fields, name) != nil {
+			return fmt.Errorf("column %q: the sheet already has that column", name)
+		}
+		goType, ok := mapGoType(typ)
+		if !ok {
+			return fmt.Errorf("column %q: unsupported type %q%s", name, typ, didYouMean(typ, SupportedTypes))
+		}
+		zero, err := parseCellValue(typ, "")
+		if err != nil {
+			return err
+		}
+		for _, item := range sheet.Items {
+			item[name] = zero
+		}
+		sheet.Fields = append(sheet.Fields, Field{
+			RawName:  name,
+			Name:     exportName(name),
+			RawType:  typ,
+			GoType:   goType,
+			Col:      -1, // not in the workbook
+			Flag:     FieldFlagAll,
+			Exported: true,
+		})
+	}
+	return nil
+}
+
+// scriptRow converts a row to the dict transform receives.
+func scriptRow(fields []Field, item map[string]any) (*starlark.Dict, error) {
+	row := starlark.NewDict(len(fields))
+	for _, f := range fields {
+		v, err := toStarlark(item[f.RawName])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.RawName, err)
+		}
+		_ = row.SetKey(starlark.String(f.RawName), v)
+	}
+	return row, nil
+}
+
+func toStarlark(v any) (starlark.Value, error) {
+	switch x := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case int:
+		return starlark.MakeInt(x), nil
+	case float64:
+		return starlark.Float(x), nil
+	case bool:
+		return starlark.Bool(x), nil
+	case string:
+		return starlark.String(x), nil
+	case []int:
+		list := make([]starlark.Value, len(x))
+		for i, n := range x {
+			list[i] = starlark.MakeInt(n)
+		}
+		return starlark.NewList(list), nil
+	case [][]int:
+		list := make([]starlark.Value, len(x))
+		for i, inner := range x {
+			list[i], _ = toStarlark(inner)
+		}
+		return starlark.NewList(list), nil
+	}
+	return nil, fmt.Errorf("unsupported value %T", v)
+}
+
+// applyScriptRow writes the dict transform edited back into item, checking
+// every value against its column's type; a removed key resets the cell to
+// the zero value.
+func applyScriptRow(fields []Field, item map[string]any, row *starlark.Dict) error {
+	for _, k := range row.Keys() {
+		name, ok := starlark.AsString(k)
+		if !ok || findField(fields, name) == nil {
+			return fmt.Errorf("no column %s%s; declare new columns in add_columns", k, didYouMean(name, fieldNames(fields)))
+		}
+	}
+	for _, f := range fields {
+		v, found, _ := row.Get(starlark.String(f.RawName))
+		var (
+			x   any
+			err error
+		)
+		if !found || v == starlark.None {
+			x, err = parseCellValue(f.RawType, "")
+		} else {
+			x, err = fromStarlark(f.RawType, v)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.RawName, err)
+		}
+		item[f.RawName] = x
+	}
+	return nil
+}
+
+// fromStarlark converts a script value to the Go value a cell of rawType
+// parses to.
+func fromStarlark(rawType string, v starlark.Value) (any, error) {
+	mismatch := fmt.Errorf("%s %s, want %s", v.Type(), v, rawType)
+	switch t := strings.ToLower(rawType); t {
+	case "int", "int32", "int64":
+		n, ok := v.(starlark.Int)
+		if !ok {
+			return nil, mismatch
+		}
+		i, ok := n.Int64()
+		if !ok || i != int64(int(i)) {
+			return nil, fmt.Errorf("%s overflows %s", n, rawType)
+		}
+		if err := checkIntRange(t, int(i)); err != nil {
+			return nil, err
+		}
+		return int(i), nil
+	case "float", "float32", "float64":
+		f, ok := starlark.AsFloat(v)
+		if !ok {
+			return nil, mismatch
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%v is not a finite number", f)
+		}
+		return f, nil
+	case "bool":
+		b, ok := v.(starlark.Bool)
+		if !ok {
+			return nil, mismatch
+		}
+		return bool(b), nil
+	case "string":
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return nil, mismatch
+		}
+		return s, nil
+	case "datetime":
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return nil, mismatch
+		}
+		return parseCellValue(t, s)
+	case "int[]":
+		return starlarkInts(v, mismatch)
+	case "int[][]":
+		seq, ok := v.(starlark.Indexable)
+		if !ok {
+			return nil, mismatch
+		}
+		out := make([][]int, seq.Len())
+		for i := range out {
+			inner, err := starlarkInts(seq.Index(i), mismatch)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = inner
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", rawType)
+}
+
+func starlarkInts(v starlark.Value, mismatch error) ([]int, error) {
+	seq, ok := v.(starlark.Indexable)
+	if !ok {
+		return nil, mismatch
+	}
+	if _, isStr := v.(starlark.String); isStr {
+		return nil, mismatch
+	}
+	out := make([]int, seq.Len())
+	for i := range out {
+		n, ok := seq.Index(i).(starlark.Int)
+		if !ok {
+			return nil, mismatch
+		}
+		x, ok := n.Int64()
+		if !ok || x != int64(int(x)) {
+			return nil, fmt.Errorf("%s overflows int", n)
+		}
+		out[i] = int(x)
+	}
+	return out, nil
+}
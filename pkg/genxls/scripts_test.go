@@ -0,0 +1,82 @@
+package genxls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadScriptSheet(t *testing.T) *Sheet {
+	t.Helper()
+	src := NewMemorySource("db").Add("Monster", [][]string{
+		{"id#int", "name#string", "level#int", "hidden#bool", "damage#int", "interval#float", "tags#int[]"},
+		{"1", "Slime", "1", "false", "10", "2", "{1}"},
+		{"2", "Ghost", "0", "false", "5", "1", "{}"},
+		{"3", "Boss", "9", "false", "90", "1.5", "{2,3}"},
+		{"4", "Test", "5", "true", "1", "1", "{}"},
+	})
+	sheets, err := LoadSources(context.Background(), []SheetSource{src}, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sheets[0]
+}
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "monster.star")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSheetScript(t *testing.T) {
+	sheet := loadScriptSheet(t)
+	path := writeScript(t, `
+add_columns = {"dps": "float", "origin": "string"}
+
+def transform(row):
+    if row["hidden"] or row["level"] < 1:
+        return False
+    row["name"] = "%s (%d)" % (row["name"], row["level"])
+    row["dps"] = row["damage"] / row["interval"]
+    row["tags"] = row["tags"] + [len(columns)]
+    row["origin"] = sheet
+`)
+	if err := runSheetScript(sheet, path); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sheet.Rows, []int{2, 4}) {
+		t.Errorf("rows %v, want [2 4]", sheet.Rows)
+	}
+	if got := fieldNames(sheet.Fields); !reflect.DeepEqual(got, []string{"id", "name", "level", "hidden", "damage", "interval", "tags", "dps", "origin"}) {
+		t.Errorf("columns %v", got)
+	}
+	want := map[string]any{"id": 3, "name": "Boss (9)", "level": 9, "hidden": false, "damage": 90, "interval": 1.5, "tags": []int{2, 3, 7}, "dps": 60.0, "origin": "Monster"}
+	if !reflect.DeepEqual(sheet.Items[1], want) {
+		t.Errorf("row 4:\n got %v\nwant %v", sheet.Items[1], want)
+	}
+}
+
+func TestSheetScriptErrors(t *testing.T) {
+	for _, tt := range []struct{ name, src, want string }{
+		{"type", "def transform(row):\n    row[\"level\"] = \"high\"\n", `row 2: level: string "high", want int`},
+		{"column", "def transform(row):\n    row[\"lvl\"] = 1\n", `row 2: no column "lvl"`},
+		{"runtime", "def transform(row):\n    return row[\"name\"] / 2\n", "monster.star:2:24: row 2: unknown binary op: string / int"},
+		{"syntax", "def transform(row)\n", "monster.star:2:1: got newline, want ':'"},
+		{"missing", "x = 1\n", "no transform(row) function"},
+		{"result", "def transform(row):\n    return 1\n", "transform returned int"},
+		{"add", "add_columns = {\"name\": \"string\"}\ndef transform(row):\n    pass\n", `column "name": the sheet already has that column`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runSheetScript(loadScriptSheet(t), writeScript(t, tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
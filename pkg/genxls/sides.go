@@ -0,0 +1,74 @@
+package genxls
+
+import (
+	"path/filepath"
+	"slices"
+)
+
+// --flag both exports the server and the client view of the workbooks in one
+// run: every column is loaded once, then each side gets the sheets without
+// the other side's columns and writes them into its own subdirectory of --out.
+
+// ExportSide is one set of outputs of a run.
+type ExportSide struct {
+	Flag   string // server, client or "" (no --flag): lock section and column filter
+	Dir    string // subdirectory of --out; "" for --out itself
+	Sheets []*Sheet
+}
+
+// fieldFlag is the flag define rows are filtered by while loading: none for
+// --flag both, whose sides are split off after loading.
+func (o Options) fieldFlag() string {
+	if o.Flag == "both" {
+		return ""
+	}
+	return o.Flag
+}
+
+// exportSides returns the sides of a run, sheets itself for a single one.
+func exportSides(flag string, sheets []*Sheet) []ExportSide {
+	if flag != "both" {
+		return []ExportSide{{Flag: flag, Sheets: sheets}}
+	}
+	return []ExportSide{
+		{Flag: "server", Dir: "server", Sheets: sideSheets(sheets, FieldFlagClient)},
+		{Flag: "client", Dir: "client", Sheets: sideSheets(sheets, FieldFlagServer)},
+	}
+}
+
+// sideSheets copies sheets without the columns marked drop, in the fields and
+// in every row.
+func sideSheets(sheets []*Sheet, drop FieldFlag) []*Sheet {
+	out := make([]*Sheet, len(sheets))
+	for i, sheet := range sheets {
+		cp := *sheet
+		cp.Fields = slices.DeleteFunc(slices.Clone(sheet.Fields), func(f Field) bool { return f.Flag == drop })
+		if len(cp.Fields) < len(sheet.Fields) {
+			cp.Items = make([]map[string]any, len(sheet.Items))
+			for j, item := range sheet.Items {
+				row := make(map[string]any, len(cp.Fields))
+				for _, f := range cp.Fields {
+					if v, ok := item[f.RawName]; ok {
+						row[f.RawName] = v
+					}
+				}
+				cp.Items[j] = row
+			}
+		}
+		out[i] = &cp
+	}
+	return out
+}
+
+// sideLayout lays out the artifacts of side under its subdirectory.
+func (l *OutputLayout) sideLayout(side ExportSide) *OutputLayout {
+	if side.Dir == "" {
+		return l
+	}
+	if l.written == nil {
+		l.written = make(map[string]string)
+	}
+	cp := *l
+	cp.OutDir = filepath.Join(l.OutDir, side.Dir)
+	return &cp
+}
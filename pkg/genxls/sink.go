@@ -0,0 +1,251 @@
+package genxls
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArtifactSink stores generated artifacts. Paths are those the output layout
+// renders, under --out; embedders and tests can capture artifacts with a
+// MemorySink instead of reading them back from a temporary directory.
+type ArtifactSink interface {
+	WriteArtifact(ctx context.Context, path string, data []byte) error
+}
+
+// artifactAborter is a sink that can discard what a failed run wrote so far:
+// Export aborts the sinks it creates when it returns an error.
+type artifactAborter interface {
+	Abort()
+}
+
+// fsSink writes artifacts to the filesystem, the default.
+type fsSink struct{}
+
+func (fsSink) WriteArtifact(_ context.Context, path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// MemorySink keeps artifacts in memory, in the order they were written.
+type MemorySink struct {
+	paths []string
+	files map[string][]byte
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{files: make(map[string][]byte)}
+}
+
+func (m *MemorySink) WriteArtifact(_ context.Context, path string, data []byte) error {
+	if _, ok := m.files[path]; !ok {
+		m.paths = append(m.paths, path)
+	}
+	m.files[path] = append([]byte(nil), data...)
+	return nil
+}
+
+// Paths lists the written artifacts.
+func (m *MemorySink) Paths() []string { return m.paths }
+
+// File returns the content of an artifact.
+func (m *MemorySink) File(path string) ([]byte, bool) {
+	data, ok := m.files[path]
+	return data, ok
+}
+
+// NewArtifactSink returns the sink of --sink: a URL receives an HTTP PUT per
+// artifact, a .zip, .tar, .tar.gz or .tgz path an archive, and "" the files in
+// --out. Artifact paths are made relative to outDir for the first two.
+func NewArtifactSink(target, outDir string) (ArtifactSink, error) {
+	lower := strings.ToLower(target)
+	switch {
+	case target == "":
+		return fsSink{}, nil
+	case isURL(target):
+		return &httpSink{base: strings.TrimSuffix(target, "/"), outDir: outDir}, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return newArchiveSink(target, outDir, "zip")
+	case strings.HasSuffix(lower, ".tar"):
+		return newArchiveSink(target, outDir, "tar")
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return newArchiveSink(target, outDir, "tar.gz")
+	}
+	return nil, fmt.Errorf("--sink %q: expect a URL or a .zip, .tar, .tar.gz or .tgz file", target)
+}
+
+// artifactName is path relative to outDir, with forward slashes; artifacts
+// outside --out have no place in an archive or under a URL.
+func artifactName(outDir, path string) (string, error) {
+	rel, err := filepath.Rel(outDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("--sink: %s is outside %s", path, outDir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// archiveSink writes artifacts into a zip or tar archive. The archive is
+// built next to its path and only replaces it once closed, so a failed run
+// leaves the previous one.
+type archiveSink struct {
+	done   bool // closed or aborted
+	path   string
+	outDir string
+	f      *os.File
+	zw     *zip.Writer
+	tw     *tar.Writer
+	gz     *gzip.Writer
+	now    time.Time
+}
+
+func newArchiveSink(path, outDir, format string) (*archiveSink, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	s := &archiveSink{path: path, outDir: outDir, f: f, now: time.Now()}
+	switch format {
+	case "zip":
+		s.zw = zip.NewWriter(f)
+	case "tar":
+		s.tw = tar.NewWriter(f)
+	default:
+		s.gz = gzip.NewWriter(f)
+		s.tw = tar.NewWriter(s.gz)
+	}
+	return s, nil
+}
+
+func (s *archiveSink) WriteArtifact(_ context.Context, path string, data []byte) error {
+	name, err := artifactName(s.outDir, path)
+	if err != nil {
+		return err
+	}
+	if s.zw != nil {
+		w, err := s.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: s.now})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: s.now, Typeflag: tar.TypeReg}
+	if err := s.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = s.tw.Write(data)
+	return err
+}
+
+// Close finishes the archive and moves it into place.
+func (s *archiveSink) Close() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+	var err error
+	if s.zw != nil {
+		err = s.zw.Close()
+	} else {
+		err = s.tw.Close()
+		if s.gz != nil && err == nil {
+			err = s.gz.Close()
+		}
+	}
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(s.f.Name())
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	return os.Rename(s.f.Name(), s.path)
+}
+
+// Abort drops the unfinished archive, leaving the previous one in place.
+func (s *archiveSink) Abort() {
+	if s.done {
+		return
+	}
+	s.done = true
+	_ = s.f.Close()
+	_ = os.Remove(s.f.Name())
+}
+
+// httpSink PUTs every artifact to base/<path relative to --out>, e.g. to an
+// object store or a CDN origin.
+type httpSink struct {
+	base   string
+	outDir string
+
+	mu     sync.Mutex
+	failed error // the first failed upload; later artifacts are not sent
+}
+
+func (s *httpSink) WriteArtifact(ctx context.Context, path string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed != nil {
+		return s.failed
+	}
+	if err := s.put(ctx, path, data); err != nil {
+		s.failed = err
+		return err
+	}
+	return nil
+}
+
+// Abort stops uploading; artifacts already sent stay where they are.
+func (s *httpSink) Abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed == nil {
+		s.failed = errors.New("--sink: run aborted")
+	}
+}
+
+func (s *httpSink) put(ctx context.Context, path string, data []byte) error {
+	name, err := artifactName(s.outDir, path)
+	if err != nil {
+		return err
+	}
+	u := s.base + "/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(name)); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	} else {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("PUT %s: %s", u, resp.Status)
+	}
+	return nil
+}
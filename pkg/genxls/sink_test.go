@@ -0,0 +1,54 @@
+package genxls
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveSinkAbortedOnError(t *testing.T) {
+	dir := t.TempDir()
+	opts := DefaultOptions()
+	opts.OutDir = filepath.Join(dir, "out")
+	opts.Sources = []SheetSource{NewMemorySource("db").Add("Item", [][]string{{"id#int", "name#string"}, {"1", "Sword"}})}
+	opts.Sink = filepath.Join(dir, "config.zip")
+	opts.OutTemplate = "{outDir}/same" // every artifact collides with the first
+	if err := Export(context.Background(), opts, nil); err == nil {
+		t.Fatal("colliding artifacts: no error")
+	}
+	left, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range left {
+		if f != opts.OutDir {
+			t.Errorf("failed run left %s", f)
+		}
+	}
+}
+
+func TestHTTPSinkStopsAtFirstError(t *testing.T) {
+	var puts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		puts = append(puts, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	out := t.TempDir()
+	sink, err := NewArtifactSink(srv.URL, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := sink.WriteArtifact(ctx, filepath.Join(out, "a.json"), nil); err == nil {
+		t.Fatal("500: no error")
+	}
+	if err := sink.WriteArtifact(ctx, filepath.Join(out, "b.json"), nil); err == nil {
+		t.Error("write after a failed upload: no error")
+	}
+	if len(puts) != 1 {
+		t.Errorf("uploads %v, want only the first", puts)
+	}
+}
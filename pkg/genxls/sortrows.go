@@ -0,0 +1,88 @@
+package genxls
+
+import (
+	"cmp"
+	"sort"
+)
+
+// SortSheetRows orders rows by the columns marked with the ",sort" option, or
+// by the first column (the primary key) when byKey is set and none are marked.
+// The sort is stable so rows with equal keys keep their sheet order.
+func SortSheetRows(sheet *Sheet, byKey bool) {
+	var keys []Field
+	for _, f := range sheet.Fields {
+		if f.SortKey {
+			keys = append(keys, f)
+		}
+	}
+	if len(keys) == 0 {
+		if !byKey {
+			return
+		}
+		keys = sheet.Fields[:1]
+	}
+	// Sort a permutation so Items and Rows stay aligned.
+	perm := make([]int, len(sheet.Items))
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(i, j int) bool {
+		a, b := sheet.Items[perm[i]], sheet.Items[perm[j]]
+		for _, f := range keys {
+			if c := compareValues(a[f.RawName], b[f.RawName]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	items := make([]map[string]any, len(perm))
+	rows := make([]int, len(perm))
+	for i, p := range perm {
+		items[i] = sheet.Items[p]
+		rows[i] = sheet.Rows[p]
+	}
+	sheet.Items, sheet.Rows = items, rows
+}
+
+// compareValues compares two parsed cell values of the same column type.
+func compareValues(a, b any) int {
+	switch x := a.(type) {
+	case int:
+		y, _ := b.(int)
+		return cmp.Compare(x, y)
+	case float64:
+		y, _ := b.(float64)
+		return cmp.Compare(x, y)
+	case string:
+		y, _ := b.(string)
+		return cmp.Compare(x, y)
+	case bool:
+		y, _ := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		default:
+			return 1
+		}
+	case []int:
+		y, _ := b.([]int)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := cmp.Compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(x), len(y))
+	case [][]int:
+		y, _ := b.([][]int)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compareValues(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(x), len(y))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,333 @@
+package genxls
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SheetSource is one input of LoadSources: a workbook, a delimited text file,
+// or sheets built in memory, so embedders can feed rows from a database or a
+// test without touching the filesystem.
+type SheetSource interface {
+	// Name identifies the source in messages. Config rules (owners,
+	// passwords) and --namespace-by-file match it like a file path.
+	Name() string
+	// Sheets lists the sheet names in order.
+	Sheets() ([]string, error)
+	// Rows returns the cell text of a sheet, header rows included.
+	Rows(sheet string) ([][]string, error)
+}
+
+// Sources may also implement these; LoadSources closes sources that are
+// io.Closers once their sheets are read.
+type (
+	// noteSource has cell comments, the docs of define-row cells.
+	noteSource interface {
+		Notes(sheet string) (map[string]string, error)
+	}
+	// propsSource has document properties, for who last modified a sheet.
+	propsSource interface {
+		DocProps() *excelize.DocProperties
+	}
+	// originSource names its sheets in messages other than as Name[sheet].
+	originSource interface {
+		origin(sheet string) string
+	}
+	// rowStreamer hands out the rows of big sheets one at a time instead of
+	// through Rows; ok is false for sheets to read whole.
+	rowStreamer interface {
+		streamRows(sheet string) (it rowIterator, ok bool, err error)
+	}
+)
+
+// rowIterator yields the rows of a sheet in order; Next returns io.EOF after
+// the last one.
+type rowIterator interface {
+	Next() ([]string, error)
+	Close() error
+}
+
+// sliceRows iterates rows already in memory.
+type sliceRows [][]string
+
+func (s *sliceRows) Next() ([]string, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	row := (*s)[0]
+	*s = (*s)[1:]
+	return row, nil
+}
+
+func (s *sliceRows) Close() error { return nil }
+
+// chainRows iterates its iterators one after the other.
+type chainRows []rowIterator
+
+func (c *chainRows) Next() ([]string, error) {
+	for len(*c) > 0 {
+		row, err := (*c)[0].Next()
+		if err != io.EOF {
+			return row, err
+		}
+		*c = (*c)[1:]
+	}
+	return nil, io.EOF
+}
+
+func (c *chainRows) Close() error { return nil }
+
+// readRows appends the remaining rows of it to rows.
+func readRows(rows [][]string, it rowIterator) ([][]string, error) {
+	for {
+		row, err := it.Next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func sourceOrigin(src SheetSource, sheet string) string {
+	if o, ok := src.(originSource); ok {
+		return o.origin(sheet)
+	}
+	return fmt.Sprintf("%s[%s]", src.Name(), sheet)
+}
+
+// FileSources returns the sources of input files: .csv and .tsv files are
+// delimited text, anything else a workbook (or tab-separated text in
+// disguise). Workbooks are only opened when their sheets are listed.
+func FileSources(inPaths []string, opts Options, cfg *Config) []SheetSource {
+	sources := make([]SheetSource, 0, len(inPaths))
+	for _, p := range inPaths {
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".csv":
+			sources = append(sources, &delimitedSource{path: p, comma: ','})
+		case ".tsv":
+			sources = append(sources, &delimitedSource{path: p, comma: '\t'})
+		default:
+			sources = append(sources, &workbookSource{path: p, password: cfg.PasswordFor(p, opts.Password), opts: opts})
+		}
+	}
+	return sources
+}
+
+// workbookSource reads an xlsx/xlsm workbook, working around WPS quirks and
+// streaming big worksheets. Files that turn out not to be workbooks are read
+// as tab-separated text.
+type workbookSource struct {
+	path     string
+	password string
+	opts     Options
+
+	f        *excelize.File
+	text     *delimitedSource
+	stream   bool
+	dangling map[string][]danglingString
+}
+
+func (w *workbookSource) Name() string { return w.path }
+
+func (w *workbookSource) Sheets() ([]string, error) {
+	if st, err := os.Stat(w.path); err == nil && w.opts.StreamSize > 0 && st.Size() >= w.opts.StreamSize {
+		f, ok, err := openWorkbookFile(w.path)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			w.f, w.stream = f, true
+			w.opts.logger().Info(fmt.Sprintf("%s: %s, reading row by row", w.path, FormatByteSize(st.Size())), "path", w.path)
+		}
+	}
+	f := w.f
+	if f == nil {
+		var err error
+		if f, err = OpenWorkbook(w.path, w.password); err != nil {
+			return nil, err
+		}
+	}
+	if f == nil {
+		w.text = &delimitedSource{path: w.path, comma: '\t'}
+		return w.text.Sheets()
+	}
+	w.f = f
+	sheetNames := f.GetSheetList()
+	if len(sheetNames) == 0 {
+		return nil, fmt.Errorf("%s: xlsx has no sheets", w.path)
+	}
+	if isWPSWorkbook(f) {
+		// Encrypted packages aren't zip files; they are left alone.
+		if zr, err := zip.OpenReader(w.path); err == nil {
+			w.dangling, err = danglingSharedStrings(&zr.Reader)
+			_ = zr.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", w.path, err)
+			}
+		}
+	}
+	return sheetNames, nil
+}
+
+func (w *workbookSource) Rows(sheet string) ([][]string, error) {
+	if w.text != nil {
+		return w.text.Rows(sheet)
+	}
+	rows, err := w.f.GetRows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	rows = trimGhostCells(rows)
+	w.warnDangling(sheet, blankDanglingStrings(rows, w.dangling[sheet]))
+	return rows, nil
+}
+
+// streamRows reads the sheets of workbooks of at least --stream-threshold
+// row by row.
+func (w *workbookSource) streamRows(sheet string) (rowIterator, bool, error) {
+	if !w.stream {
+		return nil, false, nil
+	}
+	it, err := w.f.Rows(sheet)
+	if err != nil {
+		return nil, false, err
+	}
+	return &workbookRows{xlsxRows: xlsxRows{it: it, dangling: danglingByRow(w.dangling[sheet])}, src: w, sheet: sheet}, true, nil
+}
+
+// workbookRows reports the blanked cells of a streamed sheet once it is read.
+type workbookRows struct {
+	xlsxRows
+	src   *workbookSource
+	sheet string
+}
+
+func (r *workbookRows) Close() error {
+	r.src.warnDangling(r.sheet, r.blanked)
+	return r.xlsxRows.Close()
+}
+
+func (w *workbookSource) warnDangling(sheet string, n int) {
+	if n > 0 {
+		w.opts.logger().Warn(fmt.Sprintf("%s[%s]: %d cells reference missing shared strings (WPS), read as empty", w.path, sheet, n), "path", w.path, "sheet", sheet)
+	}
+}
+
+func (w *workbookSource) Notes(sheet string) (map[string]string, error) {
+	if w.f == nil {
+		return nil, nil
+	}
+	return sheetNotes(w.f, sheet)
+}
+
+func (w *workbookSource) DocProps() *excelize.DocProperties {
+	if w.f == nil {
+		return nil
+	}
+	props, _ := w.f.GetDocProps() // core properties are optional
+	return props
+}
+
+func (w *workbookSource) origin(sheet string) string {
+	if w.text != nil {
+		return w.text.origin(sheet)
+	}
+	return fmt.Sprintf("%s[%s]", w.path, sheet)
+}
+
+func (w *workbookSource) Close() error {
+	if w.f == nil {
+		return nil
+	}
+	return w.f.Close()
+}
+
+// delimitedSource reads a single sheet, named after the file, from tab- or
+// comma-separated text.
+type delimitedSource struct {
+	path  string
+	comma rune
+}
+
+func (d *delimitedSource) Name() string { return d.path }
+
+func (d *delimitedSource) Sheets() ([]string, error) {
+	return []string{strings.TrimSuffix(filepath.Base(d.path), filepath.Ext(d.path))}, nil
+}
+
+func (d *delimitedSource) Rows(string) ([][]string, error) {
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	if d.comma == '\t' {
+		rows = parseTSV(data)
+	} else {
+		r := csv.NewReader(bytes.NewReader(data))
+		r.Comma = d.comma
+		r.FieldsPerRecord = -1
+		if rows, err = r.ReadAll(); err != nil {
+			return nil, err
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("empty file")
+	}
+	return rows, nil
+}
+
+func (d *delimitedSource) origin(string) string { return d.path }
+
+// MemorySource holds sheets built in code. Add them in output order:
+//
+//	src := NewMemorySource("db").Add("Item", [][]string{{"id#int", "name#string"}, {"1", "Sword"}})
+type MemorySource struct {
+	name   string
+	sheets []string
+	rows   map[string][][]string
+}
+
+func NewMemorySource(name string) *MemorySource {
+	return &MemorySource{name: name, rows: make(map[string][][]string)}
+}
+
+// Add adds or replaces a sheet; rows are cell text as a workbook holds it.
+func (m *MemorySource) Add(sheet string, rows [][]string) *MemorySource {
+	if _, ok := m.rows[sheet]; !ok {
+		m.sheets = append(m.sheets, sheet)
+	}
+	m.rows[sheet] = rows
+	return m
+}
+
+func (m *MemorySource) Name() string { return m.name }
+
+func (m *MemorySource) Sheets() ([]string, error) {
+	if len(m.sheets) == 0 {
+		return nil, fmt.Errorf("%s: no sheets", m.name)
+	}
+	return m.sheets, nil
+}
+
+func (m *MemorySource) Rows(sheet string) ([][]string, error) {
+	rows, ok := m.rows[sheet]
+	if !ok {
+		return nil, fmt.Errorf("no sheet %q", sheet)
+	}
+	return rows, nil
+}
+
+var _ io.Closer = (*workbookSource)(nil)
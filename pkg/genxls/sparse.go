@@ -0,0 +1,187 @@
+package genxls
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// markSparseSheets flags every sheet whose payload is smaller as a base row plus
+// per-row overrides than as a plain row array. Sparse sheets are written as
+//
+//	"monsters": {"base": {...}, "rows": [{"lv": 2}, {"lv": 3, "hp": 90}, ...]}
+//
+// and the generated loaders expand them back into full rows.
+func markSparseSheets(sheets []*Sheet) error {
+	for _, sheet := range sheets {
+		if len(sheet.Items) < 2 {
+			continue
+		}
+		dense, err := json.Marshal(jsonItems(sheet))
+		if err != nil {
+			return err
+		}
+		sparse, err := json.Marshal(sparsePayload(sheet))
+		if err != nil {
+			return err
+		}
+		sheet.Sparse = len(sparse) < len(dense)
+	}
+	return nil
+}
+
+func hasSparseSheets(sheets []*Sheet) bool {
+	for _, sheet := range sheets {
+		if sheet.Sparse {
+			return true
+		}
+	}
+	return false
+}
+
+// sparsePayload picks the most common value of every column as the base row
+// (first seen wins ties) and keeps only the differing columns per row.
+func sparsePayload(sheet *Sheet) map[string]any {
+	items := jsonItems(sheet)
+	enc := make([]map[string]string, len(items))
+	for i, item := range items {
+		enc[i] = make(map[string]string, len(sheet.Fields))
+		for _, f := range sheet.Fields {
+			data, _ := json.Marshal(item[f.RawName])
+			enc[i][f.RawName] = string(data)
+		}
+	}
+
+	base := make(map[string]any, len(sheet.Fields))
+	baseEnc := make(map[string]string, len(sheet.Fields))
+	for _, f := range sheet.Fields {
+		counts := make(map[string]int)
+		best := -1
+		for i := range items {
+			e := enc[i][f.RawName]
+			counts[e]++
+			if best < 0 || counts[e] > counts[enc[best][f.RawName]] {
+				best = i
+			}
+		}
+		base[f.RawName] = items[best][f.RawName]
+		baseEnc[f.RawName] = enc[best][f.RawName]
+	}
+
+	rows := make([]map[string]any, len(items))
+	for i, item := range items {
+		o := make(map[string]any)
+		for _, f := range sheet.Fields {
+			if enc[i][f.RawName] != baseEnc[f.RawName] {
+				o[f.RawName] = item[f.RawName]
+			}
+		}
+		rows[i] = o
+	}
+	return map[string]any{"base": base, "rows": rows}
+}
+
+func sparseKeys(sheets []*Sheet) []string {
+	var keys []string
+	for _, sheet := range sheets {
+		if sheet.Sparse {
+			keys = append(keys, sheet.JSONKey)
+		}
+	}
+	return keys
+}
+
+func quotedKeys(keys []string) string {
+	q := make([]string, len(keys))
+	for i, k := range keys {
+		q[i] = `"` + k + `"`
+	}
+	return strings.Join(q, ", ")
+}
+
+// goSparseLoader makes json.Unmarshal into the root struct expand sparse sheets.
+func goSparseLoader(rootName string, sheets []*Sheet) string {
+	var b strings.Builder
+	b.WriteString("// UnmarshalJSON expands sheets exported as a base row plus per-row overrides.\n")
+	b.WriteString("func (c *" + rootName + ") UnmarshalJSON(data []byte) error {\n")
+	b.WriteString("\tvar raw map[string]json.RawMessage\n")
+	b.WriteString("\tif err := json.Unmarshal(data, &raw); err != nil {\n\t\treturn err\n\t}\n")
+	b.WriteString("\tfor _, key := range []string{" + quotedKeys(sparseKeys(sheets)) + "} {\n")
+	b.WriteString("\t\tv := raw[key]\n")
+	b.WriteString("\t\tif len(v) == 0 || v[0] != '{' {\n\t\t\tcontinue\n\t\t}\n")
+	b.WriteString("\t\tvar sparse struct {\n")
+	b.WriteString("\t\t\tBase map[string]json.RawMessage   `json:\"base\"`\n")
+	b.WriteString("\t\t\tRows []map[string]json.RawMessage `json:\"rows\"`\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t\tif err := json.Unmarshal(v, &sparse); err != nil {\n\t\t\treturn err\n\t\t}\n")
+	b.WriteString("\t\trows := make([]map[string]json.RawMessage, len(sparse.Rows))\n")
+	b.WriteString("\t\tfor i, o := range sparse.Rows {\n")
+	b.WriteString("\t\t\trow := make(map[string]json.RawMessage, len(sparse.Base))\n")
+	b.WriteString("\t\t\tfor k, x := range sparse.Base {\n\t\t\t\trow[k] = x\n\t\t\t}\n")
+	b.WriteString("\t\t\tfor k, x := range o {\n\t\t\t\trow[k] = x\n\t\t\t}\n")
+	b.WriteString("\t\t\trows[i] = row\n\t\t}\n")
+	b.WriteString("\t\texpanded, err := json.Marshal(rows)\n")
+	b.WriteString("\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n")
+	b.WriteString("\t\traw[key] = expanded\n\t}\n")
+	b.WriteString("\tdata, err := json.Marshal(raw)\n")
+	b.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+	b.WriteString("\ttype plain " + rootName + "\n")
+	b.WriteString("\treturn json.Unmarshal(data, (*plain)(c))\n}\n")
+	return b.String()
+}
+
+// csSparseLoader is a static Load method for the root class.
+func csSparseLoader(rootName string, sheets []*Sheet) string {
+	var b strings.Builder
+	b.WriteString("    // Load expands sheets exported as a base row plus per-row overrides.\n")
+	b.WriteString("    public static " + rootName + " Load(string json)\n    {\n")
+	b.WriteString("        var root = JsonNode.Parse(json)!.AsObject();\n")
+	b.WriteString("        foreach (var key in new[] { " + quotedKeys(sparseKeys(sheets)) + " })\n        {\n")
+	b.WriteString("            if (root[key] is not JsonObject sparse)\n                continue;\n")
+	b.WriteString("            var baseRow = sparse[\"base\"]!.AsObject();\n")
+	b.WriteString("            var rows = new JsonArray();\n")
+	b.WriteString("            foreach (var o in sparse[\"rows\"]!.AsArray())\n            {\n")
+	b.WriteString("                var row = (JsonObject)baseRow.DeepClone();\n")
+	b.WriteString("                foreach (var kv in o!.AsObject())\n")
+	b.WriteString("                    row[kv.Key] = kv.Value?.DeepClone();\n")
+	b.WriteString("                rows.Add(row);\n            }\n")
+	b.WriteString("            root[key] = rows;\n        }\n")
+	b.WriteString("        return root.Deserialize<" + rootName + ">()!;\n    }\n\n")
+	return b.String()
+}
+
+func tsSparseLoader(rootName string, sheets []*Sheet) string {
+	var b strings.Builder
+	b.WriteString("\n// expand" + rootName + " expands sheets exported as a base row plus per-row overrides.\n")
+	b.WriteString("export function expand" + rootName + "(raw: any): " + rootName + " {\n")
+	b.WriteString("  const out = { ...raw };\n")
+	b.WriteString("  for (const key of [" + quotedKeys(sparseKeys(sheets)) + "]) {\n")
+	b.WriteString("    const v = raw[key];\n")
+	b.WriteString("    if (v && !Array.isArray(v)) {\n")
+	b.WriteString("      out[key] = v.rows.map((o: object) => ({ ...structuredClone(v.base), ...o }));\n")
+	b.WriteString("    }\n  }\n")
+	b.WriteString("  return out as " + rootName + ";\n}\n")
+	return b.String()
+}
+
+const gdSparseLoader = `
+static func _expand_sparse(v) -> Array:
+	if typeof(v) != TYPE_DICTIONARY:
+		return v
+	var out: Array = []
+	for o in v.get("rows", []):
+		var row: Dictionary = v.get("base", {}).duplicate(true)
+		row.merge(o, true)
+		out.append(row)
+	return out
+`
+
+const dartSparseLoader = `
+/// Expands a sheet exported as a base row plus per-row overrides.
+List<dynamic> _expandSparse(dynamic v) {
+  if (v is! Map<String, dynamic>) return v as List<dynamic>? ?? const [];
+  final base = v['base'] as Map<String, dynamic>;
+  return (v['rows'] as List<dynamic>)
+      .map((o) => <String, dynamic>{...base, ...(o as Map<String, dynamic>)})
+      .toList();
+}
+`
@@ -0,0 +1,120 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stateFileName is kept in --out and records what the previous run exported,
+// so the next run can compare against it.
+const stateFileName = ".genxls-state.json"
+
+type RunState struct {
+	Sheets map[string]*SheetState `json:"sheets"` // keyed by JSON key
+}
+
+type SheetState struct {
+	Columns []string          `json:"columns"`        // name#type in define-row order
+	Rows    map[string]string `json:"rows,omitempty"` // primary key -> row hash
+	// Values holds drift-limited columns: column -> primary key -> value.
+	Values map[string]map[string]float64 `json:"values,omitempty"`
+}
+
+func statePath(outDir string) string {
+	return filepath.Join(outDir, stateFileName)
+}
+
+// loadRunState reads the previous run's state; a missing file yields an empty state.
+func loadRunState(path string) (*RunState, error) {
+	st := &RunState{Sheets: make(map[string]*SheetState)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Sheets == nil {
+		st.Sheets = make(map[string]*SheetState)
+	}
+	return st, nil
+}
+
+func saveRunState(path string, st *RunState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func buildRunState(sheets []*Sheet) (*RunState, error) {
+	st := &RunState{Sheets: make(map[string]*SheetState, len(sheets))}
+	for _, sheet := range sheets {
+		rows, err := rowHashes(sheet)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := rowKeys(sheet)
+		if err != nil {
+			return nil, err
+		}
+		st.Sheets[sheet.JSONKey] = &SheetState{
+			Columns: sheetColumns(sheet),
+			Rows:    rows,
+			Values:  driftValues(sheet, keys),
+		}
+	}
+	return st, nil
+}
+
+func sheetColumns(sheet *Sheet) []string {
+	cols := make([]string, len(sheet.Fields))
+	for i, f := range sheet.Fields {
+		cols[i] = f.RawName + "#" + strings.ToLower(f.RawType)
+	}
+	return cols
+}
+
+// checkColumnOrder reports sheets whose columns are the same as in the previous
+// run but appear in a different order.
+func checkColumnOrder(prev *RunState, sheets []*Sheet) []Problem {
+	var reports []Problem
+	for _, sheet := range sheets {
+		old, ok := prev.Sheets[sheet.JSONKey]
+		if !ok {
+			continue
+		}
+		cur := sheetColumns(sheet)
+		if strings.Join(cur, ",") == strings.Join(old.Columns, ",") || !sameColumnSet(cur, old.Columns) {
+			continue
+		}
+		reports = append(reports, problemf(sheet, "%s: columns reordered\n  old: %s\n  new: %s",
+			sheet.Origin, strings.Join(old.Columns, ", "), strings.Join(cur, ", ")))
+	}
+	return reports
+}
+
+func sameColumnSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := append([]string(nil), a...)
+	y := append([]string(nil), b...)
+	sort.Strings(x)
+	sort.Strings(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
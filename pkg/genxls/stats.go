@@ -0,0 +1,108 @@
+package genxls
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+)
+
+// ColumnStats summarizes one numeric column of a sheet.
+type ColumnStats struct {
+	Field  Field
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	Median float64
+}
+
+// numericColumnStats computes stats for every int and float column with rows.
+func numericColumnStats(sheet *Sheet) []ColumnStats {
+	var out []ColumnStats
+	for _, f := range sheet.Fields {
+		if !isIntType(f.RawType) && !isFloatType(f.RawType) || len(sheet.Items) == 0 {
+			continue
+		}
+		vals := columnValues(sheet, f)
+		sorted := slices.Clone(vals)
+		slices.Sort(sorted)
+		sum := 0.0
+		for _, v := range vals {
+			sum += v
+		}
+		median := sorted[len(sorted)/2]
+		if len(sorted)%2 == 0 {
+			median = (sorted[len(sorted)/2-1] + median) / 2
+		}
+		out = append(out, ColumnStats{
+			Field:  f,
+			Count:  len(vals),
+			Min:    sorted[0],
+			Max:    sorted[len(sorted)-1],
+			Mean:   sum / float64(len(vals)),
+			Median: median,
+		})
+	}
+	return out
+}
+
+func columnValues(sheet *Sheet, f Field) []float64 {
+	vals := make([]float64, len(sheet.Items))
+	for i, item := range sheet.Items {
+		switch v := item[f.RawName].(type) {
+		case int:
+			vals[i] = float64(v)
+		case float64:
+			vals[i] = v
+		}
+	}
+	return vals
+}
+
+// findOutliers reports values at least factor times larger or smaller (in
+// magnitude) than their column's median, the typical extra or missing zero.
+// Zeros and columns with a zero median are skipped.
+func findOutliers(sheet *Sheet, stats []ColumnStats, factor float64) []Problem {
+	var hints []Problem
+	for _, st := range stats {
+		median := math.Abs(st.Median)
+		if median == 0 {
+			continue
+		}
+		for i, v := range columnValues(sheet, st.Field) {
+			a := math.Abs(v)
+			if a == 0 || (a < median*factor && a > median/factor) {
+				continue
+			}
+			p := problemf(sheet, "%s row %d (%s): %s is %sx the column median %s",
+				sheet.Origin, sheet.Rows[i], st.Field.RawName, formatStat(v), formatStat(v/st.Median), formatStat(st.Median))
+			p.Row, p.Col = sheet.cellOf(i, st.Field)
+			hints = append(hints, p)
+		}
+	}
+	return hints
+}
+
+func formatStat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// printColumnStats writes one aligned table of numeric column stats per sheet.
+func printColumnStats(out io.Writer, sheets []*Sheet, stats [][]ColumnStats) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, sheet := range sheets {
+		if len(stats[i]) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s\n", sheet.JSONKey)
+		fmt.Fprintln(w, "  column\tcount\tmin\tmax\tmean\tmedian")
+		for _, st := range stats[i] {
+			fmt.Fprintf(w, "  %s\t%d\t%s\t%s\t%s\t%s\n", st.Field.RawName, st.Count,
+				formatStat(st.Min), formatStat(st.Max), formatStat(st.Mean), formatStat(st.Median))
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,92 @@
+package genxls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CellMode controls how forgiving cell parsing is.
+type CellMode int
+
+const (
+	CellModeDefault CellMode = iota
+	// CellModeStrict rejects every cell the default mode would silently coerce.
+	CellModeStrict
+	// CellModeLenient turns unparsable cells into zero values with a warning.
+	CellModeLenient
+)
+
+// strictDefineRow rejects define rows with gaps before later definitions and
+// returns the index of the last defined column.
+func strictDefineRow(def []string, defineRow int) (int, error) {
+	last := -1
+	for i, c := range def {
+		if strings.TrimSpace(c) != "" {
+			last = i
+		}
+	}
+	for i := 0; i < last; i++ {
+		if strings.TrimSpace(def[i]) == "" {
+			return 0, fmt.Errorf("define row %d has an empty column %d before later definitions (--strict)", defineRow, i+1)
+		}
+	}
+	return last, nil
+}
+
+// strictRows rejects data cells in columns the define row doesn't declare,
+// right of column last, as the rows go by.
+type strictRows struct {
+	rowIterator
+	row, last int // row: 1-based sheet row of the next row
+}
+
+func (s *strictRows) Next() ([]string, error) {
+	row, err := s.rowIterator.Next()
+	if err != nil {
+		return nil, err
+	}
+	for c := s.last + 1; c < len(row); c++ {
+		if strings.TrimSpace(row[c]) != "" {
+			return nil, fmt.Errorf("row %d col %d: value %q in a column without field definition (--strict)", s.row, c+1, row[c])
+		}
+	}
+	s.row++
+	return row, nil
+}
+
+// coercionNote describes how the parser would have to coerce raw into the
+// field's type, or returns "" when raw is already canonical. Coercions the column
+// opted into are not reported; unparsable cells also return "" and are left to
+// parseFieldValue to report.
+func coercionNote(f Field, raw string) string {
+	s := strings.TrimSpace(raw)
+	if s != raw {
+		return "surrounding whitespace"
+	}
+	if s == "" {
+		return ""
+	}
+	switch strings.ToLower(f.RawType) {
+	case "int", "int32", "int64":
+		if n, err := strconv.Atoi(s); err == nil && strconv.Itoa(n) != s {
+			return fmt.Sprintf("integer %q is not written canonically as %d", s, n)
+		}
+	case "bool":
+		ls := strings.ToLower(s)
+		if f.Coerce.YesNo && (ls == "yes" || ls == "no" || ls == "y" || ls == "n") {
+			return ""
+		}
+		if ls != "true" && ls != "false" {
+			return fmt.Sprintf("bool written as %q instead of true/false", s)
+		}
+	case "int[]", "int[][]":
+		if strings.HasPrefix(s, "\"") {
+			return "array wrapped in quotes"
+		}
+		if !strings.HasPrefix(s, "{") {
+			return "array without braces"
+		}
+	}
+	return ""
+}
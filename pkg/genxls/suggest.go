@@ -0,0 +1,65 @@
+package genxls
+
+import (
+	"strings"
+)
+
+// SupportedTypes is the define-row type vocabulary, in documentation order.
+var SupportedTypes = []string{"int", "int32", "int64", "float", "float32", "float64", "bool", "string", "datetime", "int[]", "int[][]"}
+
+// fieldOptions are the options accepted after the type in a field definition.
+var fieldOptions = []string{"s", "c", "str", "sort", "key", "exact", "round", "floor", "ceil", "trunc", "thousands", "yesno", "unit"}
+
+// suggest returns the vocabulary entry closest to word, or "" when nothing is
+// close enough to be a plausible typo. Matching ignores case.
+func suggest(word string, vocab []string) string {
+	word = strings.ToLower(word)
+	best, bestDist := "", -1
+	for _, v := range vocab {
+		d := editDistance(word, strings.ToLower(v))
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = v, d
+		}
+	}
+	limit := len(word) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	if bestDist < 0 || bestDist > limit {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// Levenshtein plus adjacent transpositions, the most common typo ("flaot").
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func didYouMean(word string, vocab []string) string {
+	if s := suggest(word, vocab); s != "" {
+		return " (did you mean " + s + "?)"
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package genxls
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// printRunSummary lists every exported sheet with its row count, source and
+// who last modified the workbook.
+func printRunSummary(out io.Writer, sheets []*Sheet) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "sheet\trows\tsource\tlast modified by\tmodified")
+	for _, sheet := range sheets {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", sheet.JSONKey, len(sheet.Items), sheet.Origin,
+			orDash(sheet.ModifiedBy), orDash(sheet.Modified))
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
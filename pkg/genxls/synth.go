@@ -0,0 +1,185 @@
+package genxls
+
+import (
+	"errors"
+	"fmt"
+	rand "math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SynthSpec describes a synthetic workbook: the same spec and seed always give
+// the same cells, so tests (ours and those of teams writing their own loaders)
+// can generate inputs instead of checking in binary workbooks.
+type SynthSpec struct {
+	Seed   uint64       `json:"seed"`
+	Sheets []SynthSheet `json:"sheets"`
+}
+
+// SynthSheet is one sheet of a SynthSpec, laid out like a designer's: a
+// title row, a description row, the define row and Rows data rows.
+type SynthSheet struct {
+	Name    string   `json:"name"`
+	Rows    int      `json:"rows"`
+	Columns []string `json:"columns"` // define-row cells, e.g. "id#int" or "cost#int,s"; the first is the key
+	// Merged merges the title across the columns and, every tenth row, a
+	// non-key cell with the one below it. Loaders see the merged-away cell as
+	// empty, as Excel stores it.
+	Merged bool `json:"merged,omitempty"`
+	// Formulas makes the int and float cells after the key formulas of the
+	// (int) key in every other row, saved with their value as Excel does.
+	Formulas bool `json:"formulas,omitempty"`
+}
+
+// WriteSynthWorkbook writes the workbook of spec to path.
+func WriteSynthWorkbook(path string, spec SynthSpec) error {
+	f, err := NewSynthWorkbook(spec)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return f.SaveAs(path)
+}
+
+// NewSynthWorkbook builds the workbook of spec in memory; write it with
+// SaveAs or WriteTo, and Close it.
+func NewSynthWorkbook(spec SynthSpec) (*excelize.File, error) {
+	if len(spec.Sheets) == 0 {
+		return nil, errors.New("synth: no sheets")
+	}
+	f := excelize.NewFile()
+	rng := rand.New(rand.NewPCG(spec.Seed, 0))
+	for i, s := range spec.Sheets {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", s.Name); err != nil {
+				_ = f.Close()
+				return nil, fmt.Errorf("synth: sheet %q: %w", s.Name, err)
+			}
+		} else if _, err := f.NewSheet(s.Name); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("synth: sheet %q: %w", s.Name, err)
+		}
+		if err := writeSynthSheet(f, s, rng); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("synth: sheet %q: %w", s.Name, err)
+		}
+	}
+	return f, nil
+}
+
+func writeSynthSheet(f *excelize.File, s SynthSheet, rng *rand.Rand) error {
+	if s.Rows < 0 {
+		return fmt.Errorf("invalid rows %d", s.Rows)
+	}
+	fields, err := ParseFieldsFromDefineRow([][]string{s.Columns}, 1, "")
+	if err != nil {
+		return err
+	}
+	if len(fields) != len(s.Columns) {
+		return errors.New("every column needs a type (no #comment columns)")
+	}
+	switch key := strings.ToLower(fields[0].RawType); {
+	case key == "bool" && s.Rows > 2:
+		return fmt.Errorf("primary key %s is bool and can't have %d unique rows", fields[0].RawName, s.Rows)
+	case s.Formulas && key != "int" && key != "int32" && key != "int64":
+		return fmt.Errorf("formulas need an int primary key, not %s", fields[0].RawType)
+	}
+
+	set := func(col, row int, v any) error {
+		cell, err := excelize.CoordinatesToCellName(col, row)
+		if err != nil {
+			return err
+		}
+		return f.SetCellValue(s.Name, cell, v)
+	}
+	last, err := excelize.CoordinatesToCellName(len(fields), 1)
+	if err != nil {
+		return err
+	}
+	if err := set(1, 1, fmt.Sprintf("%s (%d rows)", s.Name, s.Rows)); err != nil {
+		return err
+	}
+	if s.Merged && len(fields) > 1 {
+		if err := f.MergeCell(s.Name, "A1", last); err != nil {
+			return err
+		}
+	}
+	for j, field := range fields {
+		if err := set(j+1, 2, field.RawName+" 说明"); err != nil {
+			return err
+		}
+		if err := set(j+1, 3, s.Columns[j]); err != nil {
+			return err
+		}
+	}
+
+	const firstRow = 4
+	for i := 0; i < s.Rows; i++ {
+		row := firstRow + i
+		for j, field := range fields {
+			v, err := FixtureValue(field, i, j == 0, rng)
+			if err != nil {
+				return err
+			}
+			// Numbers are stored as numbers, the rest as the text a designer types.
+			switch v.(type) {
+			case int, float64:
+			default:
+				if v, err = formatCellText(v); err != nil {
+					return err
+				}
+			}
+			if err := set(j+1, row, v); err != nil {
+				return err
+			}
+			if s.Formulas && j > 0 && i%2 == 1 {
+				if err := setSynthFormula(f, s.Name, field, j+1, row, i+1); err != nil {
+					return err
+				}
+			}
+		}
+		if s.Merged && len(fields) > 1 && i%10 == 0 && i+1 < s.Rows {
+			// The merged-away cell keeps no value, so the generated one is dropped.
+			col := 2 + rng.IntN(len(fields)-1)
+			top, _ := excelize.CoordinatesToCellName(col, row)
+			bottom, _ := excelize.CoordinatesToCellName(col, row+1)
+			if err := f.MergeCell(s.Name, top, bottom); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setSynthFormula turns an int or float cell into a formula of the key cell
+// (column A, which holds key, an int), with its value computed here as Excel
+// would cache it. Other cells are left as they are.
+func setSynthFormula(f *excelize.File, sheet string, field Field, col, row, key int) error {
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return err
+	}
+	var value any
+	formula := fmt.Sprintf("A%d*%d", row, col)
+	switch strings.ToLower(field.RawType) {
+	case "int", "int32", "int64":
+		value = key * col
+	case "float", "float32", "float64":
+		value = float64(key*col) / 4
+		formula += "/4"
+	default:
+		return nil
+	}
+	if err := f.SetCellValue(sheet, cell, value); err != nil {
+		return err
+	}
+	return f.SetCellFormula(sheet, cell, formula)
+}
@@ -0,0 +1,119 @@
+package genxls
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestSynthRoundTrip(t *testing.T) {
+	spec := SynthSpec{Seed: 42, Sheets: []SynthSheet{
+		{Name: "Item", Rows: 200, Columns: []string{"id#int", "count#int", "rate#float", "name#string", "ok#bool", "at#datetime", "tags#int[]", "grid#int[][]"}, Merged: true, Formulas: true},
+		{Name: "Npc", Rows: 30, Columns: []string{"key#string", "hp#int64", "speed#float32"}, Merged: true},
+	}}
+	f, err := NewSynthWorkbook(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	path := filepath.Join(t.TempDir(), "synth.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stream := range []int64{0, 1} {
+		opts := Options{StreamSize: stream}
+		sheets, err := LoadSources(context.Background(), FileSources([]string{path}, opts, nil), opts, nil)
+		if err != nil {
+			t.Fatalf("stream size %d: %v", stream, err)
+		}
+		if len(sheets) != len(spec.Sheets) {
+			t.Fatalf("stream size %d: loaded %d sheets, want %d", stream, len(sheets), len(spec.Sheets))
+		}
+		for i, s := range spec.Sheets {
+			checkSynthSheet(t, f, s, sheets[i])
+		}
+	}
+}
+
+// checkSynthSheet compares a loaded sheet with the cells the generator wrote,
+// and the formula and merged cells with what Excel would show.
+func checkSynthSheet(t *testing.T, f *excelize.File, s SynthSheet, sheet *Sheet) {
+	t.Helper()
+	if len(sheet.Items) != s.Rows {
+		t.Fatalf("%s: %d rows, want %d", s.Name, len(sheet.Items), s.Rows)
+	}
+	rows, err := f.GetRows(s.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const firstRow = 4
+	for i, item := range sheet.Items {
+		if sheet.Rows[i] != firstRow+i {
+			t.Errorf("%s: item %d from row %d, want %d", s.Name, i, sheet.Rows[i], firstRow+i)
+		}
+		for j, field := range sheet.Fields {
+			cell := ""
+			if j < len(rows[firstRow-1+i]) {
+				cell = rows[firstRow-1+i][j]
+			}
+			want, err := parseFieldValue(field, cell)
+			if err != nil {
+				t.Fatalf("%s: row %d col %d: %v", s.Name, firstRow+i, j+1, err)
+			}
+			if got := item[field.RawName]; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: row %d %s = %#v, want %#v", s.Name, firstRow+i, field.RawName, got, want)
+			}
+			if !s.Formulas || j == 0 || i%2 == 0 || cell == "" {
+				continue
+			}
+			// Formula cells load as their cached value, not the formula;
+			// merged-away ones are empty and checked below.
+			switch key := item[sheet.Fields[0].RawName].(int); strings.ToLower(field.RawType) {
+			case "int":
+				if got := item[field.RawName]; got != key*(j+1) {
+					t.Errorf("%s: formula cell row %d %s = %v, want %d", s.Name, firstRow+i, field.RawName, got, key*(j+1))
+				}
+			case "float":
+				if got := item[field.RawName]; got != float64(key*(j+1))/4 {
+					t.Errorf("%s: formula cell row %d %s = %v, want %v", s.Name, firstRow+i, field.RawName, got, float64(key*(j+1))/4)
+				}
+			}
+		}
+	}
+
+	merged, err := f.GetMergeCells(s.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := 0
+	for _, m := range merged {
+		col, top, err := excelize.CellNameToCoordinates(m.GetStartAxis())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if top < firstRow {
+			continue // the title
+		}
+		data++
+		field := sheet.Fields[col-1]
+		zero, _ := parseCellValue(field.RawType, "")
+		if got := sheet.Items[top-firstRow+1][field.RawName]; !reflect.DeepEqual(got, zero) {
+			t.Errorf("%s: merged-away cell %s = %#v, want the zero value", s.Name, m.GetEndAxis(), got)
+		}
+		want, err := parseFieldValue(field, m.GetCellValue())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sheet.Items[top-firstRow][field.RawName]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: merged cell %s = %#v, want %#v", s.Name, m.GetStartAxis(), got, want)
+		}
+	}
+	if want := (s.Rows - 1 + 9) / 10; data != want {
+		t.Errorf("%s: %d merged data cells, want %d", s.Name, data, want)
+	}
+}
@@ -0,0 +1,162 @@
+package genxls
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Template rows repeat a group of rows once per parameter set, e.g. one quest
+// chain per faction. All markers go in the primary key cell:
+//
+//	@params faction           declares a block; the row's other cells name its variables
+//	@values faction           one parameter set; cells under the variable names hold the values
+//	@template faction : KEY   a row repeated per parameter set; KEY is its primary key template
+//
+// Consecutive @template rows of one block form a group that is repeated as a
+// whole, in @values order. Cells use ${var} and ${expr} as in @expand rows; i
+// is the 0-based index of the parameter set.
+var (
+	paramsMarkerRe   = regexp.MustCompile(`^@params\s+(\w+)$`)
+	valuesMarkerRe   = regexp.MustCompile(`^@values\s+(\w+)$`)
+	templateMarkerRe = regexp.MustCompile(`^@template\s+(\w+)\s*:\s*(.+)$`)
+	paramNameRe      = regexp.MustCompile(`^[A-Za-z]\w*$`)
+)
+
+// sourceRow is a data row to parse and the 1-based sheet row it came from.
+type sourceRow struct {
+	cells []string
+	row   int
+}
+
+type paramBlock struct {
+	row    int // @params row, for errors
+	names  map[int]string
+	values []map[string]string
+}
+
+// expandTemplateRows returns the non-empty rows, the first of which is sheet
+// row base+1, with parameter blocks removed and template groups repeated per
+// parameter set.
+func expandTemplateRows(rows [][]string, base, keyCol int) ([]sourceRow, error) {
+	keyOf := func(row []string) string { return rowKey(row, keyCol) }
+
+	blocks := make(map[string]*paramBlock)
+	for r := 0; r < len(rows); r++ {
+		m := paramsMarkerRe.FindStringSubmatch(keyOf(rows[r]))
+		if m == nil {
+			continue
+		}
+		if prev, ok := blocks[m[1]]; ok {
+			return nil, fmt.Errorf("row %d: @params %s already declared at row %d", base+r+1, m[1], prev.row)
+		}
+		b := &paramBlock{row: base + r + 1, names: make(map[int]string)}
+		for c, cell := range rows[r] {
+			name := strings.TrimSpace(cell)
+			if c == keyCol || name == "" {
+				continue
+			}
+			if !paramNameRe.MatchString(name) || name == "n" || name == "i" {
+				return nil, fmt.Errorf("row %d col %d: invalid parameter name %q", base+r+1, c+1, name)
+			}
+			b.names[c] = name
+		}
+		blocks[m[1]] = b
+	}
+	for r := 0; r < len(rows); r++ {
+		key := keyOf(rows[r])
+		m := valuesMarkerRe.FindStringSubmatch(key)
+		if m == nil {
+			if strings.HasPrefix(key, "@values") || strings.HasPrefix(key, "@params") && !paramsMarkerRe.MatchString(key) ||
+				strings.HasPrefix(key, "@template") && !templateMarkerRe.MatchString(key) {
+				return nil, fmt.Errorf("row %d: invalid marker %q (expect @params NAME, @values NAME or @template NAME : KEY)", base+r+1, key)
+			}
+			continue
+		}
+		b, ok := blocks[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("row %d: @values for undeclared @params %s", base+r+1, m[1])
+		}
+		vals := make(map[string]string, len(b.names))
+		for c, name := range b.names {
+			if c < len(rows[r]) {
+				vals[name] = strings.TrimSpace(rows[r][c])
+			} else {
+				vals[name] = ""
+			}
+		}
+		b.values = append(b.values, vals)
+	}
+
+	var out []sourceRow
+	for r := 0; r < len(rows); r++ {
+		row := rows[r]
+		if isEmptyRow(row) {
+			continue
+		}
+		key := keyOf(row)
+		if paramsMarkerRe.MatchString(key) || valuesMarkerRe.MatchString(key) {
+			continue
+		}
+		m := templateMarkerRe.FindStringSubmatch(key)
+		if m == nil {
+			out = append(out, sourceRow{cells: row, row: base + r + 1})
+			continue
+		}
+		b, ok := blocks[m[1]]
+		if !ok {
+			return nil, fmt.Errorf("row %d: @template for undeclared @params %s", base+r+1, m[1])
+		}
+		if len(b.values) == 0 {
+			return nil, fmt.Errorf("row %d: @params %s has no @values rows", base+r+1, m[1])
+		}
+		// Collect the group: this and the following @template rows of the block.
+		group := []int{r}
+		for r+1 < len(rows) {
+			next := templateMarkerRe.FindStringSubmatch(keyOf(rows[r+1]))
+			if next == nil || next[1] != m[1] {
+				break
+			}
+			r++
+			group = append(group, r)
+		}
+		for i, vals := range b.values {
+			env := exprEnv{vars: map[string]float64{"i": float64(i)}, strs: vals}
+			for name, v := range vals {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					env.vars[name] = f
+				}
+			}
+			for _, g := range group {
+				cells := make([]string, len(rows[g]))
+				for c, s := range rows[g] {
+					if c == keyCol {
+						s = templateMarkerRe.FindStringSubmatch(keyOf(rows[g]))[2]
+					}
+					v, err := interpolateExprs(s, &env)
+					if err != nil {
+						return nil, fmt.Errorf("row %d col %d (@values %d of %s): %w", g+1, c+1, i+1, m[1], err)
+					}
+					cells[c] = v
+				}
+				out = append(out, sourceRow{cells: cells, row: base + g + 1})
+			}
+		}
+	}
+	return out, nil
+}
+
+// rowKey returns the trimmed primary key cell of row.
+func rowKey(row []string, keyCol int) string {
+	if keyCol < 0 || keyCol >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[keyCol])
+}
+
+// isTemplateMarker reports whether key starts a template block row, valid or
+// not.
+func isTemplateMarker(key string) bool {
+	return strings.HasPrefix(key, "@params") || strings.HasPrefix(key, "@values") || strings.HasPrefix(key, "@template")
+}
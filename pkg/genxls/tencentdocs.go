@@ -0,0 +1,120 @@
+package genxls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// tencentDocsAPI is the Tencent Docs open API; a variable so it can be pointed
+// at a fake with -ldflags.
+var tencentDocsAPI = "https://docs.qq.com/openapi"
+
+// tencentDocsConnector reads Tencent Docs folders (--in tencent:<folderId>).
+type tencentDocsConnector struct {
+	client *remoteClient
+}
+
+// newTencentDocsConnector authenticates as the app and user given by
+// TENCENT_DOCS_CLIENT_ID, TENCENT_DOCS_OPEN_ID and TENCENT_DOCS_ACCESS_TOKEN,
+// or clientId, openId and accessToken in the config's "tencent" entry.
+func newTencentDocsConnector(cfg *Config) (remoteConnector, error) {
+	clientID := connectorSetting(cfg, "tencent", "TENCENT_DOCS_CLIENT_ID", "clientId")
+	openID := connectorSetting(cfg, "tencent", "TENCENT_DOCS_OPEN_ID", "openId")
+	token := connectorSetting(cfg, "tencent", "TENCENT_DOCS_ACCESS_TOKEN", "accessToken")
+	if clientID == "" || openID == "" || token == "" {
+		return nil, errors.New("set TENCENT_DOCS_CLIENT_ID, TENCENT_DOCS_OPEN_ID and TENCENT_DOCS_ACCESS_TOKEN")
+	}
+	return &tencentDocsConnector{client: &remoteClient{
+		every: time.Second / 5,
+		auth: func(req *http.Request) {
+			req.Header.Set("Access-Token", token)
+			req.Header.Set("Client-Id", clientID)
+			req.Header.Set("Open-Id", openID)
+		},
+	}}, nil
+}
+
+// call sends an API request and decodes the data of its response into v.
+func (c *tencentDocsConnector) call(method, path string, v any) error {
+	data, err := c.client.do(method, tencentDocsAPI+path, nil)
+	if err != nil {
+		return err
+	}
+	var r struct {
+		Ret  int             `json:"ret"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if r.Ret != 0 {
+		return fmt.Errorf("%s: %s (ret %d)", path, r.Msg, r.Ret)
+	}
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// list returns the online sheets of a folder, which change with their
+// modification time.
+func (c *tencentDocsConnector) list(folder string) ([]remoteFile, error) {
+	var files []remoteFile
+	for start := 0; ; {
+		q := url.Values{"start": {strconv.Itoa(start)}, "limit": {"20"}}
+		var page struct {
+			List []struct {
+				ID             string `json:"ID"`
+				Title          string `json:"title"`
+				Type           string `json:"type"`
+				LastModifyTime int64  `json:"lastModifyTime"`
+			} `json:"list"`
+			Next int `json:"next"`
+		}
+		if err := c.call(http.MethodGet, "/drive/v2/folders/"+url.PathEscape(folder)+"?"+q.Encode(), &page); err != nil {
+			return nil, err
+		}
+		for _, f := range page.List {
+			if f.Type == "sheet" {
+				files = append(files, remoteFile{ID: f.ID, Name: exportedName(f.Title), Revision: strconv.FormatInt(f.LastModifyTime, 10)})
+			}
+		}
+		if page.Next <= start || len(page.List) == 0 {
+			return files, nil
+		}
+		start = page.Next
+	}
+}
+
+// download exports a sheet to .xlsx and fetches the export.
+func (c *tencentDocsConnector) download(f remoteFile) ([]byte, error) {
+	var op struct {
+		OperationID string `json:"operationID"`
+	}
+	path := "/drive/v2/files/" + url.PathEscape(f.ID)
+	if err := c.call(http.MethodPost, path+"/async-export", &op); err != nil {
+		return nil, err
+	}
+	var exported string
+	err := pollExport(f.Name, func() (bool, error) {
+		var progress struct {
+			Progress int    `json:"progress"`
+			URL      string `json:"url"`
+		}
+		if err := c.call(http.MethodGet, path+"/export-progress?"+url.Values{"operationID": {op.OperationID}}.Encode(), &progress); err != nil {
+			return false, err
+		}
+		exported = progress.URL
+		return progress.Progress >= 100 && exported != "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.client.get(exported)
+}
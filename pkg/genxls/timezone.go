@@ -0,0 +1,54 @@
+package genxls
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // designers' Windows machines have no zone database
+)
+
+// DateTimeZones normalizes datetime cells: they are read as wall clock times
+// in In, the zone designers enter them in, and written as RFC 3339 in Out, the
+// zone servers expect (UTC unless the config says otherwise).
+type DateTimeZones struct {
+	In  *time.Location
+	Out *time.Location
+}
+
+// loadZones returns the zones of the config "timezone" and "outputTimezone",
+// or nil when no timezone is set and datetime values stay naive.
+func loadZones(in, out string) (*DateTimeZones, error) {
+	if in == "" {
+		if out != "" {
+			return nil, errors.New("outputTimezone requires timezone, the zone datetime cells are entered in")
+		}
+		return nil, nil
+	}
+	z := &DateTimeZones{Out: time.UTC}
+	var err error
+	if z.In, err = time.LoadLocation(in); err != nil {
+		return nil, fmt.Errorf("timezone: %w", err)
+	}
+	if out != "" {
+		if z.Out, err = time.LoadLocation(out); err != nil {
+			return nil, fmt.Errorf("outputTimezone: %w", err)
+		}
+	}
+	return z, nil
+}
+
+// dateTimeZones returns the zones of the config, or nil. c may be nil.
+func (c *Config) dateTimeZones() *DateTimeZones {
+	if c == nil {
+		return nil
+	}
+	return c.zones
+}
+
+// format writes t as the payload holds it; a nil z keeps it naive.
+func (z *DateTimeZones) format(t time.Time) string {
+	if z == nil {
+		return formatDateTime(t)
+	}
+	return t.In(z.Out).Format(time.RFC3339)
+}
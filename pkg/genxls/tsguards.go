@@ -0,0 +1,91 @@
+package genxls
+
+import (
+	"strconv"
+	"strings"
+)
+
+// tsGuardHelpers are emitted only when a guard uses them, so projects built
+// with noUnusedLocals don't trip over them.
+var tsGuardHelpers = []struct{ name, code string }{
+	{"isIntArray", "function isIntArray(v: unknown): v is number[] {\n  return Array.isArray(v) && v.every((x) => Number.isInteger(x));\n}\n"},
+	{"isIntMatrix", "function isIntMatrix(v: unknown): v is number[][] {\n  return Array.isArray(v) && v.every(isIntArray);\n}\n"},
+	{"isArrayOf", "function isArrayOf<T>(v: unknown, guard: (x: unknown) => x is T): v is T[] {\n  return Array.isArray(v) && v.every((x) => guard(x));\n}\n"},
+	{"isRecord", "function isRecord(v: unknown): v is Record<string, unknown> {\n  return typeof v === \"object\" && v !== null && !Array.isArray(v);\n}\n"},
+}
+
+// tsGuardCheck returns the expression checking the column value acc.
+func tsGuardCheck(f Field, acc string) string {
+	if f.JSONString {
+		return "typeof " + acc + " === \"string\""
+	}
+	switch strings.ToLower(f.RawType) {
+	case "int", "int32", "int64":
+		return "Number.isInteger(" + acc + ")"
+	case "float", "float32", "float64":
+		return "typeof " + acc + " === \"number\""
+	case "int[]":
+		return "isIntArray(" + acc + ")"
+	case "int[][]":
+		return "isIntMatrix(" + acc + ")"
+	case "bool":
+		return "typeof " + acc + " === \"boolean\""
+	default:
+		return "typeof " + acc + " === \"string\""
+	}
+}
+
+// tsTypeGuards renders is<Type>(obj) for every sheet and is<Root>(obj) for the
+// whole payload. They check the shape of the interfaces (sparse sheets must be
+// expanded first); extra keys are allowed.
+func tsTypeGuards(rootName string, sheets []*Sheet) string {
+	var guards strings.Builder
+	for _, sheet := range sheets {
+		guards.WriteString("\nexport function is" + sheet.TypeName + "(obj: unknown): obj is " + sheet.TypeName + " {\n")
+		guards.WriteString("  if (!isRecord(obj)) return false;\n")
+		guards.WriteString("  return (")
+		for i, f := range sheet.Fields {
+			if i > 0 {
+				guards.WriteString(" &&")
+			}
+			guards.WriteString("\n    " + tsGuardCheck(f, "obj["+strconv.Quote(f.RawName)+"]"))
+		}
+		if len(sheet.Fields) == 0 {
+			guards.WriteString("true")
+		}
+		guards.WriteString("\n  );\n}\n")
+	}
+	guards.WriteString("\nexport function is" + rootName + "(obj: unknown): obj is " + rootName + " {\n")
+	guards.WriteString("  if (!isRecord(obj)) return false;\n")
+	guards.WriteString("  return (")
+	for i, sheet := range sheets {
+		if i > 0 {
+			guards.WriteString(" &&")
+		}
+		guards.WriteString("\n    isArrayOf(obj[" + strconv.Quote(sheet.JSONKey) + "], is" + sheet.TypeName + ")")
+	}
+	if len(sheets) == 0 {
+		guards.WriteString("true")
+	}
+	guards.WriteString("\n  );\n}\n")
+
+	// Helpers may call earlier ones, so scan them last to first.
+	used := guards.String()
+	emit := make([]bool, len(tsGuardHelpers))
+	for i := len(tsGuardHelpers) - 1; i >= 0; i-- {
+		h := tsGuardHelpers[i]
+		if strings.Contains(used, h.name+"(") || strings.Contains(used, "("+h.name+")") {
+			emit[i] = true
+			used += h.code
+		}
+	}
+	var b strings.Builder
+	for i, h := range tsGuardHelpers {
+		if emit[i] {
+			b.WriteString("\n")
+			b.WriteString(h.code)
+		}
+	}
+	b.WriteString(guards.String())
+	return b.String()
+}
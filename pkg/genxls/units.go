@@ -0,0 +1,101 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// units are the units a column may be annotated with (",unit(ms)") or
+// converted between (",unit(s->ms)"): the dimension and the size in its base
+// unit. Only units of one dimension convert into each other.
+var units = map[string]struct {
+	dim   string
+	scale float64
+}{
+	"ms": {"time", 0.001}, "s": {"time", 1}, "min": {"time", 60}, "h": {"time", 3600}, "d": {"time", 86400},
+	"mm": {"length", 0.001}, "cm": {"length", 0.01}, "m": {"length", 1}, "km": {"length", 1000},
+	"ratio": {"ratio", 1}, "pct": {"ratio", 0.01}, "permille": {"ratio", 0.001}, "bp": {"ratio", 0.0001},
+}
+
+func unitNames() []string {
+	names := make([]string, 0, len(units))
+	for u := range units {
+		names = append(names, u)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnitConversion converts a column from the unit designers enter (From) to
+// the one it is exported in (To).
+type UnitConversion struct {
+	From, To string
+	Factor   float64
+}
+
+// parseUnitOption parses the argument of ",unit(...)": "ms" documents the
+// exported unit, "s->ms" also converts. conv is nil without a conversion.
+func parseUnitOption(arg string) (unit string, conv *UnitConversion, err error) {
+	from, to, converts := strings.Cut(arg, "->")
+	from, to = strings.ToLower(strings.TrimSpace(from)), strings.ToLower(strings.TrimSpace(to))
+	if !converts {
+		to = from
+	}
+	for _, u := range []string{from, to} {
+		if _, ok := units[u]; !ok {
+			return "", nil, fmt.Errorf("unknown unit %q%s; supported units: %s", u, didYouMean(u, unitNames()), strings.Join(unitNames(), ", "))
+		}
+	}
+	if !converts || from == to {
+		return to, nil, nil
+	}
+	if units[from].dim != units[to].dim {
+		return "", nil, fmt.Errorf("can't convert %s (%s) to %s (%s)", from, units[from].dim, to, units[to].dim)
+	}
+	return to, &UnitConversion{From: from, To: to, Factor: units[from].scale / units[to].scale}, nil
+}
+
+// convert applies the conversion to a parsed value. Int columns must come out
+// whole: 1500 ms is no int number of seconds.
+func (u *UnitConversion) convert(rawType string, v any) (any, error) {
+	switch x := v.(type) {
+	case float64:
+		return x * u.Factor, nil
+	case int:
+		return u.convertInt(rawType, x)
+	case []int:
+		out := make([]int, len(x))
+		for i, n := range x {
+			c, err := u.convertInt(rawType, n)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unit conversion of %T", v)
+	}
+}
+
+func (u *UnitConversion) convertInt(rawType string, n int) (int, error) {
+	r := float64(n) * u.Factor
+	whole := math.Round(r)
+	if math.Abs(r-whole) > 1e-9*math.Max(1, math.Abs(r)) {
+		return 0, fmt.Errorf("%d %s is %g %s, not a whole number", n, u.From, r, u.To)
+	}
+	if whole < math.MinInt64 || whole >= math.MaxInt64 {
+		return 0, fmt.Errorf("%d %s is out of integer range in %s", n, u.From, u.To)
+	}
+	return int(whole), checkIntRange(rawType, int(whole))
+}
+
+// unitDoc is the line added to a column's doc comment for its unit.
+func unitDoc(f Field) string {
+	if f.Coerce.Unit != nil {
+		return fmt.Sprintf("Unit: %s (entered in %s).", f.Unit, f.Coerce.Unit.From)
+	}
+	return fmt.Sprintf("Unit: %s.", f.Unit)
+}
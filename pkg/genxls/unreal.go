@@ -0,0 +1,153 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ueIntArrayStruct wraps TArray<int32> because UPROPERTY cannot nest TArrays.
+const ueIntArrayStruct = "FGenxlsIntArray"
+
+func mapUEType(t string) (string, bool) {
+	switch strings.ToLower(t) {
+	case "int", "int32":
+		return "int32", true
+	case "int64":
+		return "int64", true
+	case "int[]":
+		return "TArray<int32>", true
+	case "int[][]":
+		return "TArray<" + ueIntArrayStruct + ">", true
+	case "float", "float32":
+		return "float", true
+	case "float64":
+		return "double", true
+	case "bool":
+		return "bool", true
+	case "string", "datetime":
+		return "FString", true
+	default:
+		return "", false
+	}
+}
+
+func GenerateUEBundle(sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	b.WriteString("#pragma once\n\n")
+	b.WriteString("#include \"CoreMinimal.h\"\n")
+	b.WriteString("#include \"Engine/DataTable.h\"\n")
+	b.WriteString("#include \"ue.gen.generated.h\"\n\n")
+
+	b.WriteString("USTRUCT(BlueprintType)\n")
+	b.WriteString("struct ")
+	b.WriteString(ueIntArrayStruct)
+	b.WriteString("\n{\n\tGENERATED_BODY()\n\n")
+	b.WriteString("\tUPROPERTY(EditAnywhere, BlueprintReadOnly, Category = \"Config\")\n")
+	b.WriteString("\tTArray<int32> Values;\n};\n\n")
+
+	for _, sheet := range sheets {
+		b.WriteString("USTRUCT(BlueprintType)\n")
+		b.WriteString("struct F")
+		b.WriteString(sheet.TypeName)
+		b.WriteString(" : public FTableRowBase\n{\n\tGENERATED_BODY()\n")
+		for _, f := range sheet.Fields {
+			ueType, ok := mapUEType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("unsupported type %q", f.RawType)
+			}
+			b.WriteString("\n")
+			writeJSDocComment(&b, "\t", f.Doc)
+			b.WriteString("\tUPROPERTY(EditAnywhere, BlueprintReadOnly, Category = \"Config\")\n\t")
+			b.WriteString(ueType)
+			b.WriteString(" ")
+			b.WriteString(f.Name)
+			b.WriteString(ueZeroInit(f.RawType))
+			b.WriteString(";\n")
+		}
+		b.WriteString("};\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+func ueZeroInit(rawType string) string {
+	switch strings.ToLower(rawType) {
+	case "int", "int32", "int64":
+		return " = 0"
+	case "float", "float32":
+		return " = 0.f"
+	case "float64":
+		return " = 0.0"
+	case "bool":
+		return " = false"
+	default:
+		return ""
+	}
+}
+
+// generateUECSV renders rows in the layout Unreal's DataTable CSV importer
+// expects: a leading row-name column (the first exported field) followed by
+// one column per property, arrays written as (a,b,c).
+func generateUECSV(fields []Field, items []map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"---"}
+	for _, f := range fields {
+		header = append(header, f.Name)
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		rowName, err := ueCSVValue(item[fields[0].RawName])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		record := []string{rowName}
+		for _, f := range fields {
+			v, err := ueCSVValue(item[f.RawName])
+			if err != nil {
+				return nil, fmt.Errorf("row %d (%s): %w", i+1, f.RawName, err)
+			}
+			record = append(record, v)
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func ueCSVValue(v any) (string, error) {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	case bool:
+		if x {
+			return "True", nil
+		}
+		return "False", nil
+	case string:
+		return x, nil
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return "(" + strings.Join(parts, ",") + ")", nil
+	case [][]int:
+		parts := make([]string, len(x))
+		for i, inner := range x {
+			s, _ := ueCSVValue(inner)
+			parts[i] = "(Values=" + s + ")"
+		}
+		return "(" + strings.Join(parts, ",") + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
@@ -0,0 +1,169 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// AddSchemaValidations sets a rule on the data rows of every typed column of
+// every sheet with a define row and returns how many it set. Ref columns get a
+// drop-down of the target column when the target sheet is in the same
+// workbook. Rules from an earlier run (same column range) are replaced, others
+// are kept.
+func AddSchemaValidations(f *excelize.File) (int, error) {
+	// Sheets are named like the export names them, so refs find their
+	// targets the same way resolveRefs does.
+	var sheets []*Sheet
+	dataRow := make(map[*Sheet]int)
+	for _, name := range f.GetSheetList() {
+		rows, err := f.GetRows(name)
+		if err != nil {
+			return 0, err
+		}
+		spec, err := detectHeaderSpec(rows)
+		if err != nil || spec.Orientation != OrientationHorizontal {
+			continue
+		}
+		fields, err := ParseFieldsFromDefineRow(rows, spec.DefineRow, "")
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		base := exportName(name)
+		sheet := &Sheet{Name: name, TypeName: base, FieldName: pluralizeTypeName(base), Fields: fields}
+		sheet.JSONKey = lowerFirst(sheet.FieldName)
+		sheets = append(sheets, sheet)
+		dataRow[sheet] = spec.DefineRow + 1
+	}
+
+	count := 0
+	for _, sheet := range sheets {
+		name := sheet.Name
+		var rules []*excelize.DataValidation
+		managed := make(map[string]bool)
+		for _, field := range sheet.Fields {
+			col, err := excelize.ColumnNumberToName(field.Col + 1)
+			if err != nil {
+				return 0, err
+			}
+			var dv *excelize.DataValidation
+			if field.Ref != nil {
+				dv, err = refValidation(field, sheets, dataRow)
+			} else {
+				dv, err = fieldValidation(field, fmt.Sprintf("%s%d", col, dataRow[sheet]))
+			}
+			if err != nil {
+				return 0, fmt.Errorf("%s (%s): %w", name, field.RawName, err)
+			}
+			if dv == nil {
+				continue
+			}
+			dv.Sqref = fmt.Sprintf("%s%d:%s%d", col, dataRow[sheet], col, excelize.TotalRows)
+			managed[dv.Sqref] = true
+			rules = append(rules, dv)
+		}
+
+		existing, err := f.GetDataValidations(name)
+		if err != nil {
+			return 0, err
+		}
+		if err := f.DeleteDataValidation(name); err != nil {
+			return 0, err
+		}
+		for _, dv := range existing {
+			if managed[dv.Sqref] {
+				continue
+			}
+			// GetDataValidations unescapes formulas and AddDataValidation
+			// doesn't escape them again.
+			dv.Formula1, dv.Formula2 = escapeValidationFormula(dv.Formula1), escapeValidationFormula(dv.Formula2)
+			if err := f.AddDataValidation(name, dv); err != nil {
+				return 0, err
+			}
+		}
+		for _, dv := range rules {
+			if err := f.AddDataValidation(name, dv); err != nil {
+				return 0, err
+			}
+		}
+		count += len(rules)
+	}
+	return count, nil
+}
+
+// fieldValidation returns the rule for a column type, or nil for free text.
+// first is the column's first data cell, which relative references in custom
+// formulas are based on.
+func fieldValidation(f Field, first string) (*excelize.DataValidation, error) {
+	dv := excelize.NewDataValidation(true)
+	var err error
+	var expect string
+	switch t := strings.ToLower(f.RawType); {
+	case isIntType(t) && f.Coerce.FloatToInt == "":
+		lo, hi := math.MinInt64, math.MaxInt64 // int is 64-bit in the generated code
+		if t == "int32" {
+			lo, hi = math.MinInt32, math.MaxInt32
+		}
+		err = dv.SetRange(lo, hi, excelize.DataValidationTypeWhole, excelize.DataValidationOperatorBetween)
+		expect = "a whole number (" + t + ")"
+	case isIntType(t) || isFloatType(t):
+		err = dv.SetRange("-1E+307", "1E+307", excelize.DataValidationTypeDecimal, excelize.DataValidationOperatorBetween)
+		expect = "a number (" + t + ")"
+	case t == "bool":
+		values := []string{"TRUE", "FALSE"}
+		if f.Coerce.YesNo {
+			values = []string{"yes", "no"}
+		}
+		err = dv.SetDropList(values)
+		expect = strings.Join(values, " or ")
+	case t == "datetime":
+		err = dv.SetRange(1, 2958465, excelize.DataValidationTypeDate, excelize.DataValidationOperatorBetween)
+		expect = "a date and time"
+	case t == "int[]" || t == "int[][]":
+		dv.Type = "custom"
+		dv.Formula1 = fmt.Sprintf(`OR(ISBLANK(%[1]s),AND(LEFT(%[1]s,1)="{",RIGHT(%[1]s,1)="}"))`, first)
+		expect = "a brace array such as {1,2,3}"
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A warning, not a stop: @expand and @template rows hold text in typed columns.
+	dv.SetError(excelize.DataValidationErrorStyleWarning, f.RawName, f.RawName+" expects "+expect+".")
+	dv.SetInput(f.RawName, f.RawType)
+	return dv, nil
+}
+
+// refValidation returns a drop-down of the column a ref column points at, or
+// nil when the target isn't a sheet of this workbook (the export reports
+// targets that don't exist).
+func refValidation(f Field, sheets []*Sheet, dataRow map[*Sheet]int) (*excelize.DataValidation, error) {
+	target := FindSheet(sheets, f.Ref.Sheet)
+	if target == nil {
+		return nil, nil
+	}
+	tf := refTarget(target, f.Ref)
+	if tf == nil {
+		return nil, nil
+	}
+	col, err := excelize.ColumnNumberToName(tf.Col + 1)
+	if err != nil {
+		return nil, err
+	}
+	dv := excelize.NewDataValidation(true)
+	sheetRef := "'" + strings.ReplaceAll(target.Name, "'", "''") + "'"
+	dv.SetSqrefDropList(escapeValidationFormula(fmt.Sprintf("%s!$%s$%d:$%s$%d", sheetRef, col, dataRow[target], col, excelize.TotalRows)))
+	dv.SetError(excelize.DataValidationErrorStyleWarning, f.RawName, fmt.Sprintf("%s expects a %s of %s.", f.RawName, tf.RawName, target.Name))
+	dv.SetInput(f.RawName, f.Ref.String())
+	return dv, nil
+}
+
+func escapeValidationFormula(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		s = `"` + strings.ReplaceAll(s[1:len(s)-1], `"`, `""`) + `"`
+	}
+	return strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`).Replace(s)
+}
@@ -0,0 +1,68 @@
+package genxls
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestAddSchemaValidations(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", "Item"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.NewSheet("quest_log"); err != nil {
+		t.Fatal(err)
+	}
+	for sheet, rows := range map[string][][]any{
+		"Item":      {{"id#int", "name#string", "stack#int32", "price#int64"}, {1, "Sword", 1, 100}},
+		"quest_log": {{"id#int", "reward#ref:Item.id", "next#ref:QuestLog", "missing#ref:Npc.id"}, {1, 1, 0, 0}},
+	} {
+		for i, row := range rows {
+			if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+1), &row); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	n, err := AddSchemaValidations(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Item: id, stack, price; quest_log: id, reward, next.
+	if n != 6 {
+		t.Errorf("got %d rules, want 6", n)
+	}
+
+	want := map[string]map[string][2]string{
+		"Item": {
+			"A2:A1048576": {"-9223372036854775808", "9223372036854775807"},
+			"C2:C1048576": {"-2147483648", "2147483647"},
+			"D2:D1048576": {"-9223372036854775808", "9223372036854775807"},
+		},
+		"quest_log": {
+			"A2:A1048576": {"-9223372036854775808", "9223372036854775807"},
+			"B2:B1048576": {"'Item'!$A$2:$A$1048576"},
+			"C2:C1048576": {"'quest_log'!$A$2:$A$1048576"},
+		},
+	}
+	for sheet, rules := range want {
+		dvs, err := f.GetDataValidations(sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string][2]string)
+		for _, dv := range dvs {
+			got[dv.Sqref] = [2]string{dv.Formula1, dv.Formula2}
+		}
+		if len(got) != len(rules) {
+			t.Errorf("%s: got rules %v, want %v", sheet, got, rules)
+		}
+		for sqref, formulas := range rules {
+			if got[sqref] != formulas {
+				t.Errorf("%s %s: got formulas %q, want %q", sheet, sqref, got[sqref], formulas)
+			}
+		}
+	}
+}
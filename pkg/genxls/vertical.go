@@ -0,0 +1,144 @@
+package genxls
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Vertical sheets (A1=2) hold settings rather than rows: from the define row
+// on, each row is one key, its definition in column A, its value in B and an
+// optional description in C. The sheet exports as a single row with a column
+// per key, and the generated code gets a singleton accessor for it instead of
+// a list to index.
+
+// verticalGrid transposes a vertical sheet into the one-row table it exports:
+// key row k becomes column k, definitions in row defineRow and values in the
+// row below. Notes on the definition cells and column C descriptions become
+// notes of the transposed definition cells, for applyFieldDocs.
+func verticalGrid(rows [][]string, defineRow int, notes map[string]string) ([][]string, map[string]string) {
+	var defs, values []string
+	docs := make(map[string]string)
+	for r := defineRow - 1; r < len(rows); r++ {
+		k := len(defs)
+		defs = append(defs, cellAt(rows[r], 0))
+		values = append(values, cellAt(rows[r], 1))
+		doc := notes[fmt.Sprintf("A%d", r+1)]
+		if doc == "" {
+			doc = strings.TrimSpace(cellAt(rows[r], 2))
+		}
+		if cell, err := excelize.CoordinatesToCellName(k+1, defineRow); err == nil && doc != "" {
+			docs[cell] = doc
+		}
+	}
+	grid := make([][]string, defineRow+1)
+	grid[defineRow-1], grid[defineRow] = defs, values
+	return grid, docs
+}
+
+func cellAt(row []string, col int) string {
+	if col < len(row) {
+		return row[col]
+	}
+	return ""
+}
+
+// verticalCell maps a cell of verticalGrid's values row back to the sheet:
+// the value of key k (1-based column col) is in column B of row defineRow+k.
+func verticalCell(defineRow, col int) (int, int) {
+	return defineRow + col - 1, 2
+}
+
+// cellOf returns where the value of f in item i was read (1-based row and
+// column), for problems pointing at cells.
+func (s *Sheet) cellOf(i int, f Field) (row, col int) {
+	if s.Vertical {
+		return verticalCell(s.Rows[i], f.Col+1)
+	}
+	return s.Rows[i], f.Col + 1
+}
+
+// singletonSheets returns the vertical sheets, which get singleton accessors.
+func singletonSheets(sheets []*Sheet) []*Sheet {
+	var out []*Sheet
+	for _, sheet := range sheets {
+		if sheet.Vertical {
+			out = append(out, sheet)
+		}
+	}
+	return out
+}
+
+// goSingletonAccessors renders a root method per vertical sheet returning its
+// row, e.g. func (c *AllConfig) GlobalConfig() *GlobalConfig.
+func goSingletonAccessors(rootName string, sheets []*Sheet) string {
+	var b strings.Builder
+	for _, sheet := range singletonSheets(sheets) {
+		fmt.Fprintf(&b, "// %s returns the settings of the %s sheet, or nil if it has none.\n", sheet.TypeName, sheet.Name)
+		fmt.Fprintf(&b, "func (c *%s) %s() *%s {\n", rootName, sheet.TypeName, sheet.TypeName)
+		fmt.Fprintf(&b, "\tif len(c.%s) == 0 {\n\t\treturn nil\n\t}\n", sheet.FieldName)
+		fmt.Fprintf(&b, "\treturn &c.%s[0]\n}\n\n", sheet.FieldName)
+	}
+	return b.String()
+}
+
+// csSingletonAccessors renders a root property per vertical sheet.
+func csSingletonAccessors(sheets []*Sheet) string {
+	var b strings.Builder
+	for _, sheet := range singletonSheets(sheets) {
+		fmt.Fprintf(&b, "    /// <summary>The settings of the %s sheet, or null if it has none.</summary>\n", sheet.Name)
+		b.WriteString("    [JsonIgnore]\n")
+		fmt.Fprintf(&b, "    public %s %s => %s is { Count: > 0 } ? %s[0] : null;\n\n", sheet.TypeName, sheet.TypeName, sheet.FieldName, sheet.FieldName)
+	}
+	return b.String()
+}
+
+// tsSingletonAccessors renders a get<Type> function per vertical sheet.
+func tsSingletonAccessors(rootName string, sheets []*Sheet) string {
+	var b strings.Builder
+	for _, sheet := range singletonSheets(sheets) {
+		fmt.Fprintf(&b, "\n/** The settings of the %s sheet, or undefined if it has none. */\n", sheet.Name)
+		fmt.Fprintf(&b, "export function get%s(c: %s): %s | undefined {\n", sheet.TypeName, rootName, sheet.TypeName)
+		fmt.Fprintf(&b, "  return c.%s[0];\n}\n", sheet.JSONKey)
+	}
+	return b.String()
+}
+
+// dartSingletonGetters renders a root getter per vertical sheet.
+func dartSingletonGetters(sheets []*Sheet) string {
+	var b strings.Builder
+	for _, sheet := range singletonSheets(sheets) {
+		fmt.Fprintf(&b, "\n  /// The settings of the %s sheet, or null if it has none.\n", sheet.Name)
+		fmt.Fprintf(&b, "  %s? get %s => %s.isEmpty ? null : %s.first;\n", sheet.TypeName, lowerFirst(sheet.TypeName), sheet.JSONKey, sheet.JSONKey)
+	}
+	return b.String()
+}
+
+// gdSingletonFuncs renders a root get_<key> func per vertical sheet.
+func gdSingletonFuncs(sheets []*Sheet) string {
+	var b strings.Builder
+	for _, sheet := range singletonSheets(sheets) {
+		fmt.Fprintf(&b, "\n## The settings of the %s sheet, or null if it has none.\n", sheet.Name)
+		fmt.Fprintf(&b, "func get_%s() -> %s:\n", snakeCase(sheet.TypeName), sheet.TypeName)
+		fmt.Fprintf(&b, "\treturn %s[0] if not %s.is_empty() else null\n", sheet.JSONKey, sheet.JSONKey)
+	}
+	return b.String()
+}
+
+// snakeCase turns an exported name into GDScript's function style, e.g.
+// GlobalConfig into global_config.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,187 @@
+package genxls
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reserved columns bounding when a row is active, for scheduled event
+// content. Both are datetime columns; an empty cell leaves that side open.
+const (
+	windowStartColumn = "__start"
+	windowEndColumn   = "__end"
+)
+
+// dateTimeLayout is how datetime cells are written to every payload: ISO 8601
+// without a zone, as the cells are entered.
+const dateTimeLayout = "2006-01-02T15:04:05"
+
+// dateTimeLayouts are the cell texts parseDateTime accepts, besides Excel
+// serial numbers: ISO dates with T or a space, slashes, and the m/d/yy forms
+// of Excel's built-in date formats.
+var dateTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"1/2/06 15:04",
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/06",
+	"1/2/2006",
+	"01-02-06",
+}
+
+// excelEpoch is day 0 of Excel's 1900 date system, adjusted for its 1900 leap
+// year bug so serials from 1900-03-01 on come out right.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// parseDateTime parses a datetime cell. Cells with a date format come through
+// formatted; cells typed as plain numbers are read as Excel serials.
+func parseDateTime(s string) (time.Time, error) {
+	return parseDateTimeIn(s, time.UTC)
+}
+
+// parseDateTimeIn is parseDateTime reading times without an offset as wall
+// clock times in loc.
+func parseDateTimeIn(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 1 && f < 2958466 {
+		day := math.Floor(f)
+		secs := math.Round((f - day) * 86400)
+		t := excelEpoch.AddDate(0, 0, int(day)).Add(time.Duration(secs) * time.Second)
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid datetime %q (expect e.g. 2024-05-01 10:00)", s)
+}
+
+func formatDateTime(t time.Time) string {
+	return t.Format(dateTimeLayout)
+}
+
+func isWindowColumn(name string) bool {
+	return name == windowStartColumn || name == windowEndColumn
+}
+
+// sheetWindow returns the columns of the active window of a sheet, or nil.
+func sheetWindow(sheet *Sheet) (start, end *Field) {
+	for i := range sheet.Fields {
+		switch sheet.Fields[i].RawName {
+		case windowStartColumn:
+			start = &sheet.Fields[i]
+		case windowEndColumn:
+			end = &sheet.Fields[i]
+		}
+	}
+	return start, end
+}
+
+// windowBound parses the bound of item in column f; ok is false when the
+// sheet has no such column or the cell is empty.
+func windowBound(item map[string]any, f *Field) (t time.Time, ok bool) {
+	if f == nil {
+		return time.Time{}, false
+	}
+	s, _ := item[f.RawName].(string)
+	if s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s) // with a "timezone" in the config
+	if err != nil {
+		t, err = time.Parse(dateTimeLayout, s)
+	}
+	return t, err == nil
+}
+
+// checkWindows reports rows whose window ends before it starts.
+func checkWindows(sheets []*Sheet) []Problem {
+	var problems []Problem
+	for _, sheet := range sheets {
+		start, end := sheetWindow(sheet)
+		if start == nil || end == nil {
+			continue
+		}
+		for i, item := range sheet.Items {
+			from, okFrom := windowBound(item, start)
+			to, okTo := windowBound(item, end)
+			if okFrom && okTo && !to.After(from) {
+				p := problemf(sheet, "%s: data row %d: %s %s is not after %s %s", sheet.Origin, i+1,
+					windowEndColumn, formatDateTime(to), windowStartColumn, formatDateTime(from))
+				p.Row, p.Col = sheet.cellOf(i, *end)
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems
+}
+
+// applyAsOf drops the rows of every sheet that are not active at asOf (start
+// inclusive, end exclusive) and then the window columns themselves, which
+// were only there to schedule the rows (--as-of). It returns the number of
+// rows dropped per sheet.
+func applyAsOf(sheets []*Sheet, asOf time.Time) map[*Sheet]int {
+	dropped := make(map[*Sheet]int)
+	for _, sheet := range sheets {
+		start, end := sheetWindow(sheet)
+		if start == nil && end == nil {
+			continue
+		}
+		items, rows := sheet.Items[:0], sheet.Rows[:0]
+		for i, item := range sheet.Items {
+			if from, ok := windowBound(item, start); ok && asOf.Before(from) {
+				continue
+			}
+			if to, ok := windowBound(item, end); ok && !asOf.Before(to) {
+				continue
+			}
+			items, rows = append(items, item), append(rows, sheet.Rows[i])
+		}
+		dropped[sheet] = len(sheet.Items) - len(items)
+		sheet.Items, sheet.Rows = items, rows
+
+		fields := sheet.Fields[:0]
+		for _, f := range sheet.Fields {
+			if !isWindowColumn(f.RawName) {
+				fields = append(fields, f)
+			}
+		}
+		sheet.Fields = fields
+		for _, item := range sheet.Items {
+			delete(item, windowStartColumn)
+			delete(item, windowEndColumn)
+		}
+	}
+	return dropped
+}
+
+// parseAsOf parses --as-of: "now" or a datetime as cells take it, in the
+// config's zones when it has them.
+func parseAsOf(s string, zones *DateTimeZones) (time.Time, error) {
+	now := strings.EqualFold(strings.TrimSpace(s), "now")
+	switch {
+	case zones != nil && now:
+		return time.Now(), nil
+	case zones != nil:
+		return parseDateTimeIn(s, zones.In)
+	case now:
+		// Cells are naive local times, so compare against the local wall clock.
+		t := time.Now()
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC), nil
+	}
+	return parseDateTime(s)
+}
@@ -0,0 +1,203 @@
+package genxls
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// PasswordRule supplies the password of protected workbooks matching Pattern
+// (matched like OwnerRule, last match wins). PasswordEnv names an environment
+// variable to read it from, so secrets stay out of the config file.
+type PasswordRule struct {
+	Pattern     string `json:"pattern"`
+	Password    string `json:"password,omitempty"`
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+}
+
+// cfbMagic starts every OLE compound file: legacy binary .xls and encrypted
+// .xlsx packages alike.
+var cfbMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// zipMagic starts every .xlsx/.xlsm package.
+var zipMagic = []byte("PK\x03\x04")
+
+// PasswordFor returns the password configured for file, or fallback. c may be
+// nil.
+func (c *Config) PasswordFor(file, fallback string) string {
+	if c == nil {
+		return fallback
+	}
+	pw := fallback
+	for _, r := range c.Passwords {
+		if !pathMatches(r.Pattern, file) {
+			continue
+		}
+		pw = r.Password
+		if r.PasswordEnv != "" {
+			pw = os.Getenv(r.PasswordEnv)
+		}
+	}
+	return pw
+}
+
+// OpenWorkbook opens path as an xlsx workbook, decrypting it when it is
+// password-protected. It returns nil, nil for files that are not zip packages
+// at all, which are then read as tab-separated text; a zip package excelize
+// can't read is an error.
+func OpenWorkbook(path, password string) (*excelize.File, error) {
+	if strings.EqualFold(filepath.Ext(path), ".numbers") {
+		return nil, numbersError(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, zipMagic) {
+		if zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err == nil && isNumbersPackage(zr) {
+			return nil, numbersError(path)
+		}
+	}
+	if bytes.HasPrefix(data, cfbMagic) {
+		if !bytes.Contains(data, utf16LE("EncryptionInfo")) {
+			return nil, fmt.Errorf("%s: legacy binary .xls is not supported; save it as .xlsx", path)
+		}
+		if password == "" {
+			return nil, fmt.Errorf("%s: workbook is password-protected; supply --password or a \"passwords\" entry in the config file", path)
+		}
+		f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{Password: password})
+		if err != nil {
+			return nil, fmt.Errorf("%s: cannot decrypt workbook (wrong password?): %w", path, err)
+		}
+		f.Path = path
+		return f, nil
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil && bytes.HasPrefix(data, zipMagic) && bytes.Contains(data, cfbMagic) {
+		// excelize takes any package containing the OLE signature for an
+		// encrypted one. In .xlsm workbooks that is the VBA project (an OLE
+		// file itself) stored uncompressed; deflating it hides the signature
+		// without changing any part.
+		if data, err = recompressPackage(data); err == nil {
+			f, err = excelize.OpenReader(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: cannot read workbook: %w", path, err)
+		}
+	}
+	if err != nil {
+		if bytes.HasPrefix(data, zipMagic) {
+			return nil, fmt.Errorf("%s: cannot read workbook: %w", path, err)
+		}
+		return nil, nil
+	}
+	f.Path = path
+	return f, nil
+}
+
+// recompressPackage rewrites a zip package with every entry deflated.
+func recompressPackage(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestCompression)
+	})
+	for _, e := range zr.File {
+		r, err := e.Open()
+		if err != nil {
+			return nil, err
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.Name, Method: zip.Deflate, Modified: e.Modified})
+		if err == nil {
+			_, err = io.Copy(w, r)
+		}
+		_ = r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if bytes.Contains(buf.Bytes(), cfbMagic) {
+		return nil, errors.New("an embedded binary part keeps the OLE signature even when compressed")
+	}
+	return buf.Bytes(), nil
+}
+
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+// openWorkbookFile opens a big unencrypted workbook without reading it into
+// memory first; excelize keeps its big worksheets in temporary files and
+// xlsxRows then decodes them as a stream. ok is false for files it leaves to
+// OpenWorkbook: anything but a zip package, and packages excelize can't open
+// directly (such as .xlsm files it takes for encrypted ones).
+func openWorkbookFile(path string) (f *excelize.File, ok bool, err error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, false, nil
+	}
+	numbers := isNumbersPackage(&zr.Reader)
+	_ = zr.Close()
+	if numbers {
+		return nil, true, numbersError(path)
+	}
+	if f, err = excelize.OpenFile(path); err != nil {
+		return nil, false, nil
+	}
+	return f, true, nil
+}
+
+// xlsxRows reads a worksheet row by row through excelize's row iterator,
+// trimming and blanking each row as trimGhostCells and blankDanglingStrings
+// do for a whole sheet.
+type xlsxRows struct {
+	it       *excelize.Rows
+	row      int
+	dangling map[int][]danglingString // by 1-based row
+	blanked  int
+}
+
+func (r *xlsxRows) Next() ([]string, error) {
+	if !r.it.Next() {
+		if err := r.it.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	r.row++
+	row, err := r.it.Columns()
+	if err != nil {
+		return nil, err
+	}
+	n := len(row)
+	for n > 0 && row[n-1] == "" {
+		n--
+	}
+	row = row[:n:n]
+	if cells := r.dangling[r.row]; len(cells) > 0 {
+		r.blanked += blankDanglingRow(row, cells)
+	}
+	return row, nil
+}
+
+func (r *xlsxRows) Close() error { return r.it.Close() }
@@ -0,0 +1,83 @@
+package genxls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenWorkbookBrokenPackage(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name, data, err string
+	}{
+		{"text.xlsx", "id#int\tname#string\n1\tSword\n", ""},
+		{"truncated.xlsx", "PK\x03\x04\x14\x00\x00\x00", "cannot read workbook"},
+	} {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := OpenWorkbook(path, "")
+		switch {
+		case tc.err == "" && (err != nil || f != nil):
+			t.Errorf("%s: got %v, %v; want nil, nil (read as text)", tc.name, f, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: got error %v; want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestStreamedWorkbookMatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "synth.xlsx")
+	f, err := NewSynthWorkbook(SynthSpec{Seed: 7, Sheets: []SynthSheet{
+		{Name: "Item", Rows: 300, Columns: []string{"id#int", "name#string", "cost#float", "tags#int[]", "at#datetime"}, Merged: true, Formulas: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.NewSheet("Quest"); err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range [][]any{
+		{"id#int", "name#string"},
+		{1, "intro"},
+		{"@params side", "color"},
+		{"@values side", "red"},
+		{"@values side", "blue"},
+		{"@template side : ${100+i}", "quest_${color}"},
+		{2, "outro"},
+	} {
+		if err := f.SetSheetRow("Quest", fmt.Sprintf("A%d", i+1), &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	load := func(stream int64) string {
+		t.Helper()
+		sheets, err := LoadSheets(context.Background(), []string{path}, Options{StreamSize: stream}, nil)
+		if err != nil {
+			t.Fatalf("stream size %d: %v", stream, err)
+		}
+		data, err := json.Marshal(buildJSONPayload(sheets))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	whole, streamed := load(0), load(1)
+	if whole != streamed {
+		t.Errorf("streamed payload differs:\nwhole:    %.300s\nstreamed: %.300s", whole, streamed)
+	}
+	if !strings.Contains(whole, `"quest_blue"`) {
+		t.Errorf("template rows not expanded: %.300s", whole)
+	}
+}
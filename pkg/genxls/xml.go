@@ -0,0 +1,192 @@
+package genxls
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// writeXMLBundle writes <DataName>.xml and the XSD describing it,
+// <DataName>.xsd, next to each other. The root element is named after the root
+// type and holds one element per sheet (its JSON key) with a <TypeName>
+// element per row and an element per column. Arrays become repeated elements:
+// int[] columns hold one <item> per value, int[][] columns one <row> of
+// <item>s per inner array.
+func writeXMLBundle(out *OutputLayout, sheets []*Sheet) ([]string, error) {
+	xsdFile, err := out.Path("xml", out.DataName+".xsd", nil)
+	if err != nil {
+		return nil, err
+	}
+	xmlFile, err := out.Path("xml", out.DataName+".xml", nil)
+	if err != nil {
+		return nil, err
+	}
+	xsdRel, err := filepath.Rel(filepath.Dir(xmlFile), xsdFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := encodeXMLPayload(out.RootName, filepath.ToSlash(xsdRel), sheets)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := generateXSD(out.RootName, sheets)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.write(xmlFile, data); err != nil {
+		return nil, err
+	}
+	if err := out.write(xsdFile, []byte(schema)); err != nil {
+		return nil, err
+	}
+	return []string{xmlFile, xsdFile}, nil
+}
+
+func encodeXMLPayload(rootName, xsdPath string, sheets []*Sheet) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<" + rootName + ` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="`)
+	if err := xml.EscapeText(&b, []byte(xsdPath)); err != nil {
+		return nil, err
+	}
+	b.WriteString("\">\n")
+	for _, sheet := range sheets {
+		b.WriteString("  <" + sheet.JSONKey + ">\n")
+		for _, item := range sheet.Items {
+			b.WriteString("    <" + sheet.TypeName + ">\n")
+			for _, f := range sheet.Fields {
+				b.WriteString("      <" + f.RawName + ">")
+				if err := writeXMLValue(&b, item[f.RawName]); err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", sheet.TypeName, f.RawName, err)
+				}
+				b.WriteString("</" + f.RawName + ">\n")
+			}
+			b.WriteString("    </" + sheet.TypeName + ">\n")
+		}
+		b.WriteString("  </" + sheet.JSONKey + ">\n")
+	}
+	b.WriteString("</" + rootName + ">\n")
+	return b.Bytes(), nil
+}
+
+func writeXMLValue(b *bytes.Buffer, v any) error {
+	switch x := v.(type) {
+	case int:
+		b.WriteString(strconv.Itoa(x))
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			b.WriteString("NaN")
+		case math.IsInf(x, 1):
+			b.WriteString("INF")
+		case math.IsInf(x, -1):
+			b.WriteString("-INF")
+		default:
+			b.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+		}
+	case bool:
+		b.WriteString(strconv.FormatBool(x))
+	case string:
+		return xml.EscapeText(b, []byte(x))
+	case []int:
+		for _, n := range x {
+			b.WriteString("<item>" + strconv.Itoa(n) + "</item>")
+		}
+	case [][]int:
+		for _, inner := range x {
+			b.WriteString("<row>")
+			_ = writeXMLValue(b, inner)
+			b.WriteString("</row>")
+		}
+	default:
+		return fmt.Errorf("unsupported value type %T", v)
+	}
+	return nil
+}
+
+// xsdType maps a column type to its XSD type; arrays map to the IntList and
+// IntMatrix complex types generateXSD declares, datetime to DateTime, which
+// also allows the empty value of an empty cell.
+func xsdType(rawType string) (string, bool) {
+	switch strings.ToLower(rawType) {
+	case "int32":
+		return "xs:int", true
+	case "int", "int64":
+		return "xs:long", true
+	case "float", "float32", "float64":
+		return "xs:double", true
+	case "bool":
+		return "xs:boolean", true
+	case "string":
+		return "xs:string", true
+	case "datetime":
+		return "DateTime", true
+	case "int[]":
+		return "IntList", true
+	case "int[][]":
+		return "IntMatrix", true
+	default:
+		return "", false
+	}
+}
+
+// generateXSD renders the schema of the payload encodeXMLPayload writes.
+func generateXSD(rootName string, sheets []*Sheet) (string, error) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString("<xs:schema xmlns:xs=\"http://www.w3.org/2001/XMLSchema\" elementFormDefault=\"qualified\">\n")
+	b.WriteString("  <xs:element name=\"" + rootName + "\">\n")
+	b.WriteString("    <xs:complexType>\n      <xs:sequence>\n")
+	for _, sheet := range sheets {
+		b.WriteString("        <xs:element name=\"" + sheet.JSONKey + "\">\n")
+		b.WriteString("          <xs:complexType>\n            <xs:sequence>\n")
+		b.WriteString("              <xs:element name=\"" + sheet.TypeName + "\" type=\"" + sheet.TypeName + "\" minOccurs=\"0\" maxOccurs=\"unbounded\"/>\n")
+		b.WriteString("            </xs:sequence>\n          </xs:complexType>\n")
+		b.WriteString("        </xs:element>\n")
+	}
+	b.WriteString("      </xs:sequence>\n    </xs:complexType>\n  </xs:element>\n")
+
+	for _, sheet := range sheets {
+		if sheet.TypeName == "IntList" || sheet.TypeName == "IntMatrix" || sheet.TypeName == "DateTime" {
+			return "", fmt.Errorf("%s: type name is reserved in the XSD", sheet.Origin)
+		}
+		b.WriteString("\n  <xs:complexType name=\"" + sheet.TypeName + "\">\n    <xs:sequence>\n")
+		for _, f := range sheet.Fields {
+			t, ok := xsdType(f.RawType)
+			if !ok {
+				return "", fmt.Errorf("%s.%s: unsupported type %q", sheet.TypeName, f.RawName, f.RawType)
+			}
+			if f.Doc == "" {
+				b.WriteString("      <xs:element name=\"" + f.RawName + "\" type=\"" + t + "\"/>\n")
+				continue
+			}
+			b.WriteString("      <xs:element name=\"" + f.RawName + "\" type=\"" + t + "\">\n")
+			b.WriteString("        <xs:annotation><xs:documentation>")
+			var doc bytes.Buffer
+			if err := xml.EscapeText(&doc, []byte(f.Doc)); err != nil {
+				return "", err
+			}
+			b.WriteString(doc.String())
+			b.WriteString("</xs:documentation></xs:annotation>\n")
+			b.WriteString("      </xs:element>\n")
+		}
+		b.WriteString("    </xs:sequence>\n  </xs:complexType>\n")
+	}
+
+	b.WriteString("\n  <xs:complexType name=\"IntList\">\n    <xs:sequence>\n")
+	b.WriteString("      <xs:element name=\"item\" type=\"xs:long\" minOccurs=\"0\" maxOccurs=\"unbounded\"/>\n")
+	b.WriteString("    </xs:sequence>\n  </xs:complexType>\n")
+	b.WriteString("\n  <xs:complexType name=\"IntMatrix\">\n    <xs:sequence>\n")
+	b.WriteString("      <xs:element name=\"row\" type=\"IntList\" minOccurs=\"0\" maxOccurs=\"unbounded\"/>\n")
+	b.WriteString("    </xs:sequence>\n  </xs:complexType>\n")
+	b.WriteString("\n  <xs:simpleType name=\"DateTime\">\n")
+	b.WriteString("    <xs:union memberTypes=\"xs:dateTime\">\n")
+	b.WriteString("      <xs:simpleType><xs:restriction base=\"xs:string\"><xs:length value=\"0\"/></xs:restriction></xs:simpleType>\n")
+	b.WriteString("    </xs:union>\n  </xs:simpleType>\n")
+	b.WriteString("</xs:schema>\n")
+	return b.String(), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"genxls/pkg/genxls"
+)
+
+// runPreview implements `genxls preview`: print the first rows of a sheet the
+// way the exporter parses them, without generating anything.
+func runPreview(args []string) {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	in := fs.String("in", "xls", "input xlsx file or directory")
+	sheetName := fs.String("sheet", "", "sheet to preview: sheet name, type name or JSON key (default: all)")
+	rows := fs.Int("rows", 5, "number of rows to print (0: all)")
+	format := fs.String("format", "table", "output format: table|json")
+	exportFlag := fs.String("flag", "", "export flag: server|client (optional)")
+	_ = fs.Parse(args)
+
+	if *format != "table" && *format != "json" {
+		exitErr(fmt.Errorf("invalid --format %q (expect table|json)", *format))
+	}
+	inPaths, err := genxls.ResolveInputPaths(*in, nil)
+	if err != nil {
+		exitErr(err)
+	}
+	sheets, err := genxls.LoadSheets(context.Background(), inPaths, genxls.Options{Flag: *exportFlag}, nil)
+	if err != nil {
+		exitErr(err)
+	}
+	if *sheetName != "" {
+		sheet := genxls.FindSheet(sheets, *sheetName)
+		if sheet == nil {
+			exitErr(fmt.Errorf("sheet %q not found", *sheetName))
+		}
+		sheets = []*genxls.Sheet{sheet}
+	}
+
+	for i, sheet := range sheets {
+		items := sheet.Items
+		if *rows > 0 && len(items) > *rows {
+			items = items[:*rows]
+		}
+		if *format == "json" {
+			out := make([]json.RawMessage, 0, len(items))
+			for _, item := range items {
+				obj, err := genxls.EncodeJSONObject(sheet.Fields, item)
+				if err != nil {
+					exitErr(err)
+				}
+				out = append(out, obj)
+			}
+			data, err := json.MarshalIndent(map[string]any{sheet.JSONKey: out}, "", "  ")
+			if err != nil {
+				exitErr(err)
+			}
+			fmt.Println(string(data))
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s (%s, %d of %d rows)\n", sheet.TypeName, sheet.Origin, len(items), len(sheet.Items))
+		if err := printPreviewTable(sheet.Fields, items); err != nil {
+			exitErr(err)
+		}
+	}
+}
+
+func printPreviewTable(fields []genxls.Field, items []map[string]any) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := make([]string, len(fields))
+	types := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.RawName
+		types[i] = f.RawType
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	fmt.Fprintln(w, strings.Join(types, "\t"))
+	for _, item := range items {
+		cells := make([]string, len(fields))
+		for i, f := range fields {
+			v := item[f.RawName]
+			if s, ok := v.(string); ok {
+				cells[i] = s
+				continue
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			cells[i] = string(data)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"genxls/pkg/genxls"
+)
+
+// runServe implements `genxls serve`: answer JSON-RPC requests until stdin is
+// closed or a shutdown request arrives, or with --http serve POST /check.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	httpAddr := fs.String("http", "", "serve POST /check on this loopback address (e.g. 127.0.0.1:7700) instead of JSON-RPC on stdin")
+	config := fs.String("config", "", "config file for --http checks (default: genxls.json, genxls.yaml or genxls.toml in the working directory, if present)")
+	_ = fs.Parse(args)
+	if *httpAddr != "" {
+		exitErr(genxls.ServeCheckHTTP(*httpAddr, *config))
+	}
+	if err := genxls.ServeRPC(os.Stdin, os.Stdout); err != nil {
+		exitErr(err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"genxls/pkg/genxls"
+)
+
+// runSchema implements `genxls schema`: print the parsed schema of the sheets,
+// after the config is applied, without generating anything.
+func runSchema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	in := fs.String("in", "xls", "input xlsx file or directory")
+	config := fs.String("config", "", "config file (default: genxls.json, genxls.yaml or genxls.toml in the working directory, if present)")
+	sheetName := fs.String("sheet", "", "sheet to print: sheet name, type name or JSON key (default: all)")
+	format := fs.String("format", "json", "output format: go|json|markdown|proto")
+	exportFlag := fs.String("flag", "", "export flag: server|client (optional)")
+	pkg := fs.String("pkg", "config", "package name for --format go|proto")
+	namespace := fs.Bool("namespace-by-file", false, "prefix sheet names with the workbook name, as in the export")
+	lockFile := fs.String("lock", genxls.DefaultLockFile, "schema lock file whose proto field numbers --format proto uses, if it exists")
+	_ = fs.Parse(args)
+
+	switch *format {
+	case "go", "json", "markdown", "proto":
+	default:
+		exitErr(fmt.Errorf("invalid --format %q (expect go|json|markdown|proto)", *format))
+	}
+	cfg, err := genxls.LoadConfig(*config)
+	if err != nil {
+		exitErr(err)
+	}
+	inPaths, err := genxls.ResolveInputPaths(*in, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	sheets, err := genxls.LoadSheets(context.Background(), inPaths, genxls.Options{Flag: *exportFlag, NamespaceFile: *namespace}, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	if err := genxls.ApplyConfig(cfg, sheets, false); err != nil {
+		exitErr(err)
+	}
+	if *sheetName != "" {
+		sheet := genxls.FindSheet(sheets, *sheetName)
+		if sheet == nil {
+			exitErr(fmt.Errorf("sheet %q not found", *sheetName))
+		}
+		sheets = []*genxls.Sheet{sheet}
+	}
+
+	const rootName = "AllConfig"
+	var text string
+	switch *format {
+	case "go":
+		text, err = genxls.GenerateGoBundle(*pkg, rootName, sheets, false, false)
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(genxls.BuildSchemaModel(rootName, sheets), "", "  ")
+		text = string(data) + "\n"
+	case "markdown":
+		text = genxls.GenerateConfigDocs(rootName, sheets)
+	case "proto":
+		if err = genxls.ApplyLockedProtoNumbers(*lockFile, *exportFlag, sheets); err == nil {
+			text, err = genxls.GenerateProtoSchema(*pkg, rootName, sheets)
+		}
+	}
+	if err != nil {
+		exitErr(err)
+	}
+	fmt.Print(text)
+}
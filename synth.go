@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"genxls/pkg/genxls"
+)
+
+// runSynth implements `genxls synth`: write a synthetic workbook from a spec
+// file or from flags describing identical sheets.
+func runSynth(args []string) {
+	fs := flag.NewFlagSet("synth", flag.ExitOnError)
+	out := fs.String("out", "synth.xlsx", "workbook to write")
+	specPath := fs.String("spec", "", "JSON SynthSpec file; overrides the flags below")
+	sheets := fs.Int("sheets", 1, "number of sheets")
+	rows := fs.Int("rows", 100, "data rows per sheet")
+	types := fs.String("types", strings.Join(genxls.SupportedTypes, ","), "comma-separated column types; the first is the key's")
+	merged := fs.Bool("merged", false, "merge the title row and some data cells")
+	formulas := fs.Bool("formulas", false, "make some numeric cells formulas")
+	seed := fs.Uint64("seed", 1, "random seed")
+	_ = fs.Parse(args)
+
+	var spec genxls.SynthSpec
+	if *specPath != "" {
+		data, err := os.ReadFile(*specPath)
+		if err != nil {
+			exitErr(err)
+		}
+		if err := json.Unmarshal(data, &spec); err != nil {
+			exitErr(fmt.Errorf("%s: %w", *specPath, err))
+		}
+	} else {
+		var columns []string
+		for i, t := range strings.Split(*types, ",") {
+			name := "f" + strconv.Itoa(i+1)
+			if i == 0 {
+				name = "id"
+			}
+			columns = append(columns, name+"#"+strings.TrimSpace(t))
+		}
+		spec.Seed = *seed
+		for i := 0; i < *sheets; i++ {
+			spec.Sheets = append(spec.Sheets, genxls.SynthSheet{
+				Name:     "Synth" + strconv.Itoa(i+1),
+				Rows:     *rows,
+				Columns:  columns,
+				Merged:   *merged,
+				Formulas: *formulas,
+			})
+		}
+	}
+	if err := genxls.WriteSynthWorkbook(*out, spec); err != nil {
+		exitErr(err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"genxls/pkg/genxls"
+	"github.com/xuri/excelize/v2"
+)
+
+// runValidation implements `genxls validation`: write Excel data-validation
+// rules derived from the define row into the source workbooks, so designers get
+// feedback while typing instead of at export time. Without --out a workbook
+// is updated in place after copying it to <name>.bak.
+func runValidation(args []string) {
+	fs := flag.NewFlagSet("validation", flag.ExitOnError)
+	in := fs.String("in", "xls", "input xlsx file or directory")
+	out := fs.String("out", "", "write the updated workbooks into this directory instead of updating them in place (after a .bak copy)")
+	config := fs.String("config", "", "config file (default: genxls.json, genxls.yaml or genxls.toml in the working directory, if present)")
+	password := fs.String("password", "", "password for protected workbooks without a config \"passwords\" entry")
+	verbose := fs.Bool("v", false, "verbose")
+	_ = fs.Parse(args)
+
+	cfg, err := genxls.LoadConfig(*config)
+	if err != nil {
+		exitErr(err)
+	}
+	inPaths, err := genxls.ResolveInputPaths(*in, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	if *out != "" {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			exitErr(err)
+		}
+	}
+	seen := make(map[string]string) // output path -> input
+	for _, p := range inPaths {
+		pw := cfg.PasswordFor(p, *password)
+		f, err := genxls.OpenWorkbook(p, pw)
+		if err != nil {
+			exitErr(err)
+		}
+		if f == nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: not a workbook, skipped\n", p)
+			continue
+		}
+		dst := p
+		if *out != "" {
+			dst = filepath.Join(*out, filepath.Base(p))
+			if prev, ok := seen[dst]; ok {
+				_ = f.Close()
+				exitErr(fmt.Errorf("validation: %s and %s would both be written to %s", prev, p, dst))
+			}
+			seen[dst] = p
+		}
+		n, err := genxls.AddSchemaValidations(f)
+		if err == nil && dst == p {
+			err = backupFile(p)
+		}
+		if err == nil {
+			var opts []excelize.Options
+			if pw != "" {
+				opts = append(opts, excelize.Options{Password: pw})
+			}
+			err = f.SaveAs(dst, opts...)
+		}
+		_ = f.Close()
+		if err != nil {
+			exitErr(fmt.Errorf("%s: %w", p, err))
+		}
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "%s: %d column rules\n", dst, n)
+		}
+	}
+}
+
+// backupFile copies path to path.bak, replacing an older copy, before the
+// workbook is rewritten.
+func backupFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".bak", data, info.Mode().Perm())
+}